@@ -2,22 +2,48 @@ package cli
 
 import (
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/lu-zhengda/machealth/internal/health"
 )
 
+var (
+	formatFlag      string
+	textfileDirFlag string
+)
+
 var checkCmd = &cobra.Command{
 	Use:   "check",
 	Short: "Run a one-shot system health check",
-	Long:  "Runs all health checks in parallel and returns a composite health report.",
+	Long: `Runs all health checks in parallel and returns a composite health report.
+
+--format prometheus prints the report in the Prometheus text exposition format.
+--textfile-dir writes it to machealth.prom in that directory instead (atomically,
+for node_exporter's textfile collector).`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		r := health.Check()
+		switch formatFlag {
+		case "", "json", "human", "prometheus":
+		default:
+			return fmt.Errorf("unsupported format: %s (use json, human, or prometheus)", formatFlag)
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		r := runCheck(cfg)
 		exitCode = health.ExitCode(r)
 
-		if humanFlag && !jsonFlag {
+		switch {
+		case textfileDirFlag != "":
+			return writeTextfile(textfileDirFlag, r)
+		case formatFlag == "prometheus":
+			return health.WritePrometheus(os.Stdout, r)
+		case formatFlag == "human", formatFlag == "" && humanFlag && !jsonFlag:
 			printHumanReport(r)
 			return nil
 		}
@@ -26,6 +52,8 @@ var checkCmd = &cobra.Command{
 }
 
 func init() {
+	checkCmd.Flags().StringVar(&formatFlag, "format", "", "Output format: json, human, or prometheus")
+	checkCmd.Flags().StringVar(&textfileDirFlag, "textfile-dir", "", "Write Prometheus metrics to machealth.prom in this directory")
 	rootCmd.AddCommand(checkCmd)
 }
 
@@ -51,8 +79,23 @@ func printHumanReport(r health.Report) {
 	if r.ICloud.LastSync != "" {
 		icloudDetail += " (last: " + r.ICloud.LastSync + ")"
 	}
+	if r.ICloud.PendingUploads > 0 || r.ICloud.PendingDownloads > 0 {
+		icloudDetail += fmt.Sprintf(", %d up/%d down pending", r.ICloud.PendingUploads, r.ICloud.PendingDownloads)
+	}
+	for _, p := range r.ICloud.Paths {
+		switch {
+		case p.Error != "":
+			icloudDetail += fmt.Sprintf(", %s: unreadable", p.Path)
+		case p.Evicted > 0:
+			icloudDetail += fmt.Sprintf(", %s: %d evicted", p.Path, p.Evicted)
+		case !p.Materialized:
+			icloudDetail += fmt.Sprintf(", %s: partial scan", p.Path)
+		}
+	}
 	printSubsystem("iCloud", r.ICloud.Status, icloudDetail)
 
+	printSubsystem("Cloud Sync", r.CloudSync.Status, cloudSyncDetail(r.CloudSync))
+
 	battDetail := fmt.Sprintf("%d%%, %s", r.Battery.Percent, r.Battery.PowerSource)
 	if !r.Battery.Installed {
 		battDetail = "Not installed (desktop Mac)"
@@ -72,6 +115,15 @@ func printHumanReport(r health.Report) {
 		if r.TimeMachine.Percent >= 0 {
 			tmDetail += fmt.Sprintf(" %.0f%%", r.TimeMachine.Percent)
 		}
+		if r.TimeMachine.TotalBytes > 0 {
+			tmDetail += fmt.Sprintf(", %.1f/%.1f GB", float64(r.TimeMachine.BytesCopied)/(1<<30), float64(r.TimeMachine.TotalBytes)/(1<<30))
+		}
+		if r.TimeMachine.ThroughputMBps > 0 {
+			tmDetail += fmt.Sprintf(" at %.1f MB/s", r.TimeMachine.ThroughputMBps)
+		}
+		if r.TimeMachine.TimeRemainingSec >= 0 {
+			tmDetail += ", ETA " + health.FormatDuration(time.Duration(r.TimeMachine.TimeRemainingSec)*time.Second)
+		}
 	}
 	printSubsystem("Time Machine", r.TimeMachine.Status, tmDetail)
 
@@ -84,11 +136,76 @@ func printHumanReport(r health.Report) {
 			netDetail += " (" + r.Network.IP + ")"
 		}
 	}
+	if w := r.Network.WiFi; w.Connected && w.SignalDBm != 0 {
+		netDetail += fmt.Sprintf(", Wi-Fi %d dBm", w.SignalDBm)
+	}
+	if p := r.Network.Proxy; p.PACError != "" {
+		netDetail += ", PAC unreachable"
+	} else if p.PACURL != "" || p.HTTP != "" || p.HTTPS != "" || p.SOCKS != "" {
+		netDetail += ", proxy"
+	}
+	if n := len(r.Network.DNS.Unresponsive); n > 0 {
+		netDetail += fmt.Sprintf(", %d DNS server(s) not answering", n)
+	}
+	if n := len(r.Network.HostsOverrides); n > 0 {
+		netDetail += fmt.Sprintf(", %d hosts override(s)", n)
+	}
 	printSubsystem("Network", r.Network.Status, netDetail)
 
 	btDetail := bluetoothDetail(r.Bluetooth)
 	printSubsystem("Bluetooth", r.Bluetooth.Status, btDetail)
 
+	printSubsystem("Security", r.Security.Status, securityDetail(r.Security))
+	printSubsystem("OS", r.OS.Status, osDetail(r.OS))
+
+	stDetail := "Up " + health.FormatDuration(time.Duration(r.Stability.UptimeSec)*time.Second)
+	if r.Stability.LastShutdownReason != "" {
+		stDetail += ", last shutdown: " + r.Stability.LastShutdownReason
+	}
+	if r.Stability.PanicCount > 0 {
+		stDetail += fmt.Sprintf(", %d recent panic(s)", r.Stability.PanicCount)
+	}
+	printSubsystem("Stability", r.Stability.Status, stDetail)
+
+	crDetail := "No crashes or hangs"
+	if r.Crashes.Total > 0 {
+		crDetail = fmt.Sprintf("%d report(s), top: %s (%d)", r.Crashes.Total, r.Crashes.TopProcess, r.Crashes.TopCount)
+	}
+	printSubsystem("Crashes", r.Crashes.Status, crDetail)
+	printSubsystem("Power", r.Power.Status, powerDetail(r.Power))
+
+	lim := r.Limits
+	limDetail := fmt.Sprintf("Files %.0f%%, procs %.0f%%, ports %.0f%%", lim.Files.Percent, lim.Processes.Percent, lim.EphemeralPorts.Percent)
+	if lim.TimeWait > 0 {
+		limDetail += fmt.Sprintf(", %d TIME_WAIT", lim.TimeWait)
+	}
+	printSubsystem("Limits", lim.Status, limDetail)
+
+	pr := r.Processes
+	prDetail := fmt.Sprintf("%d processes", pr.Total)
+	if n := len(pr.Stuck); n > 0 {
+		prDetail += fmt.Sprintf(", %d stuck", n)
+	}
+	if n := len(pr.Uninterruptible); n > 0 {
+		prDetail += fmt.Sprintf(", %d uninterruptible", n)
+	}
+	if n := len(pr.Zombies); n > 0 {
+		prDetail += fmt.Sprintf(", %d zombie(s)", n)
+	}
+	if n := len(pr.Orphans); n > 0 {
+		prDetail += fmt.Sprintf(", %d orphaned", n)
+	}
+	printSubsystem("Processes", pr.Status, prDetail)
+	printSubsystem("Time", r.Time.Status, timeDetail(r.Time))
+	printSubsystem("Toolchain", r.Toolchain.Status, toolchainDetail(r.Toolchain))
+	printSubsystem("Containers", r.Containers.Status, containersDetail(r.Containers))
+	printSubsystem("Packages", r.Packages.Status, packagesDetail(r.Packages))
+	printSubsystem("Services", r.Services.Status, servicesDetail(r.Services))
+	printSubsystem("Certificates", r.Certificates.Status, certificatesDetail(r.Certificates))
+	for _, c := range r.Custom {
+		printSubsystem(c.Name, c.Status, customDetail(c))
+	}
+
 	if len(r.Score.Reasons) > 0 {
 		fmt.Printf("\nReasons: %s\n", strings.Join(r.Score.Reasons, ", "))
 	}
@@ -107,7 +224,10 @@ func bluetoothDetail(bt health.Bluetooth) string {
 		for _, d := range bt.Devices {
 			if d.Connected {
 				entry := d.Name
-				if d.BatteryPercent >= 0 {
+				if d.BatteryLeft >= 0 || d.BatteryRight >= 0 {
+					entry += fmt.Sprintf(" (L %s, R %s, case %s)",
+						batteryLevel(d.BatteryLeft), batteryLevel(d.BatteryRight), batteryLevel(d.BatteryCase))
+				} else if d.BatteryPercent >= 0 {
 					entry += fmt.Sprintf(" (%d%%)", d.BatteryPercent)
 				}
 				names = append(names, entry)
@@ -120,6 +240,220 @@ func bluetoothDetail(bt health.Bluetooth) string {
 	return detail
 }
 
+func securityDetail(s health.Security) string {
+	controls := []struct {
+		name string
+		c    health.SecurityControl
+	}{
+		{"FileVault", s.FileVault},
+		{"SIP", s.SIP},
+		{"Gatekeeper", s.Gatekeeper},
+		{"Firewall", s.Firewall},
+	}
+	var parts []string
+	for _, c := range controls {
+		parts = append(parts, c.name+" "+c.c.State)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func osDetail(o health.OS) string {
+	detail := strings.TrimSpace(o.ProductName + " " + o.ProductVersion)
+	if o.BuildVersion != "" {
+		detail += " (" + o.BuildVersion + ")"
+	}
+	if o.Arch != "" {
+		detail += ", " + o.Arch
+	}
+	if o.UptimeSec > 0 {
+		detail += ", up " + health.FormatDuration(time.Duration(o.UptimeSec)*time.Second)
+	}
+	if n := len(o.PendingUpdates); n > 0 {
+		detail += fmt.Sprintf(", %d update(s) pending", n)
+		if o.RestartRequired {
+			detail += " (restart required)"
+		}
+	}
+	return detail
+}
+
+func powerDetail(p health.Power) string {
+	if p.Error != "" {
+		return "Unavailable (no data)"
+	}
+	var parts []string
+	switch {
+	case p.SystemSleepPrevented:
+		parts = append(parts, "Sleep prevented")
+	case p.SleepMinutes == 0:
+		parts = append(parts, "Sleep disabled")
+	default:
+		parts = append(parts, fmt.Sprintf("Sleep after %d min", p.SleepMinutes))
+	}
+	if n := len(p.Assertions); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d assertion(s)", n))
+	}
+	if p.LidClosed {
+		parts = append(parts, "lid closed")
+	}
+	if p.LowPowerMode {
+		parts = append(parts, "Low Power Mode")
+	}
+	if p.SleepRisk {
+		parts = append(parts, "long tasks at risk of sleep")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func timeDetail(ts health.TimeSync) string {
+	detail := "Network time " + ts.NetworkTime
+	if ts.Measured {
+		detail += fmt.Sprintf(", offset %+.3fs", ts.OffsetMs/1000)
+	} else if ts.Server != "" {
+		detail += ", offset unknown"
+	}
+	if ts.Timezone != "" {
+		detail += ", " + ts.Timezone
+	}
+	return detail
+}
+
+func toolchainDetail(tc health.Toolchain) string {
+	if tc.Error != "" {
+		return "Unavailable (no data)"
+	}
+	if tc.DeveloperDir == "" {
+		return "No developer tools installed"
+	}
+	detail := tc.DeveloperDir
+	if !tc.DeveloperDirExists {
+		detail += " (missing)"
+	}
+	for _, x := range tc.Xcodes {
+		if x.Active {
+			detail += fmt.Sprintf(", Xcode %s (%s), license %s", x.Version, x.Build, tc.License)
+		}
+	}
+	if tc.CLTVersion != "" {
+		detail += ", CLT " + tc.CLTVersion
+	}
+	if tc.DerivedDataBytes >= 0 {
+		detail += fmt.Sprintf(", DerivedData %.1f GB", float64(tc.DerivedDataBytes)/(1<<30))
+	}
+	return detail
+}
+
+func containersDetail(c health.Containers) string {
+	switch {
+	case !c.Installed:
+		return "No runtime detected"
+	case c.PermissionDenied:
+		return c.Runtime + " socket permission denied"
+	case !c.Reachable:
+		return c.Runtime + " daemon unreachable"
+	}
+	detail := fmt.Sprintf("%s %s, %d/%d running, %d CPU, %.1f GB RAM",
+		c.Runtime, c.ServerVersion, c.ContainersRunning, c.ContainersTotal, c.CPUs, float64(c.MemoryBytes)/(1<<30))
+	if c.VMDiskBytes > 0 {
+		detail += fmt.Sprintf(", VM disk %.0f%%", c.VMDiskPercent)
+	}
+	return detail
+}
+
+func packagesDetail(p health.Packages) string {
+	switch {
+	case p.Manager == "":
+		return "No package manager detected"
+	case p.CheckedAt.IsZero():
+		return p.Manager + ", not checked yet"
+	}
+	detail := fmt.Sprintf("%s, %d outdated", p.Manager, p.OutdatedCount)
+	if p.SecurityUpdates > 0 {
+		detail += fmt.Sprintf(" (%d security)", p.SecurityUpdates)
+	}
+	if n := len(p.DoctorWarnings); n > 0 {
+		detail += fmt.Sprintf(", %d doctor warning(s)", n)
+	}
+	return detail
+}
+
+func servicesDetail(s health.Services) string {
+	if len(s.Services) == 0 {
+		return "None configured"
+	}
+	running := 0
+	var problems []string
+	for _, svc := range s.Services {
+		if svc.State == health.ServiceRunning {
+			running++
+		} else {
+			problems = append(problems, svc.Name+" "+svc.State)
+		}
+	}
+	detail := fmt.Sprintf("%d/%d running", running, len(s.Services))
+	if len(problems) > 0 {
+		detail += " (" + strings.Join(problems, ", ") + ")"
+	}
+	return detail
+}
+
+func certificatesDetail(c health.Certificates) string {
+	if len(c.Certificates) == 0 {
+		return "None found"
+	}
+	soonest := -1
+	for i, cert := range c.Certificates {
+		if cert.NotAfter.IsZero() {
+			continue
+		}
+		if soonest < 0 || cert.NotAfter.Before(c.Certificates[soonest].NotAfter) {
+			soonest = i
+		}
+	}
+	detail := fmt.Sprintf("%d certificate(s)", len(c.Certificates))
+	if soonest >= 0 {
+		cert := c.Certificates[soonest]
+		if cert.DaysLeft < 0 {
+			detail += fmt.Sprintf(", %s expired %d days ago", cert.Name, -cert.DaysLeft)
+		} else {
+			detail += fmt.Sprintf(", next expiry %s in %d days", cert.Name, cert.DaysLeft)
+		}
+	}
+	return detail
+}
+
+func customDetail(c health.CustomResult) string {
+	detail := c.Summary
+	if c.Error != "" {
+		if detail != "" {
+			detail += ", "
+		}
+		detail += c.Error
+	}
+	if detail == "" {
+		detail = fmt.Sprintf("exit %d", c.ExitCode)
+	}
+	return detail
+}
+
+func batteryLevel(v int) string {
+	if v < 0 {
+		return "?"
+	}
+	return fmt.Sprintf("%d%%", v)
+}
+
+func cloudSyncDetail(cs health.CloudSync) string {
+	if len(cs.Providers) == 0 {
+		return "No providers detected"
+	}
+	var parts []string
+	for _, p := range cs.Providers {
+		parts = append(parts, p.Name+" "+p.State)
+	}
+	return strings.Join(parts, ", ")
+}
+
 func printSubsystem(name string, status health.Status, detail string) {
 	fmt.Printf("  %s %-14s %s\n", statusIcon(status), name, detail)
 }
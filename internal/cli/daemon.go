@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lu-zhengda/machealth/internal/daemon"
+)
+
+var daemonSocket string
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Share one cached collector with every machealth invocation",
+	Long: `Listens on a per-user Unix socket and answers check, diagnose and watch
+for every machealth process, reusing each subsystem's result until its TTL
+(daemon.ttl in the config file) expires. Other commands use the daemon
+automatically when it is running and collect directly otherwise; pass
+--no-daemon to bypass it.
+
+The socket also serves /report, /diagnose, /metrics and /healthz, e.g.
+curl --unix-socket <socket> http://localhost/healthz`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if daemonSocket != "" {
+			cfg.Daemon.Socket = daemonSocket
+		}
+		path := daemon.SocketPath(cfg)
+		ln, err := daemon.Listen(path)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		// Requests derive their context from ctx, so streams end on shutdown.
+		srv := &http.Server{
+			Handler:           daemon.Handler(cfg),
+			ReadHeaderTimeout: 10 * time.Second,
+			BaseContext:       func(net.Listener) context.Context { return ctx },
+		}
+		errc := make(chan error, 1)
+		go func() { errc <- srv.Serve(ln) }()
+		fmt.Fprintf(os.Stderr, "machealth: daemon listening on %s\n", path)
+
+		select {
+		case err := <-errc:
+			return err
+		case <-ctx.Done():
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	daemonCmd.Flags().StringVar(&daemonSocket, "socket", "", "Unix socket path (default machealth.sock in $XDG_RUNTIME_DIR or the user cache directory)")
+	rootCmd.AddCommand(daemonCmd)
+}
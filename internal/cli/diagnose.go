@@ -14,7 +14,11 @@ var diagnoseCmd = &cobra.Command{
 	Short: "Diagnose health issues with detailed explanations",
 	Long:  "Runs all health checks and provides actionable diagnoses for any non-green subsystems.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		dr := health.Diagnose()
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		dr := health.DiagnoseFrom(runCheck(cfg))
 		exitCode = health.ExitCode(dr.Report)
 
 		if humanFlag && !jsonFlag {
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lu-zhengda/machealth/internal/mcp"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Run as a Model Context Protocol server over stdio",
+	Long: `Speaks the Model Context Protocol over stdin and stdout so agents can call
+machealth as native tools:
+
+  check               full report with composite score
+  diagnose            report plus actionable diagnoses
+  check_subsystem     one subsystem's data, status and diagnoses
+  wait_until_healthy  poll until subsystems are healthy or a timeout passes
+
+The latest report is also available as the resource machealth://report/latest.
+Full checks go through the daemon when it is running.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		return mcp.New(cfg, version, runCheck).Serve(ctx, os.Stdin, os.Stdout)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+}
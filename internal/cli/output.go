@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+
+	"github.com/lu-zhengda/machealth/internal/health"
 )
 
 func printJSON(v any) error {
@@ -14,3 +17,31 @@ func printJSON(v any) error {
 	}
 	return nil
 }
+
+// writeTextfile writes r as Prometheus metrics to dir/machealth.prom. The
+// metrics go to a temporary file in the same directory that is renamed into
+// place, so the textfile collector never reads a partial file; its name does
+// not end in .prom, so the collector ignores it meanwhile.
+func writeTextfile(dir string, r health.Report) error {
+	tmp, err := os.CreateTemp(dir, ".machealth.prom.*")
+	if err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := health.WritePrometheus(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, "machealth.prom")); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	return nil
+}
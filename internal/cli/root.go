@@ -1,18 +1,27 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/lu-zhengda/machealth/internal/daemon"
+	"github.com/lu-zhengda/machealth/internal/health"
 )
 
 var (
 	// version is set via ldflags at build time.
 	version = "dev"
 
-	humanFlag bool
-	jsonFlag  bool
+	humanFlag    bool
+	jsonFlag     bool
+	configFlag   string
+	noDaemonFlag bool
 )
 
 var rootCmd = &cobra.Command{
@@ -20,8 +29,8 @@ var rootCmd = &cobra.Command{
 	Short: "macOS system health checker for AI agents",
 	Long: `machealth is a unified macOS system health checker designed for AI agent
 consumption. It provides a single-call health assessment including CPU load,
-memory pressure, disk space, thermal state, iCloud sync, battery, Time Machine,
-and network connectivity.
+memory pressure, disk space, thermal state, iCloud and third-party cloud sync,
+battery, Time Machine, network connectivity, Bluetooth, and security posture.
 
 JSON output is the default. Use --human for human-readable output.
 Exit codes: 0=healthy, 1=degraded, 2=critical.`,
@@ -44,6 +53,43 @@ Exit codes: 0=healthy, 1=degraded, 2=critical.`,
 	},
 }
 
+// loadConfig reads the --config file, or the default config file if present.
+// A missing default file is not an error; a missing explicit file is.
+func loadConfig() (health.Config, error) {
+	if configFlag != "" {
+		return health.LoadConfig(configFlag)
+	}
+	path := health.DefaultConfigPath()
+	if path == "" {
+		return health.DefaultConfig(), nil
+	}
+	cfg, err := health.LoadConfig(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return health.DefaultConfig(), nil
+	}
+	return cfg, err
+}
+
+// daemonTimeout bounds a request to the daemon, including the time it
+// spends collecting subsystems whose results have expired.
+const daemonTimeout = 30 * time.Second
+
+// runCheck returns a report from the daemon when one is running, and runs
+// the checks directly otherwise. An explicit --config bypasses the daemon,
+// which uses the default config file.
+func runCheck(cfg health.Config) health.Report {
+	if !noDaemonFlag && configFlag == "" {
+		if socket := daemon.SocketPath(cfg); socket != "" {
+			ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+			defer cancel()
+			if r, err := daemon.NewClient(socket, daemonTimeout).Report(ctx); err == nil {
+				return r
+			}
+		}
+	}
+	return health.Check(cfg)
+}
+
 // exitCode is set by commands to indicate health status.
 var exitCode int
 
@@ -60,4 +106,6 @@ func init() {
 	rootCmd.Flags().MarkHidden("generate-completion")
 	rootCmd.PersistentFlags().BoolVar(&humanFlag, "human", false, "Output in human-readable format (default is JSON)")
 	rootCmd.PersistentFlags().BoolVar(&jsonFlag, "json", false, "Output in JSON format (default; explicit form of the default)")
+	rootCmd.PersistentFlags().BoolVar(&noDaemonFlag, "no-daemon", false, "Collect directly even if machealth daemon is running")
+	rootCmd.PersistentFlags().StringVar(&configFlag, "config", "", "Path to JSON config file (default ~/.config/machealth/config.json)")
 }
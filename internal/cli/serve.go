@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lu-zhengda/machealth/internal/server"
+)
+
+var (
+	serveListen    string
+	serveInterval  time.Duration
+	serveTokenFile string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve health reports and Prometheus metrics over HTTP",
+	Long: `Runs checks on a schedule and serves the latest report over HTTP:
+
+  /metrics   Prometheus text format
+  /report    JSON report
+  /diagnose  JSON diagnosis
+  /healthz   200 healthy, 429 degraded, 503 critical
+  /events    report stream as Server-Sent Events
+  /ws        report stream over a WebSocket
+
+Add ?refresh=1 to any endpoint to run a fresh check. If --token-file or
+MACHEALTH_TOKEN is set, requests must send "Authorization: Bearer <token>".`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if serveInterval <= 0 {
+			return fmt.Errorf("--interval must be positive")
+		}
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		token := os.Getenv("MACHEALTH_TOKEN")
+		if serveTokenFile != "" {
+			b, err := os.ReadFile(serveTokenFile)
+			if err != nil {
+				return fmt.Errorf("failed to read token file: %w", err)
+			}
+			token = strings.TrimSpace(string(b))
+		}
+
+		ln, err := net.Listen("tcp", serveListen)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		s := server.New(cfg, server.Options{Interval: serveInterval, Token: token})
+		go s.Run(ctx)
+
+		// Requests derive their context from ctx, so streams end on shutdown.
+		srv := &http.Server{
+			Handler:           s.Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+			BaseContext:       func(net.Listener) context.Context { return ctx },
+		}
+		errc := make(chan error, 1)
+		go func() { errc <- srv.Serve(ln) }()
+		fmt.Fprintf(os.Stderr, "machealth: serving on http://%s (checks every %s)\n", ln.Addr(), serveInterval)
+
+		select {
+		case err := <-errc:
+			return err
+		case <-ctx.Done():
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:9876", "Address to listen on (use :9876 to accept remote scrapes)")
+	serveCmd.Flags().DurationVar(&serveInterval, "interval", 30*time.Second, "Check interval (e.g., 30s, 1m)")
+	serveCmd.Flags().StringVar(&serveTokenFile, "token-file", "", "File containing a bearer token required on every request (default $MACHEALTH_TOKEN)")
+	rootCmd.AddCommand(serveCmd)
+}
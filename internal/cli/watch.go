@@ -25,13 +25,18 @@ Use --human for a refreshing human-readable display.`,
 		sig := make(chan os.Signal, 1)
 		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
 		enc := json.NewEncoder(os.Stdout)
 
 		ticker := time.NewTicker(watchInterval)
 		defer ticker.Stop()
 
 		// Run immediately on start
-		r := health.Check()
+		r := runCheck(cfg)
 		exitCode = health.ExitCode(r)
 
 		if humanFlag && !jsonFlag {
@@ -49,7 +54,7 @@ Use --human for a refreshing human-readable display.`,
 			case <-sig:
 				return nil
 			case <-ticker.C:
-				r = health.Check()
+				r = runCheck(cfg)
 				exitCode = health.ExitCode(r)
 
 				if humanFlag && !jsonFlag {
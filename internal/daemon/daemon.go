@@ -0,0 +1,113 @@
+// Package daemon lets many machealth invocations share one cached collector
+// through a per-user Unix socket.
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/lu-zhengda/machealth/internal/health"
+	"github.com/lu-zhengda/machealth/internal/server"
+)
+
+// SocketPath returns the configured socket path, or machealth.sock in
+// $XDG_RUNTIME_DIR or the user cache directory.
+func SocketPath(cfg health.Config) string {
+	if cfg.Daemon.Socket != "" {
+		return cfg.Daemon.Socket
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "machealth.sock")
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "machealth", "machealth.sock")
+}
+
+// Listen creates the socket at path, readable only by the current user.
+// A socket left behind by a daemon that is no longer running is replaced.
+func Listen(path string) (net.Listener, error) {
+	if path == "" {
+		return nil, errors.New("no socket path")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a daemon is already listening on %s", path)
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != os.ModeSocket {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	// Create the socket without group or other permissions from the start.
+	old := syscall.Umask(0o077)
+	ln, err := net.Listen("unix", path)
+	syscall.Umask(old)
+	return ln, err
+}
+
+// Handler returns the daemon's HTTP handler: the endpoints of server.Server,
+// answered from a health.Collector so each subsystem is collected at most
+// once per TTL however many clients ask. ?refresh=1 recollects everything.
+func Handler(cfg health.Config) http.Handler {
+	var c health.Collector
+	return server.New(cfg, server.Options{Check: c.Check, ForceCheck: c.Refresh}).Handler()
+}
+
+// Client talks to a running daemon.
+type Client struct {
+	http *http.Client
+}
+
+// NewClient returns a client for the daemon listening on socket. Requests
+// fail after timeout, including the time the daemon spends collecting.
+func NewClient(socket string, timeout time.Duration) *Client {
+	var d net.Dialer
+	return &Client{http: &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}}
+}
+
+// Report fetches a report from the daemon.
+func (c *Client) Report(ctx context.Context) (health.Report, error) {
+	var r health.Report
+	err := c.get(ctx, "/report", &r)
+	return r, err
+}
+
+func (c *Client) get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://machealth"+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("daemon: %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,88 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lu-zhengda/machealth/internal/health"
+)
+
+// socketPath returns a short socket path; sun_path is limited to ~104 bytes.
+func socketPath(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "mh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "d.sock")
+}
+
+func TestListen(t *testing.T) {
+	path := socketPath(t)
+	ln, err := Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm()&0o077 != 0 {
+		t.Errorf("socket mode = %v, %v", info.Mode(), err)
+	}
+	if _, err := Listen(path); err == nil || !strings.Contains(err.Error(), "already listening") {
+		t.Errorf("second Listen() error = %v", err)
+	}
+	ln.Close()
+
+	// A stale socket from a daemon that died is replaced.
+	stale, _ := net.Listen("unix", path)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	ln, err = Listen(path)
+	if err != nil {
+		t.Fatalf("Listen() over stale socket: %v", err)
+	}
+	ln.Close()
+
+	// Anything else at the path is left alone.
+	os.WriteFile(path, []byte("keep"), 0o600)
+	if _, err := Listen(path); err == nil {
+		t.Error("Listen() replaced a regular file")
+	}
+}
+
+func TestClient(t *testing.T) {
+	path := socketPath(t)
+	ln, err := Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := health.Report{Timestamp: time.Unix(1700000000, 0).UTC(), Score: health.Score{Status: health.StatusYellow, Value: 70}}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/report" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(want)
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	got, err := NewClient(path, time.Second).Report(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Timestamp.Equal(want.Timestamp) || got.Score.Value != 70 {
+		t.Errorf("Report() = %+v", got)
+	}
+
+	if _, err := NewClient(filepath.Join(filepath.Dir(path), "none.sock"), time.Second).Report(context.Background()); err == nil {
+		t.Error("expected error with no daemon running")
+	}
+}
@@ -1,6 +1,8 @@
 package health
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
 	"regexp"
@@ -19,11 +21,17 @@ func CheckBattery() Battery {
 	}
 	parsePmsetBatt(string(out), &b)
 
-	// Detailed info via ioreg
+	// Detailed info: ioreg is fast and always read; cached system_profiler
+	// JSON then overrides the fields it carries.
 	out, err = exec.Command("/usr/sbin/ioreg", "-r", "-c", "AppleSmartBattery", "-w0").Output()
 	if err == nil {
+		b.Parser = ParserText
 		parseIoregBatt(string(out), &b)
 	}
+	out, err = cachedSystemProfiler("SPPowerDataType", true)
+	if err == nil && parsePowerJSON(out, &b) == nil {
+		b.Parser = ParserJSON
+	}
 
 	// No battery installed (desktop Mac) — always green
 	if !b.Installed {
@@ -120,6 +128,56 @@ func parseIoregBatt(s string, b *Battery) {
 	}
 }
 
+type spPowerJSON struct {
+	SPPowerDataType []struct {
+		Name       string `json:"_name"`
+		ChargeInfo struct {
+			StateOfCharge *int `json:"sppower_battery_state_of_charge"`
+		} `json:"sppower_battery_charge_info"`
+		HealthInfo struct {
+			CycleCount  int    `json:"sppower_battery_cycle_count"`
+			Condition   string `json:"sppower_battery_health"`
+			MaxCapacity string `json:"sppower_battery_health_maximum_capacity"`
+		} `json:"sppower_battery_health_info"`
+	} `json:"SPPowerDataType"`
+}
+
+// parsePowerJSON fills battery details from `system_profiler SPPowerDataType -json`.
+// Desktop Macs have no "spbattery_information" entry, which marks the battery
+// as not installed. Fields the JSON lacks (Intel Macs report no maximum
+// capacity) keep the values already in b. It returns an error if the output
+// is not usable JSON.
+func parsePowerJSON(data []byte, b *Battery) error {
+	var sp spPowerJSON
+	if err := json.Unmarshal(data, &sp); err != nil {
+		return err
+	}
+	if len(sp.SPPowerDataType) == 0 {
+		return errors.New("no SPPowerDataType entries")
+	}
+
+	b.Installed = false
+	for _, e := range sp.SPPowerDataType {
+		if e.Name != "spbattery_information" {
+			continue
+		}
+		b.Installed = true
+		if e.HealthInfo.CycleCount > 0 {
+			b.CycleCount = e.HealthInfo.CycleCount
+		}
+		if e.HealthInfo.Condition != "" {
+			b.Condition = e.HealthInfo.Condition
+		}
+		if v, err := strconv.ParseFloat(strings.TrimSuffix(e.HealthInfo.MaxCapacity, "%"), 64); err == nil {
+			b.HealthPercent = v
+		}
+		if e.ChargeInfo.StateOfCharge != nil {
+			b.Percent = *e.ChargeInfo.StateOfCharge
+		}
+	}
+	return nil
+}
+
 // DiagnoseBattery returns diagnosis for battery issues.
 func DiagnoseBattery(b Battery) *Diagnosis {
 	if b.Status == StatusGreen {
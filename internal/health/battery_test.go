@@ -102,3 +102,61 @@ func TestParseIoregBatt_NoBattery(t *testing.T) {
 		t.Errorf("Percent should not be modified when no battery, got %d", b.Percent)
 	}
 }
+
+func TestParsePowerJSON(t *testing.T) {
+	laptop := `{"SPPowerDataType": [
+		{
+			"_name": "spbattery_information",
+			"sppower_battery_charge_info": {
+				"sppower_battery_fully_charged": "FALSE",
+				"sppower_battery_is_charging": "TRUE",
+				"sppower_battery_state_of_charge": 78
+			},
+			"sppower_battery_health_info": {
+				"sppower_battery_cycle_count": 123,
+				"sppower_battery_health": "Good",
+				"sppower_battery_health_maximum_capacity": "89%"
+			}
+		},
+		{"_name": "sppower_ac_charger_information", "sppower_battery_charger_connected": "TRUE"}
+	]}`
+	b := Battery{Percent: -1}
+	if err := parsePowerJSON([]byte(laptop), &b); err != nil {
+		t.Fatalf("parsePowerJSON() error = %v", err)
+	}
+	if !b.Installed || b.Percent != 78 || b.CycleCount != 123 || b.Condition != "Good" {
+		t.Errorf("battery = %+v", b)
+	}
+	if abs(b.HealthPercent-89) > 0.1 {
+		t.Errorf("HealthPercent = %.1f, want 89", b.HealthPercent)
+	}
+
+	// Intel Macs report no maximum capacity; the ioreg value is kept.
+	intel := `{"SPPowerDataType": [{"_name": "spbattery_information",
+		"sppower_battery_health_info": {"sppower_battery_cycle_count": 351, "sppower_battery_health": "Good"}}]}`
+	b = Battery{Percent: -1}
+	parseIoregBatt(`"BatteryInstalled" = Yes
+"DesignCapacity" = 6075
+"NominalChargeCapacity" = 5225`, &b)
+	if err := parsePowerJSON([]byte(intel), &b); err != nil {
+		t.Fatalf("parsePowerJSON() error = %v", err)
+	}
+	if b.CycleCount != 351 || abs(b.HealthPercent-float64(5225)/float64(6075)*100) > 0.01 {
+		t.Errorf("Intel battery = %+v, want ioreg health kept", b)
+	}
+
+	desktop := `{"SPPowerDataType": [{"_name": "sppower_ac_charger_information"}]}`
+	b = Battery{Installed: true}
+	if err := parsePowerJSON([]byte(desktop), &b); err != nil {
+		t.Fatalf("parsePowerJSON() error = %v", err)
+	}
+	if b.Installed {
+		t.Error("desktop Mac should report no battery installed")
+	}
+
+	for _, bad := range []string{"Power:\n  Battery Information:", `{"SPPowerDataType": []}`} {
+		if err := parsePowerJSON([]byte(bad), &Battery{}); err == nil {
+			t.Errorf("parsePowerJSON(%q) should fail", bad)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package health
 
 import (
-	"os/exec"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
@@ -13,18 +15,31 @@ import (
 func CheckBluetooth() Bluetooth {
 	bt := Bluetooth{Status: StatusGreen}
 
-	out, err := exec.Command("/usr/sbin/system_profiler", "SPBluetoothDataType").Output()
-	if err != nil || len(strings.TrimSpace(string(out))) == 0 {
-		// system_profiler unavailable or returned nothing — degrade gracefully.
-		bt.Available = false
-		return bt
-	}
+	// Prefer the JSON output: it carries per-component batteries and device
+	// metadata under stable keys. Fall back to the text layout if it fails.
+	out, err := cachedSystemProfiler("SPBluetoothDataType", true)
+	if err == nil && parseBluetoothJSON(out, &bt) == nil {
+		bt.Available = true
+		bt.Parser = ParserJSON
+	} else {
+		out, err = cachedSystemProfiler("SPBluetoothDataType", false)
+		if err != nil {
+			// system_profiler unavailable or returned nothing — degrade gracefully.
+			bt.Available = false
+			return bt
+		}
 
-	bt.Available = true
-	parseBluetooth(string(out), &bt)
+		bt.Available = true
+		bt.Parser = ParserText
+		parseBluetooth(string(out), &bt)
+	}
 
 	// Status logic: Bluetooth is informational (read-only monitoring).
-	// Never returns red to avoid false criticals.
+	// Never returns red to avoid false criticals; a low input-device battery
+	// is worth a yellow so agents can warn before the keyboard dies.
+	if len(lowBatteryInputDevices(bt)) > 0 {
+		bt.Status = StatusYellow
+	}
 	return bt
 }
 
@@ -34,12 +49,177 @@ var (
 	// macOS Ventura and earlier: "Bluetooth Power: On"
 	btPowerRe = regexp.MustCompile(`(?i)^\s+Bluetooth Power:\s*(On|Off)\s*$`)
 
-	// Battery regexps covering multiple macOS key variants.
-	btBatteryLineRe = regexp.MustCompile(`(?i)(?:Device batteryPercent|Battery Level|Left Battery Level|Right Battery Level|Case Battery Level):\s*(\d+)%`)
 	// Connected property (old format): "Connected: Yes/No"
 	btConnectedPropRe = regexp.MustCompile(`(?i)^\s+Connected:\s*(Yes|No)\s*$`)
+	// Battery values: "88%"
+	btPercentRe = regexp.MustCompile(`(\d+)\s*%`)
 )
 
+// Input device minor types; a low battery on these gets a diagnosis.
+var btInputMinorTypes = map[string]bool{
+	"keyboard": true,
+	"mouse":    true,
+	"trackpad": true,
+}
+
+// btLowBatteryPercent is the level at or below which a connected input
+// device's battery is reported as low.
+const btLowBatteryPercent = 20
+
+func newBluetoothDevice(name string, connected bool) *BluetoothDevice {
+	return &BluetoothDevice{
+		Name:           name,
+		Connected:      connected,
+		BatteryPercent: -1,
+		BatteryMain:    -1,
+		BatteryLeft:    -1,
+		BatteryRight:   -1,
+		BatteryCase:    -1,
+	}
+}
+
+// finishBluetoothDevice sets BatteryPercent to the highest component level.
+func finishBluetoothDevice(d *BluetoothDevice) {
+	for _, v := range []int{d.BatteryMain, d.BatteryLeft, d.BatteryRight, d.BatteryCase} {
+		if v > d.BatteryPercent {
+			d.BatteryPercent = v
+		}
+	}
+}
+
+func parseBatteryPercent(s string) int {
+	if m := btPercentRe.FindStringSubmatch(s); len(m) >= 2 {
+		if v, err := strconv.Atoi(m[1]); err == nil {
+			return v
+		}
+	}
+	return -1
+}
+
+// applyBluetoothProperty sets the device field for a "Key: Value" line from
+// the text output. Unknown keys are ignored.
+func applyBluetoothProperty(d *BluetoothDevice, line string) {
+	key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+	if !ok {
+		return
+	}
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case "device batterypercent", "battery level", "main battery level":
+		d.BatteryMain = parseBatteryPercent(value)
+	case "left battery level":
+		d.BatteryLeft = parseBatteryPercent(value)
+	case "right battery level":
+		d.BatteryRight = parseBatteryPercent(value)
+	case "case battery level":
+		d.BatteryCase = parseBatteryPercent(value)
+	case "minor type":
+		d.MinorType = strings.ToLower(value)
+	case "address":
+		d.Address = value
+	case "vendor id":
+		d.VendorID = value
+	case "product id":
+		d.ProductID = value
+	case "firmware version":
+		d.Firmware = value
+	}
+}
+
+// spBluetoothDevice holds the per-device keys of `system_profiler
+// SPBluetoothDataType -json`.
+type spBluetoothDevice struct {
+	Address      string `json:"device_address"`
+	MinorType    string `json:"device_minorType"`
+	VendorID     string `json:"device_vendorID"`
+	ProductID    string `json:"device_productID"`
+	Firmware     string `json:"device_firmwareVersion"`
+	BatteryMain  string `json:"device_batteryLevelMain"`
+	BatteryLeft  string `json:"device_batteryLevelLeft"`
+	BatteryRight string `json:"device_batteryLevelRight"`
+	BatteryCase  string `json:"device_batteryLevelCase"`
+	// Ventura and earlier.
+	BatteryPercent string `json:"device_batteryPercent"`
+	IsConnected    string `json:"device_isconnected"`
+}
+
+type spBluetoothJSON struct {
+	SPBluetoothDataType []struct {
+		// Sonoma and later.
+		Controller struct {
+			State string `json:"controller_state"`
+		} `json:"controller_properties"`
+		Connected    []map[string]spBluetoothDevice `json:"device_connected"`
+		NotConnected []map[string]spBluetoothDevice `json:"device_not_connected"`
+
+		// Ventura and earlier.
+		LocalDevice struct {
+			Power string `json:"general_power"`
+		} `json:"local_device_title"`
+		Devices []map[string]spBluetoothDevice `json:"device_title"`
+	} `json:"SPBluetoothDataType"`
+}
+
+// parseBluetoothJSON fills bt from `system_profiler SPBluetoothDataType -json`.
+// It returns an error if the output is not JSON or holds no controller, so the
+// caller can fall back to the text parser.
+func parseBluetoothJSON(data []byte, bt *Bluetooth) error {
+	var sp spBluetoothJSON
+	if err := json.Unmarshal(data, &sp); err != nil {
+		return err
+	}
+	if len(sp.SPBluetoothDataType) == 0 {
+		return errors.New("no SPBluetoothDataType entries")
+	}
+	entry := sp.SPBluetoothDataType[0]
+
+	state := entry.Controller.State
+	if state == "" {
+		state = entry.LocalDevice.Power
+	}
+	if state == "" {
+		return errors.New("no controller state")
+	}
+	// Values look like "attrib_on" / "attrib_On".
+	bt.Enabled = strings.EqualFold(strings.TrimPrefix(state, "attrib_"), "on")
+	if !bt.Enabled {
+		return nil
+	}
+
+	add := func(list []map[string]spBluetoothDevice, connected func(spBluetoothDevice) bool) {
+		for _, m := range list {
+			for name, sd := range m {
+				d := newBluetoothDevice(name, connected(sd))
+				d.Address = sd.Address
+				d.MinorType = strings.ToLower(sd.MinorType)
+				d.VendorID = sd.VendorID
+				d.ProductID = sd.ProductID
+				d.Firmware = sd.Firmware
+				d.BatteryMain = parseBatteryPercent(sd.BatteryMain)
+				if d.BatteryMain < 0 {
+					d.BatteryMain = parseBatteryPercent(sd.BatteryPercent)
+				}
+				d.BatteryLeft = parseBatteryPercent(sd.BatteryLeft)
+				d.BatteryRight = parseBatteryPercent(sd.BatteryRight)
+				d.BatteryCase = parseBatteryPercent(sd.BatteryCase)
+				finishBluetoothDevice(d)
+
+				bt.Devices = append(bt.Devices, *d)
+				if d.Connected {
+					bt.ConnectedDeviceCount++
+				}
+			}
+		}
+	}
+	add(entry.Connected, func(spBluetoothDevice) bool { return true })
+	add(entry.NotConnected, func(spBluetoothDevice) bool { return false })
+	add(entry.Devices, func(sd spBluetoothDevice) bool {
+		return strings.EqualFold(strings.TrimPrefix(sd.IsConnected, "attrib_"), "yes")
+	})
+
+	return nil
+}
+
 // parseBluetooth fills bt from the text output of `system_profiler SPBluetoothDataType`.
 // It handles two output layouts:
 //   - Legacy (macOS ≤ Ventura): "Bluetooth Power: On" + "Devices (Paired...):" section
@@ -108,6 +288,7 @@ func parseBluetoothModern(lines []string, bt *Bluetooth) {
 
 	flushDevice := func() {
 		if currentDevice != nil && currentDevice.Name != "" {
+			finishBluetoothDevice(currentDevice)
 			bt.Devices = append(bt.Devices, *currentDevice)
 			if currentDevice.Connected {
 				bt.ConnectedDeviceCount++
@@ -152,24 +333,13 @@ func parseBluetoothModern(lines []string, bt *Bluetooth) {
 		if indent >= 9 && indent <= 13 && strings.HasSuffix(trimmed, ":") {
 			flushDevice()
 			name := strings.TrimSuffix(trimmed, ":")
-			currentDevice = &BluetoothDevice{
-				Name:           name,
-				Connected:      currentSection == sectionConnected,
-				BatteryPercent: -1,
-			}
+			currentDevice = newBluetoothDevice(name, currentSection == sectionConnected)
 			continue
 		}
 
 		// Device property lines (indent ≥ 14)
 		if currentDevice != nil && indent >= 14 {
-			if m := btBatteryLineRe.FindStringSubmatch(line); len(m) >= 2 {
-				if v, err := strconv.Atoi(m[1]); err == nil {
-					// Keep highest battery value found (Left/Right/Case variants).
-					if v > currentDevice.BatteryPercent {
-						currentDevice.BatteryPercent = v
-					}
-				}
-			}
+			applyBluetoothProperty(currentDevice, line)
 		}
 	}
 	flushDevice()
@@ -183,6 +353,7 @@ func parseBluetoothLegacy(lines []string, bt *Bluetooth) {
 
 	flushDevice := func() {
 		if currentDevice != nil && currentDevice.Name != "" {
+			finishBluetoothDevice(currentDevice)
 			bt.Devices = append(bt.Devices, *currentDevice)
 			if currentDevice.Connected {
 				bt.ConnectedDeviceCount++
@@ -215,7 +386,7 @@ func parseBluetoothLegacy(lines []string, bt *Bluetooth) {
 		if indent >= 8 && indent <= 12 && strings.HasSuffix(trimmed, ":") {
 			flushDevice()
 			name := strings.TrimSuffix(trimmed, ":")
-			currentDevice = &BluetoothDevice{Name: name, BatteryPercent: -1}
+			currentDevice = newBluetoothDevice(name, false)
 			continue
 		}
 
@@ -224,18 +395,25 @@ func parseBluetoothLegacy(lines []string, bt *Bluetooth) {
 			if m := btConnectedPropRe.FindStringSubmatch(line); len(m) >= 2 {
 				currentDevice.Connected = strings.EqualFold(m[1], "yes")
 			}
-			if m := btBatteryLineRe.FindStringSubmatch(line); len(m) >= 2 {
-				if v, err := strconv.Atoi(m[1]); err == nil {
-					if v > currentDevice.BatteryPercent {
-						currentDevice.BatteryPercent = v
-					}
-				}
-			}
+			applyBluetoothProperty(currentDevice, line)
 		}
 	}
 	flushDevice()
 }
 
+// lowBatteryInputDevices returns connected keyboards, mice and trackpads whose
+// battery is at or below btLowBatteryPercent.
+func lowBatteryInputDevices(bt Bluetooth) []BluetoothDevice {
+	var low []BluetoothDevice
+	for _, d := range bt.Devices {
+		if d.Connected && btInputMinorTypes[d.MinorType] &&
+			d.BatteryPercent >= 0 && d.BatteryPercent <= btLowBatteryPercent {
+			low = append(low, d)
+		}
+	}
+	return low
+}
+
 // DiagnoseBluetooth returns a diagnosis for Bluetooth issues.
 // Currently Bluetooth is informational — it only produces diagnoses for
 // yellow status; it never returns red severity to avoid false criticals.
@@ -243,6 +421,19 @@ func DiagnoseBluetooth(bt Bluetooth) *Diagnosis {
 	if bt.Status == StatusGreen {
 		return nil
 	}
+	if low := lowBatteryInputDevices(bt); len(low) > 0 {
+		var names []string
+		for _, d := range low {
+			names = append(names, fmt.Sprintf("%s (%s, %d%%)", d.Name, d.MinorType, d.BatteryPercent))
+		}
+		return &Diagnosis{
+			Subsystem: "bluetooth",
+			Severity:  StatusYellow,
+			Summary:   "Bluetooth input device battery is low",
+			Detail:    "Low battery: " + strings.Join(names, ", ") + ".",
+			Action:    "Charge or replace the batteries soon; the device may disconnect without warning",
+		}
+	}
 	return &Diagnosis{
 		Subsystem: "bluetooth",
 		Severity:  StatusYellow,
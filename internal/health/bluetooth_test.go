@@ -1,6 +1,9 @@
 package health
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 // Modern macOS (Sonoma/Sequoia) format with Connected: / Not Connected: sections.
 const btSampleModern = `Bluetooth:
@@ -286,3 +289,162 @@ func TestDiagnoseBluetooth_Yellow(t *testing.T) {
 		t.Errorf("subsystem = %s, want bluetooth", d.Subsystem)
 	}
 }
+
+func TestParseBluetooth_ModernComponents(t *testing.T) {
+	bt := Bluetooth{}
+	parseBluetooth(btSampleModern, &bt)
+
+	var airpods, kbd BluetoothDevice
+	for _, d := range bt.Devices {
+		switch d.Name {
+		case "AirPods Pro":
+			airpods = d
+		case "Keychron K2":
+			kbd = d
+		}
+	}
+	if airpods.BatteryLeft != 88 || airpods.BatteryRight != 91 || airpods.BatteryCase != 74 {
+		t.Errorf("AirPods components = L%d R%d C%d, want L88 R91 C74",
+			airpods.BatteryLeft, airpods.BatteryRight, airpods.BatteryCase)
+	}
+	if airpods.BatteryMain != -1 {
+		t.Errorf("AirPods BatteryMain = %d, want -1", airpods.BatteryMain)
+	}
+	if airpods.Address != "74:15:F5:4E:D0:50" {
+		t.Errorf("AirPods Address = %q", airpods.Address)
+	}
+	if kbd.MinorType != "keyboard" {
+		t.Errorf("Keychron MinorType = %q, want keyboard", kbd.MinorType)
+	}
+}
+
+const btSampleJSON = `{
+  "SPBluetoothDataType" : [
+    {
+      "controller_properties" : {
+        "controller_address" : "D0:11:E5:3A:9D:DF",
+        "controller_state" : "attrib_on"
+      },
+      "device_connected" : [
+        {
+          "AirPods Pro" : {
+            "device_address" : "74:15:F5:4E:D0:50",
+            "device_batteryLevelCase" : "74%",
+            "device_batteryLevelLeft" : "3%",
+            "device_batteryLevelRight" : "91%",
+            "device_firmwareVersion" : "6A321",
+            "device_minorType" : "Headphones",
+            "device_productID" : "0x2014",
+            "device_vendorID" : "0x004C"
+          }
+        },
+        {
+          "Magic Trackpad" : {
+            "device_address" : "88:E9:FE:11:22:33",
+            "device_batteryLevelMain" : "12%",
+            "device_minorType" : "Trackpad",
+            "device_productID" : "0x0265",
+            "device_vendorID" : "0x004C"
+          }
+        }
+      ],
+      "device_not_connected" : [
+        {
+          "MX Master 3" : {
+            "device_address" : "EE:F8:FA:CA:8A:28",
+            "device_minorType" : "Mouse"
+          }
+        }
+      ]
+    }
+  ]
+}`
+
+func TestParseBluetoothJSON(t *testing.T) {
+	bt := Bluetooth{}
+	if err := parseBluetoothJSON([]byte(btSampleJSON), &bt); err != nil {
+		t.Fatalf("parseBluetoothJSON() error = %v", err)
+	}
+	if !bt.Enabled {
+		t.Error("Enabled should be true")
+	}
+	if bt.ConnectedDeviceCount != 2 || len(bt.Devices) != 3 {
+		t.Fatalf("connected=%d devices=%d, want 2/3", bt.ConnectedDeviceCount, len(bt.Devices))
+	}
+
+	airpods := bt.Devices[0]
+	if airpods.BatteryLeft != 3 || airpods.BatteryRight != 91 || airpods.BatteryCase != 74 {
+		t.Errorf("AirPods components = L%d R%d C%d", airpods.BatteryLeft, airpods.BatteryRight, airpods.BatteryCase)
+	}
+	if airpods.BatteryPercent != 91 {
+		t.Errorf("AirPods BatteryPercent = %d, want 91", airpods.BatteryPercent)
+	}
+	if airpods.MinorType != "headphones" || airpods.VendorID != "0x004C" || airpods.ProductID != "0x2014" || airpods.Firmware != "6A321" {
+		t.Errorf("AirPods metadata = %+v", airpods)
+	}
+
+	trackpad := bt.Devices[1]
+	if trackpad.BatteryMain != 12 || trackpad.MinorType != "trackpad" {
+		t.Errorf("trackpad = %+v", trackpad)
+	}
+
+	mouse := bt.Devices[2]
+	if mouse.Connected || mouse.BatteryPercent != -1 {
+		t.Errorf("mouse = %+v, want disconnected with no battery", mouse)
+	}
+}
+
+func TestParseBluetoothJSON_LegacyAndErrors(t *testing.T) {
+	legacy := `{"SPBluetoothDataType": [{
+		"local_device_title": {"general_power": "attrib_On"},
+		"device_title": [
+			{"Magic Keyboard": {"device_isconnected": "attrib_Yes", "device_batteryPercent": "64%", "device_minorType": "Keyboard"}},
+			{"Old Mouse": {"device_isconnected": "attrib_No"}}
+		]
+	}]}`
+	bt := Bluetooth{}
+	if err := parseBluetoothJSON([]byte(legacy), &bt); err != nil {
+		t.Fatalf("parseBluetoothJSON() error = %v", err)
+	}
+	if !bt.Enabled || bt.ConnectedDeviceCount != 1 || len(bt.Devices) != 2 {
+		t.Errorf("legacy parse = %+v", bt)
+	}
+	if bt.Devices[0].BatteryMain != 64 {
+		t.Errorf("Magic Keyboard BatteryMain = %d, want 64", bt.Devices[0].BatteryMain)
+	}
+
+	off := Bluetooth{}
+	if err := parseBluetoothJSON([]byte(`{"SPBluetoothDataType": [{"controller_properties": {"controller_state": "attrib_off"}}]}`), &off); err != nil {
+		t.Fatalf("parseBluetoothJSON() error = %v", err)
+	}
+	if off.Enabled {
+		t.Error("Enabled should be false")
+	}
+
+	for _, bad := range []string{"not json", `{"SPBluetoothDataType": []}`, `{"SPBluetoothDataType": [{}]}`} {
+		if err := parseBluetoothJSON([]byte(bad), &Bluetooth{}); err == nil {
+			t.Errorf("parseBluetoothJSON(%q) should fail", bad)
+		}
+	}
+}
+
+func TestDiagnoseBluetooth_LowInputBattery(t *testing.T) {
+	bt := Bluetooth{Available: true, Enabled: true}
+	if err := parseBluetoothJSON([]byte(btSampleJSON), &bt); err != nil {
+		t.Fatal(err)
+	}
+
+	low := lowBatteryInputDevices(bt)
+	if len(low) != 1 || low[0].Name != "Magic Trackpad" {
+		t.Fatalf("lowBatteryInputDevices = %v, want [Magic Trackpad]", low)
+	}
+
+	bt.Status = StatusYellow
+	d := DiagnoseBluetooth(bt)
+	if d == nil {
+		t.Fatal("expected non-nil diagnosis")
+	}
+	if d.Severity != StatusYellow || !strings.Contains(d.Detail, "Magic Trackpad (trackpad, 12%)") {
+		t.Errorf("diagnosis = %+v", d)
+	}
+}
@@ -0,0 +1,125 @@
+package health
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// Slow commands (softwareupdate, brew) are never run inline by a check.
+// Their output is cached under the user cache directory and refreshed by a
+// detached background process once it is older than the configured TTL, so
+// a check reads the last known result immediately and the next check after
+// the refresh sees new data.
+
+// refreshLockTTL is how long an in-flight refresh blocks another one.
+const refreshLockTTL = 15 * time.Minute
+
+// cacheDir returns the directory for cached command output.
+func cacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "machealth")
+}
+
+// readCache returns the cached output for name and when it was written.
+// stale is true when the entry is missing or older than ttl.
+func readCache(dir, name string, ttl time.Duration) (data []byte, fetched time.Time, stale bool) {
+	path := filepath.Join(dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, true
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, true
+	}
+	return data, info.ModTime(), time.Since(info.ModTime()) > ttl
+}
+
+// cachedOutput returns the cached output for name, starting a background
+// refresh with argv when it is stale.
+func cachedOutput(dir, name string, ttl time.Duration, argv ...string) ([]byte, time.Time) {
+	data, fetched, stale := readCache(dir, name, ttl)
+	if stale {
+		refreshCacheAsync(dir, name, argv...)
+	}
+	return data, fetched
+}
+
+// writeCache stores data as the cached output for name, atomically so a
+// concurrent readCache never sees a partial file.
+func writeCache(dir, name string, data []byte) {
+	if dir == "" || os.MkdirAll(dir, 0o755) != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(dir, name+".*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil || os.Rename(tmp.Name(), filepath.Join(dir, name)) != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// refreshCacheAsync starts argv in a detached process group that writes its
+// stdout to dir/name atomically. It returns false without starting anything
+// if another refresh for name is already in flight.
+func refreshCacheAsync(dir, name string, argv ...string) bool {
+	if dir == "" || len(argv) == 0 {
+		return false
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return false
+	}
+
+	lock := filepath.Join(dir, name+".lock")
+	if !acquireRefreshLock(lock) {
+		return false
+	}
+
+	// The shell outlives machealth, so the result lands even after exit.
+	script := `"$0" "$@" > "$MH_TMP" 2>/dev/null && mv -f "$MH_TMP" "$MH_OUT"; rm -f "$MH_TMP" "$MH_LOCK"`
+	cmd := exec.Command("/bin/sh", append([]string{"-c", script}, argv...)...)
+	cmd.Env = append(os.Environ(),
+		"MH_OUT="+filepath.Join(dir, name),
+		"MH_TMP="+filepath.Join(dir, name+".tmp"),
+		"MH_LOCK="+lock,
+	)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		os.Remove(lock)
+		return false
+	}
+	cmd.Process.Release()
+	return true
+}
+
+// acquireRefreshLock creates lock exclusively, so only one of several
+// concurrent checks starts a refresh. A lock older than refreshLockTTL was
+// left by a refresh that died and is taken over.
+func acquireRefreshLock(lock string) bool {
+	for range 2 {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.Close()
+			return true
+		}
+		info, err := os.Stat(lock)
+		if err == nil && time.Since(info.ModTime()) < refreshLockTTL {
+			return false
+		}
+		if err := os.Remove(lock); err != nil && !os.IsNotExist(err) {
+			return false
+		}
+	}
+	return false
+}
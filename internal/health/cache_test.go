@@ -0,0 +1,94 @@
+package health
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadCache(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, fetched, stale := readCache(dir, "missing.txt", time.Hour); !stale || !fetched.IsZero() {
+		t.Errorf("missing entry: fetched=%v stale=%v, want zero/true", fetched, stale)
+	}
+
+	path := filepath.Join(dir, "entry.txt")
+	if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, fetched, stale := readCache(dir, "entry.txt", time.Hour)
+	if string(data) != "data" || fetched.IsZero() || stale {
+		t.Errorf("fresh entry: data=%q fetched=%v stale=%v", data, fetched, stale)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, stale := readCache(dir, "entry.txt", time.Hour); !stale {
+		t.Error("entry older than ttl should be stale")
+	}
+}
+
+func TestRefreshCacheAsync(t *testing.T) {
+	dir := t.TempDir()
+
+	if !refreshCacheAsync(dir, "echo.txt", "/bin/echo", "hello") {
+		t.Fatal("refreshCacheAsync() = false, want true")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, _, _ := readCache(dir, "echo.txt", time.Hour)
+		if string(data) == "hello\n" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("cache not written, got %q", data)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// A fresh lock blocks a concurrent refresh.
+	if err := os.WriteFile(filepath.Join(dir, "busy.txt.lock"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if refreshCacheAsync(dir, "busy.txt", "/bin/echo", "x") {
+		t.Error("refreshCacheAsync() should not start while a refresh is in flight")
+	}
+}
+
+func TestAcquireRefreshLock(t *testing.T) {
+	lock := filepath.Join(t.TempDir(), "entry.lock")
+
+	if !acquireRefreshLock(lock) {
+		t.Fatal("first acquire should succeed")
+	}
+	if acquireRefreshLock(lock) {
+		t.Error("second acquire should fail while the lock is fresh")
+	}
+
+	old := time.Now().Add(-2 * refreshLockTTL)
+	if err := os.Chtimes(lock, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if !acquireRefreshLock(lock) {
+		t.Error("a stale lock should be taken over")
+	}
+}
+
+func TestWriteCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "machealth")
+
+	writeCache(dir, "entry.txt", []byte("v1"))
+	writeCache(dir, "entry.txt", []byte("v2"))
+	data, fetched, stale := readCache(dir, "entry.txt", time.Hour)
+	if string(data) != "v2" || fetched.IsZero() || stale {
+		t.Errorf("written entry: data=%q fetched=%v stale=%v", data, fetched, stale)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
@@ -0,0 +1,330 @@
+package health
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Certificate sources.
+const (
+	CertSourceKeychain = "keychain"
+	CertSourceFile     = "file"
+	CertSourceEndpoint = "endpoint"
+)
+
+// codesignCacheName is the cache entry for code signing identities and the
+// keychain certificates needed to read their expiry.
+const codesignCacheName = "codesigning-identities.txt"
+
+// cssmCertExpired is the error `security find-identity` prints next to an
+// identity whose certificate has expired.
+const cssmCertExpired = "CSSMERR_TP_CERT_EXPIRED"
+
+var errNoCertificate = errors.New("no certificate found")
+
+// CheckCertificates reports days until expiry for code signing identities in
+// the keychain, configured PEM files and configured TLS endpoints. Keychain
+// enumeration is cached and refreshed in the background; endpoints are
+// dialled in parallel, bounded by the configured timeout. A certificate
+// expiring within the yellow/red thresholds (or expired) degrades status, as
+// does a file or endpoint that cannot be read (yellow). Keychain identities
+// sharing a name are collapsed to the one that expires last, so a renewed
+// identity is rated rather than the expired one it replaced.
+func CheckCertificates(cfg CertificatesConfig) Certificates {
+	c := Certificates{Status: StatusGreen}
+	now := time.Now()
+
+	if cfg.Keychain {
+		if _, err := os.Stat("/usr/bin/security"); err == nil {
+			// Without -v, so identities that have already expired are listed too.
+			data, fetched := cachedOutput(cacheDir(), codesignCacheName, time.Duration(cfg.CacheTTL),
+				"/bin/sh", "-c", "/usr/bin/security find-identity -p codesigning && echo '--- certificates' && /usr/bin/security find-certificate -a -Z -p")
+			if !fetched.IsZero() {
+				c.Certificates = append(c.Certificates, parseCodesigningIdentities(string(data), now)...)
+			}
+		}
+	}
+
+	for _, path := range cfg.Files {
+		c.Certificates = append(c.Certificates, checkCertFile(expandHome(path), now))
+	}
+
+	endpoints := make([]CertificateInfo, len(cfg.Endpoints))
+	var wg sync.WaitGroup
+	for i, ep := range cfg.Endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			endpoints[i] = checkCertEndpoint(ep, time.Duration(cfg.Timeout), now)
+		}()
+	}
+	wg.Wait()
+	c.Certificates = append(c.Certificates, endpoints...)
+
+	for i := range c.Certificates {
+		cert := &c.Certificates[i]
+		cert.Status = certStatus(*cert, cfg)
+		if cert.Status == StatusRed || (cert.Status == StatusYellow && c.Status == StatusGreen) {
+			c.Status = cert.Status
+		}
+	}
+	return c
+}
+
+var findIdentityRe = regexp.MustCompile(`^\s*\d+\)\s+([0-9A-F]{40})\s+"([^"]*)"(?:\s+\((\S+)\))?`)
+
+// parseCodesigningIdentities parses `security find-identity -p codesigning`
+// followed by a "--- certificates" marker and `security find-certificate -a -Z -p`,
+// which prints each certificate's SHA-1 hash before its PEM:
+//
+//	Policy: Code Signing
+//	  Matching identities
+//	  1) 0123...CDEF "Apple Development: Jane Doe (ABCDE12345)"
+//	  2) 4567...89AB "Developer ID Application: Corp (TEAMID)" (CSSMERR_TP_CERT_EXPIRED)
+//	--- certificates
+//	SHA-1 hash: 0123...CDEF
+//	-----BEGIN CERTIFICATE-----
+//
+// Identities are listed twice (matching and valid); each is reported once,
+// and identities with the same name are collapsed by collapseIdentities.
+func parseCodesigningIdentities(s string, now time.Time) []CertificateInfo {
+	identities, certs, _ := strings.Cut(s, "--- certificates")
+
+	byHash := map[string]*x509.Certificate{}
+	rest := []byte(certs)
+	for {
+		block, next := pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		// The hash line precedes the PEM block in the bytes just skipped.
+		preamble := string(rest[:len(rest)-len(next)])
+		if i := strings.LastIndex(preamble, "SHA-1 hash:"); i >= 0 {
+			hash, _, _ := strings.Cut(preamble[i+len("SHA-1 hash:"):], "\n")
+			if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+				byHash[strings.TrimSpace(hash)] = cert
+			}
+		}
+		rest = next
+	}
+
+	var out []CertificateInfo
+	seen := map[string]bool{}
+	for _, line := range strings.Split(identities, "\n") {
+		m := findIdentityRe.FindStringSubmatch(line)
+		if len(m) < 3 || seen[m[1]] {
+			continue
+		}
+		seen[m[1]] = true
+		info := CertificateInfo{Source: CertSourceKeychain, Name: m[2], Location: m[1]}
+		if len(m) >= 4 && m[3] != "" {
+			info.Error = m[3]
+		}
+		if cert, ok := byHash[m[1]]; ok {
+			applyCertificate(&info, cert, now)
+		} else if info.Error == "" {
+			info.Error = "certificate not found in keychain"
+		}
+		out = append(out, info)
+	}
+	return collapseIdentities(out)
+}
+
+// collapseIdentities keeps one identity per name, the one that expires last,
+// and counts the others as superseded. Renewing a certificate leaves the old
+// identity in the keychain, and signing picks the newest one.
+func collapseIdentities(ids []CertificateInfo) []CertificateInfo {
+	var out []CertificateInfo
+	index := map[string]int{}
+	for _, id := range ids {
+		i, ok := index[id.Name]
+		if !ok {
+			index[id.Name] = len(out)
+			out = append(out, id)
+			continue
+		}
+		superseded := out[i].Superseded + 1
+		if id.NotAfter.After(out[i].NotAfter) {
+			out[i] = id
+		}
+		out[i].Superseded = superseded
+	}
+	return out
+}
+
+// checkCertFile reads a PEM file and reports the certificate in it that
+// expires first.
+func checkCertFile(path string, now time.Time) CertificateInfo {
+	info := CertificateInfo{Source: CertSourceFile, Location: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	certs, err := parsePEMCertificates(data)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	applyCertificate(&info, earliestExpiry(certs), now)
+	return info
+}
+
+// parsePEMCertificates returns every CERTIFICATE block in data.
+func parsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			break
+		}
+		data = rest
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errNoCertificate
+	}
+	return certs, nil
+}
+
+func earliestExpiry(certs []*x509.Certificate) *x509.Certificate {
+	sort.Slice(certs, func(i, j int) bool { return certs[i].NotAfter.Before(certs[j].NotAfter) })
+	return certs[0]
+}
+
+// checkCertEndpoint performs a TLS handshake with endpoint ("host" or
+// "host:port", default 443) and reports the leaf certificate. Verification is
+// skipped so that expired or self-signed certificates can still be reported.
+func checkCertEndpoint(endpoint string, timeout time.Duration, now time.Time) CertificateInfo {
+	info := CertificateInfo{Source: CertSourceEndpoint, Location: endpoint}
+	addr := endpoint
+	if _, _, err := net.SplitHostPort(endpoint); err != nil {
+		addr = net.JoinHostPort(endpoint, "443")
+	}
+	host, _, _ := net.SplitHostPort(addr)
+
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	defer conn.Close()
+
+	peers := conn.ConnectionState().PeerCertificates
+	if len(peers) == 0 {
+		info.Error = errNoCertificate.Error()
+		return info
+	}
+	applyCertificate(&info, peers[0], now)
+	return info
+}
+
+func applyCertificate(info *CertificateInfo, cert *x509.Certificate, now time.Time) {
+	if info.Name == "" {
+		info.Name = cert.Subject.CommonName
+		if info.Name == "" {
+			info.Name = cert.Subject.String()
+		}
+	}
+	info.Issuer = cert.Issuer.CommonName
+	info.NotAfter = cert.NotAfter.UTC()
+	info.DaysLeft = int(cert.NotAfter.Sub(now).Hours() / 24)
+	if cert.NotAfter.Before(now) {
+		// Round towards "more expired" so a certificate expired an hour ago is -1.
+		info.DaysLeft = min(info.DaysLeft, -1)
+	}
+}
+
+// certStatus rates one certificate against the expiry thresholds.
+func certStatus(c CertificateInfo, cfg CertificatesConfig) Status {
+	switch {
+	case c.Error == cssmCertExpired:
+		return StatusRed
+	case c.NotAfter.IsZero():
+		return StatusYellow
+	case c.DaysLeft < 0, c.DaysLeft < cfg.RedDays:
+		return StatusRed
+	case c.DaysLeft < cfg.YellowDays:
+		return StatusYellow
+	default:
+		return StatusGreen
+	}
+}
+
+// DiagnoseCertificates returns one diagnosis per expiring, expired or
+// unreadable certificate.
+func DiagnoseCertificates(c Certificates) []Diagnosis {
+	if c.Status == StatusGreen {
+		return nil
+	}
+
+	var diags []Diagnosis
+	for _, cert := range c.Certificates {
+		if cert.Status == StatusGreen {
+			continue
+		}
+		d := Diagnosis{
+			Subsystem: "certificates",
+			Severity:  cert.Status,
+		}
+		name := cert.Name
+		if name == "" {
+			name = cert.Location
+		}
+
+		switch {
+		case cert.Error == cssmCertExpired && cert.NotAfter.IsZero():
+			d.Summary = fmt.Sprintf("Certificate %s has expired", name)
+			d.Detail = fmt.Sprintf("security reports %s for %s.", cert.Error, name)
+		case cert.NotAfter.IsZero():
+			d.Summary = fmt.Sprintf("Certificate %s could not be read", name)
+			d.Detail = fmt.Sprintf("%s %s: %s.", cert.Source, cert.Location, cert.Error)
+			d.Action = "Check the path or endpoint in the config and that it serves a PEM/TLS certificate"
+			diags = append(diags, d)
+			continue
+		case cert.DaysLeft < 0:
+			d.Summary = fmt.Sprintf("Certificate %s has expired", name)
+			d.Detail = fmt.Sprintf("%s expired on %s (%d days ago).", name, cert.NotAfter.Format("2006-01-02"), -cert.DaysLeft)
+		default:
+			d.Summary = fmt.Sprintf("Certificate %s expires in %d days", name, cert.DaysLeft)
+			d.Detail = fmt.Sprintf("%s expires on %s.", name, cert.NotAfter.Format("2006-01-02"))
+		}
+		d.Detail += fmt.Sprintf(" Source: %s %s", cert.Source, cert.Location)
+		if cert.Issuer != "" {
+			d.Detail += ", issued by " + cert.Issuer
+		}
+		d.Detail += "."
+
+		switch cert.Source {
+		case CertSourceKeychain:
+			d.Action = "Create a new certificate in Xcode > Settings > Accounts > Manage Certificates (or the Apple Developer portal), install it on build machines and delete the old identity from the keychain"
+		case CertSourceFile:
+			d.Action = fmt.Sprintf("Renew the certificate and replace %s", cert.Location)
+		default:
+			d.Action = fmt.Sprintf("Renew the certificate served by %s", cert.Location)
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
@@ -1,38 +1,110 @@
 package health
 
 import (
+	"fmt"
+	"maps"
+	"slices"
 	"sync"
 	"time"
 )
 
+// subsystem collects one part of a Report. name is the subsystem's key in
+// the report JSON.
+type subsystem struct {
+	name  string
+	check func(cfg Config, r *Report)
+}
+
+// subsystems lists every subsystem in report order.
+var subsystems = []subsystem{
+	{"cpu", func(cfg Config, r *Report) { r.CPU = CheckCPU() }},
+	{"memory", func(cfg Config, r *Report) { r.Memory = CheckMemory() }},
+	{"disk", func(cfg Config, r *Report) { r.Disk = CheckDisk() }},
+	{"thermal", func(cfg Config, r *Report) { r.Thermal = CheckThermal() }},
+	{"icloud", func(cfg Config, r *Report) { r.ICloud = CheckICloud(cfg.ICloud) }},
+	{"cloudsync", func(cfg Config, r *Report) { r.CloudSync = CheckCloudSync() }},
+	{"battery", func(cfg Config, r *Report) { r.Battery = CheckBattery() }},
+	{"timemachine", func(cfg Config, r *Report) { r.TimeMachine = CheckTimeMachine() }},
+	{"network", func(cfg Config, r *Report) { r.Network = CheckNetwork(cfg.Network) }},
+	{"bluetooth", func(cfg Config, r *Report) { r.Bluetooth = CheckBluetooth() }},
+	{"security", func(cfg Config, r *Report) { r.Security = CheckSecurity() }},
+	{"os", func(cfg Config, r *Report) { r.OS = CheckOS(cfg.OS) }},
+	{"stability", func(cfg Config, r *Report) { r.Stability = CheckStability(cfg.Stability) }},
+	{"crashes", func(cfg Config, r *Report) { r.Crashes = CheckCrashes(cfg.Crashes) }},
+	{"power", func(cfg Config, r *Report) { r.Power = CheckPower(cfg.Power) }},
+	{"limits", func(cfg Config, r *Report) { r.Limits = CheckLimits(cfg.Limits) }},
+	{"processes", func(cfg Config, r *Report) { r.Processes = CheckProcesses(cfg.Processes) }},
+	{"time", func(cfg Config, r *Report) { r.Time = CheckTime(cfg.Time) }},
+	{"toolchain", func(cfg Config, r *Report) { r.Toolchain = CheckToolchain(cfg.Toolchain) }},
+	{"containers", func(cfg Config, r *Report) { r.Containers = CheckContainers(cfg.Containers) }},
+	{"packages", func(cfg Config, r *Report) { r.Packages = CheckPackages(cfg.Packages) }},
+	{"services", func(cfg Config, r *Report) { r.Services = CheckServices(cfg.Services) }},
+	{"certificates", func(cfg Config, r *Report) { r.Certificates = CheckCertificates(cfg.Certificates) }},
+	{"custom", func(cfg Config, r *Report) { r.Custom = CheckCustom(cfg.Custom) }},
+}
+
+// SubsystemNames returns the names of all subsystems in report order.
+func SubsystemNames() []string {
+	names := make([]string, len(subsystems))
+	for i, s := range subsystems {
+		names[i] = s.name
+	}
+	return names
+}
+
 // Check runs all health checks in parallel and returns a complete report.
-func Check() Report {
+func Check(cfg Config) Report {
 	r := Report{
 		Timestamp: time.Now().UTC(),
 	}
 
 	var wg sync.WaitGroup
-	wg.Add(9)
-
-	go func() { defer wg.Done(); r.CPU = CheckCPU() }()
-	go func() { defer wg.Done(); r.Memory = CheckMemory() }()
-	go func() { defer wg.Done(); r.Disk = CheckDisk() }()
-	go func() { defer wg.Done(); r.Thermal = CheckThermal() }()
-	go func() { defer wg.Done(); r.ICloud = CheckICloud() }()
-	go func() { defer wg.Done(); r.Battery = CheckBattery() }()
-	go func() { defer wg.Done(); r.TimeMachine = CheckTimeMachine() }()
-	go func() { defer wg.Done(); r.Network = CheckNetwork() }()
-	go func() { defer wg.Done(); r.Bluetooth = CheckBluetooth() }()
-
+	for _, s := range subsystems {
+		wg.Go(func() { s.check(cfg, &r) })
+	}
 	wg.Wait()
 
 	r.Score = computeScore(r)
 	return r
 }
 
+// CheckSubsystems runs only the named subsystems in parallel. The score
+// covers just those subsystems; the others are left empty. A name given
+// more than once is checked once.
+func CheckSubsystems(cfg Config, names ...string) (Report, error) {
+	var selected []subsystem
+	for _, name := range names {
+		i := slices.IndexFunc(subsystems, func(s subsystem) bool { return s.name == name })
+		if i < 0 {
+			return Report{}, fmt.Errorf("unknown subsystem %q", name)
+		}
+		if !slices.ContainsFunc(selected, func(s subsystem) bool { return s.name == name }) {
+			selected = append(selected, subsystems[i])
+		}
+	}
+
+	r := Report{
+		Timestamp: time.Now().UTC(),
+	}
+
+	var wg sync.WaitGroup
+	for _, s := range selected {
+		wg.Go(func() { s.check(cfg, &r) })
+	}
+	wg.Wait()
+
+	r.Score = computeScore(r)
+	return r, nil
+}
+
 // Diagnose runs all checks and generates diagnoses for non-green subsystems.
-func Diagnose() DiagnoseReport {
-	r := Check()
+func Diagnose(cfg Config) DiagnoseReport {
+	return DiagnoseFrom(Check(cfg))
+}
+
+// DiagnoseFrom generates diagnoses for the non-green subsystems of an
+// existing report.
+func DiagnoseFrom(r Report) DiagnoseReport {
 	dr := DiagnoseReport{Report: r}
 
 	diagnosers := []func() *Diagnosis{
@@ -41,10 +113,21 @@ func Diagnose() DiagnoseReport {
 		func() *Diagnosis { return DiagnoseDisk(r.Disk) },
 		func() *Diagnosis { return DiagnoseThermal(r.Thermal) },
 		func() *Diagnosis { return DiagnoseICloud(r.ICloud) },
+		func() *Diagnosis { return DiagnoseCloudSync(r.CloudSync) },
 		func() *Diagnosis { return DiagnoseBattery(r.Battery) },
 		func() *Diagnosis { return DiagnoseTimeMachine(r.TimeMachine) },
 		func() *Diagnosis { return DiagnoseNetwork(r.Network) },
 		func() *Diagnosis { return DiagnoseBluetooth(r.Bluetooth) },
+		func() *Diagnosis { return DiagnoseOS(r.OS) },
+		func() *Diagnosis { return DiagnoseStability(r.Stability) },
+		func() *Diagnosis { return DiagnoseCrashes(r.Crashes) },
+		func() *Diagnosis { return DiagnosePower(r.Power) },
+		func() *Diagnosis { return DiagnoseLimits(r.Limits) },
+		func() *Diagnosis { return DiagnoseProcesses(r.Processes) },
+		func() *Diagnosis { return DiagnoseTime(r.Time) },
+		func() *Diagnosis { return DiagnoseToolchain(r.Toolchain) },
+		func() *Diagnosis { return DiagnoseContainers(r.Containers) },
+		func() *Diagnosis { return DiagnosePackages(r.Packages) },
 	}
 
 	for _, fn := range diagnosers {
@@ -53,6 +136,12 @@ func Diagnose() DiagnoseReport {
 		}
 	}
 
+	// Subsystems with independent controls, services or certificates yield one diagnosis per issue.
+	dr.Diagnoses = append(dr.Diagnoses, DiagnoseSecurity(r.Security)...)
+	dr.Diagnoses = append(dr.Diagnoses, DiagnoseServices(r.Services)...)
+	dr.Diagnoses = append(dr.Diagnoses, DiagnoseCertificates(r.Certificates)...)
+	dr.Diagnoses = append(dr.Diagnoses, DiagnoseCustom(r.Custom)...)
+
 	if dr.Diagnoses == nil {
 		dr.Diagnoses = []Diagnosis{}
 	}
@@ -62,13 +151,14 @@ func Diagnose() DiagnoseReport {
 
 // Subsystem weights for composite score.
 var weights = map[string]int{
-	"cpu":     20,
-	"memory":  25,
-	"disk":    15,
-	"thermal": 20,
-	"battery": 10,
-	"icloud":  5,
-	"network": 5,
+	"cpu":      20,
+	"memory":   25,
+	"disk":     15,
+	"thermal":  20,
+	"battery":  10,
+	"icloud":   5,
+	"network":  5,
+	"security": 10,
 }
 
 func statusValue(s Status) int {
@@ -90,28 +180,59 @@ func computeScore(r Report) Score {
 	}
 
 	subsystems := map[string]Status{
-		"cpu":         r.CPU.Status,
-		"memory":      r.Memory.Status,
-		"disk":        r.Disk.Status,
-		"thermal":     r.Thermal.Status,
-		"icloud":      r.ICloud.Status,
-		"battery":     r.Battery.Status,
-		"timemachine": r.TimeMachine.Status,
-		"network":     r.Network.Status,
-		"bluetooth":   btStatus,
-	}
-
-	// TimeMachine shares disk weight (not a separate weight)
-	// but still contributes to overall status
+		"cpu":          r.CPU.Status,
+		"memory":       r.Memory.Status,
+		"disk":         r.Disk.Status,
+		"thermal":      r.Thermal.Status,
+		"icloud":       r.ICloud.Status,
+		"cloudsync":    r.CloudSync.Status,
+		"battery":      r.Battery.Status,
+		"timemachine":  r.TimeMachine.Status,
+		"network":      r.Network.Status,
+		"bluetooth":    btStatus,
+		"security":     r.Security.Status,
+		"os":           r.OS.Status,
+		"stability":    r.Stability.Status,
+		"crashes":      r.Crashes.Status,
+		"power":        r.Power.Status,
+		"limits":       r.Limits.Status,
+		"processes":    r.Processes.Status,
+		"time":         r.Time.Status,
+		"toolchain":    r.Toolchain.Status,
+		"containers":   r.Containers.Status,
+		"packages":     r.Packages.Status,
+		"services":     r.Services.Status,
+		"certificates": r.Certificates.Status,
+	}
+
+	// Custom checks are scored under "custom:<name>" with their own weight.
+	scoreWeights := weights
+	if len(r.Custom) > 0 {
+		scoreWeights = maps.Clone(weights)
+		for _, c := range r.Custom {
+			key := "custom:" + c.Name
+			subsystems[key] = c.Status
+			if c.Weight > 0 {
+				scoreWeights[key] = c.Weight
+			}
+		}
+	}
+
+	// Subsystems without an entry in weights (timemachine, cloudsync, os,
+	// stability, ...) don't move the score but still contribute to overall status
 	totalWeight := 0
 	weightedSum := 0
 	worstStatus := StatusGreen
 	var reasons []string
 
 	for name, status := range subsystems {
-		w, ok := weights[name]
+		// Subsystems that were not collected have no status.
+		if status == "" {
+			continue
+		}
+		w, ok := scoreWeights[name]
 		if !ok {
-			// timemachine doesn't have its own weight — skip for scoring
+			// unweighted subsystem — skip for scoring
 			// but still check for status degradation
 			if status != StatusGreen {
 				if worstStatus == StatusGreen || (worstStatus == StatusYellow && status == StatusRed) {
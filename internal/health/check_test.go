@@ -1,13 +1,15 @@
 package health
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
 
 func TestCheck_Integration(t *testing.T) {
 	start := time.Now()
-	r := Check()
+	r := Check(DefaultConfig())
 	elapsed := time.Since(start)
 
 	// Must complete under 500ms
@@ -100,6 +102,7 @@ func TestComputeScore(t *testing.T) {
 				TimeMachine: TimeMachine{Status: StatusRed},
 				Network:     Network{Status: StatusRed},
 				Bluetooth:   Bluetooth{Status: StatusRed},
+				Security:    Security{Status: StatusRed},
 			},
 			wantStatus: StatusRed,
 			wantMin:    0, wantMax: 0,
@@ -121,11 +124,42 @@ func TestComputeScore(t *testing.T) {
 			wantStatus: StatusYellow,
 			wantMin:    80, wantMax: 100,
 		},
+		{
+			name: "security red is weighted and makes status red",
+			report: Report{
+				CPU:      CPU{Status: StatusGreen},
+				Memory:   Memory{Status: StatusGreen},
+				Disk:     Disk{Status: StatusGreen},
+				Thermal:  Thermal{Status: StatusGreen},
+				Security: Security{Status: StatusRed},
+			},
+			wantStatus: StatusRed,
+			wantMin:    80, wantMax: 95,
+		},
+		{
+			name: "weighted custom check moves the score",
+			report: Report{
+				CPU:    CPU{Status: StatusGreen},
+				Memory: Memory{Status: StatusGreen},
+				Custom: []CustomResult{{Name: "license", Status: StatusRed, Weight: 45}},
+			},
+			wantStatus: StatusRed,
+			wantMin:    70, wantMax: 70,
+		},
+		{
+			name: "unweighted custom check only degrades status",
+			report: Report{
+				CPU:    CPU{Status: StatusGreen},
+				Custom: []CustomResult{{Name: "cache", Status: StatusYellow}},
+			},
+			wantStatus: StatusYellow,
+			wantMin:    100, wantMax: 100,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			score := computeScore(tt.report)
+			score := computeScore(withGreen(tt.report))
 			if score.Status != tt.wantStatus {
 				t.Errorf("status = %s, want %s", score.Status, tt.wantStatus)
 			}
@@ -136,6 +170,30 @@ func TestComputeScore(t *testing.T) {
 	}
 }
 
+// withGreen returns r with every subsystem that has no status set to green,
+// so cases only spell out the subsystems they are about.
+func withGreen(r Report) Report {
+	v := reflect.ValueOf(&r).Elem()
+	for i := range v.NumField() {
+		f := v.Field(i)
+		if f.Kind() != reflect.Struct {
+			continue
+		}
+		if s := f.FieldByName("Status"); s.IsValid() && s.String() == "" {
+			s.SetString(string(StatusGreen))
+		}
+	}
+	return r
+}
+
+func TestComputeScore_Uncollected(t *testing.T) {
+	// CheckSubsystems leaves the subsystems it did not run without a status.
+	score := computeScore(Report{CPU: CPU{Status: StatusGreen}, Memory: Memory{Status: StatusYellow}})
+	if score.Status != StatusYellow || len(score.Reasons) != 1 || score.Value != 72 {
+		t.Errorf("score = %+v, want yellow from memory alone", score)
+	}
+}
+
 func TestExitCode(t *testing.T) {
 	tests := []struct {
 		status Status
@@ -157,7 +215,7 @@ func TestExitCode(t *testing.T) {
 }
 
 func TestDiagnose_Integration(t *testing.T) {
-	dr := Diagnose()
+	dr := Diagnose(DefaultConfig())
 
 	if dr.Diagnoses == nil {
 		t.Error("diagnoses should not be nil")
@@ -168,3 +226,49 @@ func TestDiagnose_Integration(t *testing.T) {
 		t.Errorf("expected 0 diagnoses for green status, got %d", len(dr.Diagnoses))
 	}
 }
+
+func TestDiagnoseFrom(t *testing.T) {
+	r := withGreen(Report{
+		Timestamp: time.Unix(1700000000, 0).UTC(),
+		Memory:    Memory{Status: StatusRed, PressurePercent: 5},
+		Custom:    []CustomResult{{Name: "license", Status: StatusYellow}},
+	})
+	dr := DiagnoseFrom(r)
+	if !dr.Timestamp.Equal(r.Timestamp) || dr.Memory.PressurePercent != 5 {
+		t.Error("report should be carried over unchanged")
+	}
+	found := map[string]bool{}
+	for _, d := range dr.Diagnoses {
+		found[d.Subsystem] = true
+	}
+	if len(dr.Diagnoses) != 2 || !found["memory"] || !found["custom:license"] {
+		t.Errorf("diagnosed subsystems = %v, want memory and custom:license", found)
+	}
+}
+
+func TestCheckSubsystems(t *testing.T) {
+	r, err := CheckSubsystems(DefaultConfig(), "cpu", "disk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.CPU.Status == "" || r.Disk.Status == "" {
+		t.Errorf("selected subsystems not collected: cpu=%q disk=%q", r.CPU.Status, r.Disk.Status)
+	}
+	if r.Memory.Status != "" || r.Network.Status != "" {
+		t.Error("unselected subsystems should be left empty")
+	}
+	for _, reason := range r.Score.Reasons {
+		if !strings.HasPrefix(reason, "cpu:") && !strings.HasPrefix(reason, "disk:") {
+			t.Errorf("score reason %q from an unselected subsystem", reason)
+		}
+	}
+
+	// Duplicates would otherwise run the same check concurrently.
+	if r, err := CheckSubsystems(DefaultConfig(), "memory", "memory"); err != nil || r.Memory.Status == "" {
+		t.Errorf("duplicate subsystem: status %q, %v", r.Memory.Status, err)
+	}
+
+	if _, err := CheckSubsystems(DefaultConfig(), "gpu"); err == nil {
+		t.Error("expected error for unknown subsystem")
+	}
+}
@@ -0,0 +1,276 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cloudSyncProvider describes how to detect a third-party sync client.
+type cloudSyncProvider struct {
+	name string
+	// bundleID prefix of the client's File Provider extension, as printed
+	// by `fileproviderctl dump`.
+	bundleID string
+	// storagePrefix is the folder name prefix under ~/Library/CloudStorage
+	// where macOS mounts the provider's domains (e.g. "GoogleDrive-me@x.com").
+	storagePrefix string
+	// statusFile is a path relative to $HOME that the client writes when it
+	// is configured. It is optional.
+	statusFile string
+}
+
+var cloudSyncProviders = []cloudSyncProvider{
+	{name: "Dropbox", bundleID: "com.getdropbox.dropbox", storagePrefix: "Dropbox", statusFile: ".dropbox/info.json"},
+	{name: "Google Drive", bundleID: "com.google.drivefs", storagePrefix: "GoogleDrive", statusFile: "Library/Application Support/Google/DriveFS"},
+	{name: "OneDrive", bundleID: "com.microsoft.OneDrive", storagePrefix: "OneDrive", statusFile: "Library/Group Containers/UBF8T346G9.OneDriveSyncClientSuite"},
+}
+
+// Cloud sync states, shared by every provider.
+const (
+	CloudSyncCaughtUp = "caught-up"
+	CloudSyncSyncing  = "syncing"
+	CloudSyncError    = "error"
+	CloudSyncUnknown  = "unknown"
+)
+
+// CheckCloudSync collects sync state for third-party File Provider clients
+// (Dropbox, Google Drive, OneDrive). Like iCloud, a provider that is not
+// caught up or reports errors turns the subsystem yellow.
+func CheckCloudSync() CloudSync {
+	cs := CloudSync{Status: StatusGreen}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return cs
+	}
+
+	cs.Providers = detectCloudProviders(home)
+	if len(cs.Providers) == 0 {
+		return cs
+	}
+
+	// fileproviderctl walks every domain and can be slow; bound it.
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "/usr/bin/fileproviderctl", "dump").Output()
+	if err != nil {
+		// State stays unknown — informational, not a failure.
+		return cs
+	}
+	parseFileProviderDump(string(out), cs.Providers)
+
+	for _, p := range cs.Providers {
+		if p.State == CloudSyncSyncing || p.State == CloudSyncError {
+			cs.Status = StatusYellow
+		}
+	}
+
+	return cs
+}
+
+// detectCloudProviders finds configured providers from their CloudStorage
+// mounts and status files under home.
+func detectCloudProviders(home string) []CloudSyncProvider {
+	entries, _ := os.ReadDir(filepath.Join(home, "Library", "CloudStorage"))
+
+	var found []CloudSyncProvider
+	for _, spec := range cloudSyncProviders {
+		p := CloudSyncProvider{Name: spec.name, State: CloudSyncUnknown}
+
+		for _, e := range entries {
+			if strings.HasPrefix(e.Name(), spec.storagePrefix) {
+				p.Domains = append(p.Domains, e.Name())
+				if p.Root == "" {
+					p.Root = filepath.Join(home, "Library", "CloudStorage", e.Name())
+				}
+			}
+		}
+
+		configured := len(p.Domains) > 0
+		if spec.statusFile != "" {
+			path := filepath.Join(home, spec.statusFile)
+			if _, err := os.Stat(path); err == nil {
+				configured = true
+				if p.Root == "" && strings.HasSuffix(path, "info.json") {
+					p.Root = parseDropboxInfo(path)
+				}
+			}
+		}
+
+		if configured {
+			found = append(found, p)
+		}
+	}
+	return found
+}
+
+// parseDropboxInfo returns the sync root from ~/.dropbox/info.json, which
+// holds one entry per linked account ("personal", "business").
+func parseDropboxInfo(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	var info map[string]struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return ""
+	}
+	keys := make([]string, 0, len(info))
+	for k := range info {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if info[k].Path != "" {
+			return info[k].Path
+		}
+	}
+	return ""
+}
+
+var (
+	// fpFieldRe matches a "key: value" state field. Keys are plain words, so
+	// item lines naming files ("failed-tests.log", "pending.txt") never match.
+	fpFieldRe   = regexp.MustCompile(`^([A-Za-z][A-Za-z ]*?)\s*[:=]\s*(.*)$`)
+	fpErrorRe   = regexp.MustCompile(`(?i)\b(errors?|failed|failure)\b`)
+	fpSyncingRe = regexp.MustCompile(`(?i)\b(uploading|downloading|syncing|pending)\b`)
+	fpCountRe   = regexp.MustCompile(`^(\d+)\b`)
+)
+
+// fpFieldState reports whether a dump line is a state field recording an
+// error or an in-flight transfer. Error fields ("errors: 3", "last error:
+// ...") count when their value is a nonzero count or a message, transfer
+// fields ("uploading: 2 items") when their count is nonzero, and "state"
+// or "status" fields by the keywords in their value.
+func fpFieldState(line string) (errored, syncing bool) {
+	m := fpFieldRe.FindStringSubmatch(line)
+	if m == nil {
+		return false, false
+	}
+	key, value := strings.ToLower(m[1]), strings.TrimSpace(m[2])
+	active := func() bool {
+		if c := fpCountRe.FindStringSubmatch(value); c != nil {
+			n, _ := strconv.Atoi(c[1])
+			return n > 0
+		}
+		v := strings.ToLower(value)
+		return v != "" && v != "none" && v != "(null)"
+	}
+	switch {
+	case key == "state" || key == "status":
+		return fpErrorRe.MatchString(value), fpSyncingRe.MatchString(value)
+	case fpErrorRe.MatchString(key):
+		return active(), false
+	case fpSyncingRe.MatchString(key):
+		return false, active()
+	}
+	return false, false
+}
+
+// parseFileProviderDump updates provider state from `fileproviderctl dump`.
+// The dump format is undocumented and changes between releases, so the
+// parser only relies on two things: a provider's block starts at a line
+// naming its bundle ID and extends over the more-indented lines after it,
+// and within that block error or in-flight transfer fields mark its state.
+func parseFileProviderDump(s string, providers []CloudSyncProvider) {
+	lines := strings.Split(s, "\n")
+
+	for i := range providers {
+		var spec cloudSyncProvider
+		for _, sp := range cloudSyncProviders {
+			if sp.name == providers[i].Name {
+				spec = sp
+			}
+		}
+		if spec.bundleID == "" {
+			continue
+		}
+
+		seen, syncing := false, false
+		var errs []string
+
+		blockIndent := -1
+		for _, line := range lines {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" {
+				continue
+			}
+			indent := len(line) - len(strings.TrimLeft(line, " \t"))
+
+			if strings.Contains(line, spec.bundleID) {
+				seen = true
+				blockIndent = indent
+			} else if blockIndent < 0 || indent <= blockIndent {
+				blockIndent = -1
+				continue
+			}
+
+			errored, transferring := fpFieldState(trimmed)
+			if errored {
+				errs = append(errs, trimmed)
+			}
+			syncing = syncing || transferring
+		}
+
+		if !seen {
+			continue
+		}
+		providers[i].Errors = errs
+		switch {
+		case len(errs) > 0:
+			providers[i].State = CloudSyncError
+		case syncing:
+			providers[i].State = CloudSyncSyncing
+		default:
+			providers[i].State = CloudSyncCaughtUp
+		}
+		providers[i].Syncing = syncing
+		providers[i].CaughtUp = providers[i].State == CloudSyncCaughtUp
+	}
+}
+
+// DiagnoseCloudSync returns diagnosis for third-party cloud sync issues.
+func DiagnoseCloudSync(cs CloudSync) *Diagnosis {
+	if cs.Status == StatusGreen {
+		return nil
+	}
+	d := &Diagnosis{
+		Subsystem: "cloudsync",
+		Severity:  cs.Status,
+	}
+
+	var failing, syncing []string
+	for _, p := range cs.Providers {
+		name := p.Name
+		if p.Root != "" {
+			name += " (" + p.Root + ")"
+		}
+		switch p.State {
+		case CloudSyncError:
+			failing = append(failing, name)
+		case CloudSyncSyncing:
+			syncing = append(syncing, name)
+		}
+	}
+
+	if len(failing) > 0 {
+		d.Summary = "Cloud sync provider reports errors"
+		d.Detail = "Errors reported by: " + strings.Join(failing, ", ") + ". Files in these folders may be stale or incomplete."
+		d.Action = "Open the provider's menu bar app to resolve sync errors before building from its folders"
+	} else {
+		d.Summary = "Cloud sync provider is actively syncing"
+		d.Detail = "Not caught up: " + strings.Join(syncing, ", ") + ". Half-synced files can break builds."
+		d.Action = "Wait for sync to complete, or pause the provider before building from its folders"
+	}
+	return d
+}
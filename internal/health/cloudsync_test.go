@@ -0,0 +1,164 @@
+package health
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectCloudProviders(t *testing.T) {
+	home := t.TempDir()
+	storage := filepath.Join(home, "Library", "CloudStorage")
+	for _, dir := range []string{"GoogleDrive-me@example.com", "OneDrive-Personal", "OneDrive-Contoso"} {
+		if err := os.MkdirAll(filepath.Join(storage, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(home, ".dropbox"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	info := `{"personal": {"path": "/Users/me/Dropbox", "host": 1234, "is_team": false}}`
+	if err := os.WriteFile(filepath.Join(home, ".dropbox", "info.json"), []byte(info), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	providers := detectCloudProviders(home)
+	if len(providers) != 3 {
+		t.Fatalf("len(providers) = %d, want 3", len(providers))
+	}
+
+	byName := map[string]CloudSyncProvider{}
+	for _, p := range providers {
+		byName[p.Name] = p
+		if p.State != CloudSyncUnknown {
+			t.Errorf("%s state = %s, want unknown before dump", p.Name, p.State)
+		}
+	}
+	if got := byName["Dropbox"].Root; got != "/Users/me/Dropbox" {
+		t.Errorf("Dropbox root = %q, want /Users/me/Dropbox", got)
+	}
+	if got := len(byName["OneDrive"].Domains); got != 2 {
+		t.Errorf("OneDrive domains = %d, want 2", got)
+	}
+	if got := byName["Google Drive"].Root; got != filepath.Join(storage, "GoogleDrive-me@example.com") {
+		t.Errorf("Google Drive root = %q", got)
+	}
+}
+
+func TestDetectCloudProviders_None(t *testing.T) {
+	if providers := detectCloudProviders(t.TempDir()); len(providers) != 0 {
+		t.Errorf("expected no providers, got %v", providers)
+	}
+}
+
+const fpDumpSample = `Providers:
+  com.getdropbox.dropbox.fileprovider (Dropbox):
+    domain: Dropbox
+      enumeration: up to date
+      errors: 0
+      uploading: 3 items
+  com.google.drivefs.fpext (Google Drive):
+    domain: me@example.com
+      enumeration: up to date
+      uploading: 0
+      errors: 0
+      items:
+        failed-tests.log
+        pending.txt
+        error: none
+  com.microsoft.OneDrive.FileProvider (OneDrive):
+    domain: Personal
+      last error: NSFileProviderErrorDomain -1005 server unreachable
+other:
+  uploading: 99 items
+`
+
+func TestParseFileProviderDump(t *testing.T) {
+	providers := []CloudSyncProvider{
+		{Name: "Dropbox", State: CloudSyncUnknown},
+		{Name: "Google Drive", State: CloudSyncUnknown},
+		{Name: "OneDrive", State: CloudSyncUnknown},
+	}
+	parseFileProviderDump(fpDumpSample, providers)
+
+	tests := []struct {
+		name       string
+		wantState  string
+		wantErrors int
+	}{
+		{"Dropbox", CloudSyncSyncing, 0},
+		{"Google Drive", CloudSyncCaughtUp, 0},
+		{"OneDrive", CloudSyncError, 1},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := providers[i]
+			if p.State != tt.wantState {
+				t.Errorf("state = %s, want %s", p.State, tt.wantState)
+			}
+			if len(p.Errors) != tt.wantErrors {
+				t.Errorf("errors = %v, want %d", p.Errors, tt.wantErrors)
+			}
+			if p.CaughtUp != (tt.wantState == CloudSyncCaughtUp) {
+				t.Errorf("CaughtUp = %v for state %s", p.CaughtUp, p.State)
+			}
+		})
+	}
+}
+
+func TestFPFieldState(t *testing.T) {
+	tests := []struct {
+		line             string
+		errored, syncing bool
+	}{
+		{"errors: 0", false, false},
+		{"error=0", false, false},
+		{"errors: 2", true, false},
+		{"errors: (null)", false, false},
+		{"last error: NSFileProviderErrorDomain -1005", true, false},
+		{"state: upload failed", true, false},
+		{"status: syncing", false, true},
+		{"uploading: 3 items", false, true},
+		{"downloading: none", false, false},
+		{"enumeration: up to date", false, false},
+		{"failed-tests.log", false, false},
+		{"pending.txt: 4 KB", false, false},
+	}
+	for _, tt := range tests {
+		errored, syncing := fpFieldState(tt.line)
+		if errored != tt.errored || syncing != tt.syncing {
+			t.Errorf("fpFieldState(%q) = %v, %v, want %v, %v", tt.line, errored, syncing, tt.errored, tt.syncing)
+		}
+	}
+}
+
+func TestParseFileProviderDump_NotListed(t *testing.T) {
+	providers := []CloudSyncProvider{{Name: "Dropbox", State: CloudSyncUnknown}}
+	parseFileProviderDump("Providers:\n  com.apple.CloudDocs.MobileDocumentsFileProvider\n", providers)
+	if providers[0].State != CloudSyncUnknown {
+		t.Errorf("state = %s, want unknown", providers[0].State)
+	}
+}
+
+func TestDiagnoseCloudSync(t *testing.T) {
+	if d := DiagnoseCloudSync(CloudSync{Status: StatusGreen}); d != nil {
+		t.Error("expected nil diagnosis for green status")
+	}
+
+	d := DiagnoseCloudSync(CloudSync{
+		Status: StatusYellow,
+		Providers: []CloudSyncProvider{
+			{Name: "Dropbox", State: CloudSyncSyncing},
+			{Name: "OneDrive", State: CloudSyncError},
+		},
+	})
+	if d == nil {
+		t.Fatal("expected non-nil diagnosis")
+	}
+	if d.Subsystem != "cloudsync" || d.Severity != StatusYellow {
+		t.Errorf("diagnosis = %+v", d)
+	}
+	if d.Summary != "Cloud sync provider reports errors" {
+		t.Errorf("errors should take precedence, got summary %q", d.Summary)
+	}
+}
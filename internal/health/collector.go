@@ -0,0 +1,97 @@
+package health
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// reportFields maps each subsystem name to the index of its Report field.
+var reportFields = func() map[string]int {
+	fields := map[string]int{}
+	t := reflect.TypeFor[Report]()
+	for i := range t.NumField() {
+		if name := promJSONName(t.Field(i)); name != "" {
+			fields[name] = i
+		}
+	}
+	return fields
+}()
+
+// Collector runs checks like Check but reuses each subsystem's result until
+// its TTL (Config.Daemon.TTL) expires, so many callers share one set of
+// collections. The zero value is ready to use.
+type Collector struct {
+	mu      sync.Mutex
+	entries map[string]*collectorEntry
+
+	// now returns the current time; replaced in tests.
+	now func() time.Time
+}
+
+type collectorEntry struct {
+	mu        sync.Mutex // held while collecting, so callers wait for one collection
+	value     reflect.Value
+	collected time.Time
+}
+
+func (c *Collector) entry(name string) *collectorEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]*collectorEntry{}
+	}
+	e, ok := c.entries[name]
+	if !ok {
+		e = &collectorEntry{}
+		c.entries[name] = e
+	}
+	return e
+}
+
+func (c *Collector) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+// Check returns a report whose subsystems are at most their TTL old,
+// collecting stale ones in parallel.
+func (c *Collector) Check(cfg Config) Report {
+	return c.collect(cfg, false)
+}
+
+// Refresh collects every subsystem now, whatever its age, and stores the
+// results for later calls to Check.
+func (c *Collector) Refresh(cfg Config) Report {
+	return c.collect(cfg, true)
+}
+
+func (c *Collector) collect(cfg Config, force bool) Report {
+	r := Report{
+		Timestamp: c.clock().UTC(),
+	}
+	v := reflect.ValueOf(&r).Elem()
+
+	var wg sync.WaitGroup
+	for _, s := range subsystems {
+		field := v.Field(reportFields[s.name])
+		wg.Go(func() {
+			e := c.entry(s.name)
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			if force || !e.value.IsValid() || c.clock().Sub(e.collected) >= cfg.Daemon.ttl(s.name) {
+				var fresh Report
+				s.check(cfg, &fresh)
+				e.value = reflect.ValueOf(fresh).Field(reportFields[s.name])
+				e.collected = c.clock()
+			}
+			field.Set(e.value)
+		})
+	}
+	wg.Wait()
+
+	r.Score = computeScore(r)
+	return r
+}
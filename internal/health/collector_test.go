@@ -0,0 +1,95 @@
+package health
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubsystemNames(t *testing.T) {
+	names := SubsystemNames()
+	for name := range reportFields {
+		if name != "timestamp" && name != "score" && !slices.Contains(names, name) {
+			t.Errorf("report field %q has no subsystem", name)
+		}
+	}
+	for _, name := range names {
+		if _, ok := reportFields[name]; !ok {
+			t.Errorf("subsystem %q has no report field", name)
+		}
+	}
+}
+
+func TestCollector(t *testing.T) {
+	var cpuRuns, memRuns atomic.Int32
+	saved := subsystems
+	defer func() { subsystems = saved }()
+	subsystems = []subsystem{
+		{"cpu", func(cfg Config, r *Report) {
+			n := cpuRuns.Add(1)
+			r.CPU = CPU{Status: StatusGreen, LogicalCores: int(n)}
+		}},
+		{"memory", func(cfg Config, r *Report) {
+			memRuns.Add(1)
+			r.Memory = Memory{Status: StatusRed, PressurePercent: 90}
+		}},
+	}
+
+	now := time.Unix(1700000000, 0)
+	c := &Collector{now: func() time.Time { return now }}
+	cfg := DefaultConfig()
+	cfg.Daemon.TTL = map[string]Duration{"default": Duration(time.Minute), "cpu": Duration(5 * time.Second)}
+
+	r := c.Check(cfg)
+	if r.CPU.LogicalCores != 1 || r.Memory.PressurePercent != 90 || !r.Timestamp.Equal(now) {
+		t.Fatalf("first check = %+v", r)
+	}
+
+	now = now.Add(10 * time.Second)
+	r = c.Check(cfg)
+	if r.CPU.LogicalCores != 2 || cpuRuns.Load() != 2 {
+		t.Errorf("cpu past its TTL was not recollected: %+v", r.CPU)
+	}
+	if memRuns.Load() != 1 || r.Memory.PressurePercent != 90 {
+		t.Errorf("memory within its TTL was recollected %d times", memRuns.Load())
+	}
+
+	now = now.Add(time.Minute)
+	c.Check(cfg)
+	if memRuns.Load() != 2 {
+		t.Errorf("memory past the default TTL was not recollected")
+	}
+
+	// Refresh ignores TTLs, and Check then reuses what it collected.
+	r = c.Refresh(cfg)
+	if memRuns.Load() != 3 || cpuRuns.Load() != 4 || r.CPU.LogicalCores != 4 {
+		t.Errorf("refresh: memory runs = %d, cpu runs = %d", memRuns.Load(), cpuRuns.Load())
+	}
+	if r = c.Check(cfg); memRuns.Load() != 3 || r.CPU.LogicalCores != 4 {
+		t.Errorf("check after refresh recollected: memory runs = %d, cpu = %+v", memRuns.Load(), r.CPU)
+	}
+}
+
+func TestDaemonConfigTTL(t *testing.T) {
+	c := DefaultConfig().Daemon
+	if got := c.ttl("cpu"); got != 5*time.Second {
+		t.Errorf("ttl(cpu) = %v", got)
+	}
+	if got := c.ttl("services"); got != 30*time.Second {
+		t.Errorf("ttl(services) = %v, want the default", got)
+	}
+
+	// Entries in a config file are merged with the default TTLs.
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"daemon": {"ttl": {"services": "2m"}}}`), 0o644)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Daemon.ttl("services") != 2*time.Minute || cfg.Daemon.ttl("cpu") != 5*time.Second {
+		t.Errorf("merged TTLs = %v", cfg.Daemon.TTL)
+	}
+}
@@ -0,0 +1,360 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Config holds user settings for checks that need site-specific input.
+// It is read from a JSON file; every field is optional and a missing
+// section falls back to DefaultConfig.
+type Config struct {
+	ICloud       ICloudConfig       `json:"icloud"`
+	Network      NetworkConfig      `json:"network"`
+	OS           OSConfig           `json:"os"`
+	Stability    StabilityConfig    `json:"stability"`
+	Crashes      CrashesConfig      `json:"crashes"`
+	Power        PowerConfig        `json:"power"`
+	Limits       LimitsConfig       `json:"limits"`
+	Processes    ProcessesConfig    `json:"processes"`
+	Time         TimeConfig         `json:"time"`
+	Toolchain    ToolchainConfig    `json:"toolchain"`
+	Containers   ContainersConfig   `json:"containers"`
+	Packages     PackagesConfig     `json:"packages"`
+	Services     ServicesConfig     `json:"services"`
+	Certificates CertificatesConfig `json:"certificates"`
+	Custom       CustomConfig       `json:"custom"`
+	Daemon       DaemonConfig       `json:"daemon"`
+}
+
+// ICloudConfig configures the iCloud check.
+type ICloudConfig struct {
+	// Paths are folders that must be fully materialised locally
+	// (no files evicted by "Optimize Mac Storage").
+	Paths []string `json:"paths,omitempty"`
+}
+
+// NetworkConfig configures the network subsystem.
+type NetworkConfig struct {
+	// ProbeTimeout bounds fetching the PAC file and each DNS server probe
+	// (0 disables probing).
+	ProbeTimeout Duration `json:"probe_timeout"`
+}
+
+// OSConfig configures the os subsystem.
+type OSConfig struct {
+	// CheckUpdates enables the pending software update scan.
+	CheckUpdates bool `json:"check_updates"`
+	// UpdatesCacheTTL is how long `softwareupdate --list` output is reused
+	// before a background refresh is started.
+	UpdatesCacheTTL Duration `json:"updates_cache_ttl"`
+}
+
+// StabilityConfig configures the stability subsystem.
+type StabilityConfig struct {
+	// PanicWindow is how far back a kernel panic turns stability red.
+	PanicWindow Duration `json:"panic_window"`
+	// MaxUptime is the uptime beyond which stability turns yellow (0 disables).
+	MaxUptime Duration `json:"max_uptime"`
+}
+
+// CrashesConfig configures the crashes subsystem.
+type CrashesConfig struct {
+	// Window is how far back crash and hang reports are counted.
+	Window Duration `json:"window"`
+	// RepeatThreshold is how many reports from one process in the window
+	// turn crashes yellow (0 disables).
+	RepeatThreshold int `json:"repeat_threshold"`
+}
+
+// PowerConfig configures the power subsystem.
+type PowerConfig struct {
+	// StaleAssertion is how long a sleep-preventing assertion may be held
+	// before power turns yellow (0 disables).
+	StaleAssertion Duration `json:"stale_assertion"`
+}
+
+// LimitsConfig configures the limits subsystem. Thresholds are percent
+// utilisation of the most used limit; 0 disables a threshold.
+type LimitsConfig struct {
+	YellowPercent float64 `json:"yellow_percent"`
+	RedPercent    float64 `json:"red_percent"`
+}
+
+// ProcessesConfig configures the processes subsystem.
+type ProcessesConfig struct {
+	// StuckAge is how long a watched or uninterruptible process may go
+	// without CPU progress before it is reported as stuck (0 disables).
+	StuckAge Duration `json:"stuck_age"`
+	// Watch lists command names (e.g. "git") checked for stalls and for
+	// being orphaned. Setting it replaces the default list.
+	Watch []string `json:"watch"`
+	// MaxZombies is the zombie count that turns processes yellow (0 disables).
+	MaxZombies int `json:"max_zombies"`
+}
+
+// TimeConfig configures the time subsystem.
+type TimeConfig struct {
+	// Server is the NTP server the clock offset is measured against
+	// ("" disables the measurement).
+	Server string `json:"server"`
+	// Timeout bounds a single SNTP query, including name resolution.
+	Timeout Duration `json:"timeout"`
+	// CacheTTL is how long a measured offset, or a failed query, is reused.
+	CacheTTL Duration `json:"cache_ttl"`
+	// YellowDrift and RedDrift are the absolute offsets that degrade status
+	// (0 disables).
+	YellowDrift Duration `json:"yellow_drift"`
+	RedDrift    Duration `json:"red_drift"`
+}
+
+// ToolchainConfig configures the toolchain subsystem.
+type ToolchainConfig struct {
+	// CacheTTL is how long simulator runtime and DerivedData size results
+	// are reused before a background refresh is started.
+	CacheTTL Duration `json:"cache_ttl"`
+	// MaxDerivedDataGB is the DerivedData size that turns toolchain yellow
+	// (0 disables).
+	MaxDerivedDataGB int `json:"max_derived_data_gb"`
+}
+
+// ContainersConfig configures the containers subsystem.
+type ContainersConfig struct {
+	// Timeout bounds the Engine API /info request.
+	Timeout Duration `json:"timeout"`
+	// DiskUsageCacheTTL is how long /system/df output is reused before a
+	// background refresh is started.
+	DiskUsageCacheTTL Duration `json:"disk_usage_cache_ttl"`
+	// YellowDiskPercent and RedDiskPercent are VM disk utilisation
+	// thresholds (0 disables).
+	YellowDiskPercent float64 `json:"yellow_disk_percent"`
+	RedDiskPercent    float64 `json:"red_disk_percent"`
+}
+
+// PackagesConfig configures the packages subsystem.
+type PackagesConfig struct {
+	// CacheTTL is how long brew/apt output is reused before a background
+	// refresh is started.
+	CacheTTL Duration `json:"cache_ttl"`
+	// MaxOutdated is the outdated package count that turns packages yellow
+	// (0 disables).
+	MaxOutdated int `json:"max_outdated"`
+}
+
+// ServicesConfig configures the services subsystem.
+type ServicesConfig struct {
+	// Launchd are job labels checked on macOS, in the user's GUI domain
+	// and then the system domain.
+	Launchd []string `json:"launchd,omitempty"`
+	// Systemd are units checked on Linux; prefix with "user:" for user units.
+	Systemd []string `json:"systemd,omitempty"`
+	// CrashLoopRestarts is the run/restart count at which a running service
+	// that has exited non-zero is considered crash-looping (0 disables).
+	CrashLoopRestarts int `json:"crash_loop_restarts"`
+}
+
+// CertificatesConfig configures the certificates subsystem.
+type CertificatesConfig struct {
+	// Keychain enables checking code signing identities in the keychain.
+	Keychain bool `json:"keychain"`
+	// CacheTTL is how long keychain enumeration is reused before a
+	// background refresh is started.
+	CacheTTL Duration `json:"cache_ttl"`
+	// Files are PEM files whose earliest-expiring certificate is checked.
+	Files []string `json:"files,omitempty"`
+	// Endpoints are "host" or "host:port" TLS servers whose leaf certificate is checked.
+	Endpoints []string `json:"endpoints,omitempty"`
+	// Timeout bounds each TLS handshake.
+	Timeout Duration `json:"timeout"`
+	// YellowDays and RedDays are the days before expiry that turn a
+	// certificate yellow and red. Expired certificates are always red.
+	YellowDays int `json:"yellow_days"`
+	RedDays    int `json:"red_days"`
+}
+
+// CustomConfig configures user-defined check scripts.
+type CustomConfig struct {
+	// Timeout bounds each script unless the check sets its own.
+	Timeout Duration `json:"timeout"`
+	// Checks are run in parallel on every check.
+	Checks []CustomCheck `json:"checks,omitempty"`
+}
+
+// CustomCheck is one user-defined check script.
+type CustomCheck struct {
+	// Name identifies the check in the report ("custom:<name>" in reasons).
+	Name string `json:"name"`
+	// Command is the program and its arguments; it is not run through a shell.
+	Command []string `json:"command"`
+	// Timeout overrides CustomConfig.Timeout for this check.
+	Timeout Duration `json:"timeout,omitempty"`
+	// Weight is the check's weight in the composite score. 0 leaves the
+	// score alone but still affects the overall status.
+	Weight int `json:"weight,omitempty"`
+}
+
+// DaemonConfig configures `machealth daemon`.
+type DaemonConfig struct {
+	// Socket is the Unix socket path ("" uses machealth.sock in the user
+	// cache directory).
+	Socket string `json:"socket,omitempty"`
+	// TTL is how long the daemon reuses each subsystem's result, keyed by
+	// subsystem name. "default" applies to subsystems not listed; entries
+	// are merged with the defaults.
+	TTL map[string]Duration `json:"ttl"`
+}
+
+// ttl returns how long the result of subsystem name is reused.
+func (c DaemonConfig) ttl(name string) time.Duration {
+	if d, ok := c.TTL[name]; ok {
+		return time.Duration(d)
+	}
+	return time.Duration(c.TTL["default"])
+}
+
+// Duration is a time.Duration read from JSON strings such as "24h" or "90s".
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"24h\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// DefaultConfig returns the configuration used when no config file exists.
+func DefaultConfig() Config {
+	return Config{
+		Network: NetworkConfig{
+			ProbeTimeout: Duration(250 * time.Millisecond),
+		},
+		OS: OSConfig{
+			CheckUpdates:    true,
+			UpdatesCacheTTL: Duration(24 * time.Hour),
+		},
+		Stability: StabilityConfig{
+			PanicWindow: Duration(7 * 24 * time.Hour),
+			MaxUptime:   Duration(60 * 24 * time.Hour),
+		},
+		Crashes: CrashesConfig{
+			Window:          Duration(24 * time.Hour),
+			RepeatThreshold: 3,
+		},
+		Power: PowerConfig{
+			StaleAssertion: Duration(12 * time.Hour),
+		},
+		Limits: LimitsConfig{
+			YellowPercent: 70,
+			RedPercent:    90,
+		},
+		Processes: ProcessesConfig{
+			StuckAge:   Duration(10 * time.Minute),
+			Watch:      []string{"git", "xcodebuild", "xcrun", "swift-build", "swift-frontend", "clang", "ld", "make", "ninja"},
+			MaxZombies: 10,
+		},
+		Time: TimeConfig{
+			Server:      "time.apple.com",
+			Timeout:     Duration(250 * time.Millisecond),
+			CacheTTL:    Duration(15 * time.Minute),
+			YellowDrift: Duration(time.Second),
+			RedDrift:    Duration(time.Minute),
+		},
+		Toolchain: ToolchainConfig{
+			CacheTTL:         Duration(6 * time.Hour),
+			MaxDerivedDataGB: 50,
+		},
+		Containers: ContainersConfig{
+			Timeout:           Duration(time.Second),
+			DiskUsageCacheTTL: Duration(10 * time.Minute),
+			YellowDiskPercent: 80,
+			RedDiskPercent:    90,
+		},
+		Packages: PackagesConfig{
+			CacheTTL:    Duration(6 * time.Hour),
+			MaxOutdated: 50,
+		},
+		Services: ServicesConfig{
+			CrashLoopRestarts: 5,
+		},
+		Certificates: CertificatesConfig{
+			Keychain:   true,
+			CacheTTL:   Duration(time.Hour),
+			Timeout:    Duration(time.Second),
+			YellowDays: 30,
+			RedDays:    7,
+		},
+		Custom: CustomConfig{
+			Timeout: Duration(5 * time.Second),
+		},
+		Daemon: DaemonConfig{
+			TTL: map[string]Duration{
+				"default":      Duration(30 * time.Second),
+				"cpu":          Duration(5 * time.Second),
+				"memory":       Duration(5 * time.Second),
+				"thermal":      Duration(10 * time.Second),
+				"processes":    Duration(10 * time.Second),
+				"network":      Duration(15 * time.Second),
+				"disk":         Duration(time.Minute),
+				"battery":      Duration(time.Minute),
+				"bluetooth":    Duration(time.Minute),
+				"timemachine":  Duration(5 * time.Minute),
+				"stability":    Duration(5 * time.Minute),
+				"security":     Duration(10 * time.Minute),
+				"os":           Duration(10 * time.Minute),
+				"toolchain":    Duration(10 * time.Minute),
+				"packages":     Duration(10 * time.Minute),
+				"certificates": Duration(10 * time.Minute),
+			},
+		},
+	}
+}
+
+// DefaultConfigPath returns ~/.config/machealth/config.json.
+func DefaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "machealth", "config.json")
+}
+
+// LoadConfig reads a JSON config file on top of DefaultConfig.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// expandHome replaces a leading "~/" in p with the user's home directory.
+func expandHome(p string) string {
+	if !strings.HasPrefix(p, "~/") {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, p[2:])
+}
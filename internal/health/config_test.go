@@ -0,0 +1,74 @@
+package health
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"icloud": {"paths": ["/tmp/project"]}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if len(cfg.ICloud.Paths) != 1 || cfg.ICloud.Paths[0] != "/tmp/project" {
+		t.Errorf("ICloud.Paths = %v, want [/tmp/project]", cfg.ICloud.Paths)
+	}
+
+	if _, err := LoadConfig(filepath.Join(dir, "missing.json")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing file error = %v, want fs.ErrNotExist", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected error for malformed config")
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	if got := expandHome("~/src"); got != filepath.Join(home, "src") {
+		t.Errorf("expandHome(~/src) = %q", got)
+	}
+	if got := expandHome("/abs/path"); got != "/abs/path" {
+		t.Errorf("expandHome(/abs/path) = %q", got)
+	}
+}
+
+func TestLoadConfig_DefaultsAndDurations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"os": {"updates_cache_ttl": "6h"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if time.Duration(cfg.OS.UpdatesCacheTTL) != 6*time.Hour {
+		t.Errorf("UpdatesCacheTTL = %v, want 6h", time.Duration(cfg.OS.UpdatesCacheTTL))
+	}
+	if !cfg.OS.CheckUpdates {
+		t.Error("CheckUpdates should keep its default when not set")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"os": {"updates_cache_ttl": 5}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected error for non-string duration")
+	}
+}
@@ -0,0 +1,317 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Container runtimes.
+const (
+	RuntimeDockerDesktop = "docker-desktop"
+	RuntimeColima        = "colima"
+	RuntimeOrbStack      = "orbstack"
+	RuntimePodman        = "podman"
+	RuntimeDocker        = "docker" // native engine or unrecognised socket
+)
+
+// dockerDFCacheName is the cache entry for the Engine API /system/df response.
+const dockerDFCacheName = "docker-system-df.json"
+
+// containerRuntime describes where a runtime's API socket and VM disk live,
+// relative to the home directory.
+type containerRuntime struct {
+	name    string
+	sockets []string
+	disks   []string // sparse VM disk images; glob patterns allowed
+}
+
+var containerRuntimes = []containerRuntime{
+	{RuntimeOrbStack, []string{".orbstack/run/docker.sock"}, []string{".orbstack/data/data.img"}},
+	{RuntimeColima, []string{".colima/default/docker.sock", ".colima/docker.sock"}, []string{".colima/_lima/colima/diffdisk", ".colima/_lima/_disks/colima/datadisk"}},
+	{RuntimeDockerDesktop, []string{".docker/run/docker.sock"}, []string{"Library/Containers/com.docker.docker/Data/vms/0/data/Docker.raw"}},
+	{RuntimePodman, []string{".local/share/containers/podman/machine/podman.sock", ".local/share/containers/podman/machine/*/podman.sock"}, []string{".local/share/containers/podman/machine/*/*.raw"}},
+}
+
+// CheckContainers detects the active container runtime from its API socket
+// and queries the Docker Engine API (served by all supported runtimes) for
+// daemon reachability, VM CPU and memory, and container counts. Image,
+// volume and build cache usage come from /system/df, which can take seconds,
+// so it is cached and refreshed in the background. An unreachable daemon, a
+// socket this user may not open, or a VM disk above the yellow/red
+// thresholds degrades status. No runtime installed is green.
+func CheckContainers(cfg ContainersConfig) Containers {
+	c := Containers{Status: StatusGreen}
+
+	home, _ := os.UserHomeDir()
+	c.Runtime, c.Socket = detectContainerRuntime(home, os.Getenv("DOCKER_HOST"))
+	if c.Socket == "" {
+		return c
+	}
+	c.Installed = true
+
+	info, err := dockerAPIGet(c.Socket, "/info", time.Duration(cfg.Timeout))
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		c.PermissionDenied = true
+		c.Error = fmt.Sprintf("permission denied: %v", err)
+	case err != nil:
+		c.Error = fmt.Sprintf("daemon unreachable: %v", err)
+	default:
+		c.Reachable = true
+		applyDockerInfo(&c, info)
+	}
+
+	if c.Reachable {
+		dir := cacheDir()
+		data, fetched, stale := readCache(dir, dockerDFCacheName, time.Duration(cfg.DiskUsageCacheTTL))
+		if stale {
+			refreshCacheAsync(dir, dockerDFCacheName, "/usr/bin/curl", "-sf", "--max-time", "60",
+				"--unix-socket", c.Socket, "http://localhost/system/df")
+		}
+		if !fetched.IsZero() {
+			applyDockerDF(&c, data)
+		}
+	}
+
+	for _, rt := range containerRuntimes {
+		if rt.name == c.Runtime {
+			c.VMDiskBytes, c.VMDiskUsedBytes = vmDiskUsage(home, rt.disks)
+		}
+	}
+	if c.VMDiskBytes > 0 {
+		c.VMDiskPercent = float64(c.VMDiskUsedBytes) / float64(c.VMDiskBytes) * 100
+	}
+
+	c.Status = containersStatus(c, cfg)
+	return c
+}
+
+// detectContainerRuntime returns the runtime and socket path to use:
+// DOCKER_HOST if it is a unix socket, then each runtime's well-known socket,
+// then /var/run/docker.sock (resolved through its symlink).
+func detectContainerRuntime(home, dockerHost string) (runtime, socket string) {
+	if path, ok := strings.CutPrefix(dockerHost, "unix://"); ok {
+		return runtimeForSocket(home, path), path
+	}
+	for _, rt := range containerRuntimes {
+		for _, pattern := range rt.sockets {
+			matches, _ := filepath.Glob(filepath.Join(home, pattern))
+			for _, m := range matches {
+				if isSocket(m) {
+					return rt.name, m
+				}
+			}
+		}
+	}
+	for _, path := range []string{"/var/run/docker.sock", "/run/podman/podman.sock"} {
+		if isSocket(path) {
+			return runtimeForSocket(home, path), path
+		}
+	}
+	return "", ""
+}
+
+// runtimeForSocket names the runtime serving path, following symlinks such
+// as /var/run/docker.sock -> ~/.docker/run/docker.sock.
+func runtimeForSocket(home, path string) string {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		resolved = path
+	}
+	for _, rt := range containerRuntimes {
+		for _, pattern := range rt.sockets {
+			if ok, _ := filepath.Match(filepath.Join(home, pattern), resolved); ok {
+				return rt.name
+			}
+		}
+	}
+	if strings.Contains(resolved, "podman") {
+		return RuntimePodman
+	}
+	return RuntimeDocker
+}
+
+func isSocket(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode()&os.ModeSocket != 0
+}
+
+// dockerAPIGet performs a GET against the Engine API on a unix socket.
+func dockerAPIGet(socket, path string, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	client := &http.Client{Transport: &http.Transport{
+		DisableKeepAlives: true,
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+}
+
+// dockerInfo holds the fields we use from GET /info.
+type dockerInfo struct {
+	ServerVersion     string `json:"ServerVersion"`
+	OperatingSystem   string `json:"OperatingSystem"`
+	NCPU              int    `json:"NCPU"`
+	MemTotal          int64  `json:"MemTotal"`
+	Containers        int    `json:"Containers"`
+	ContainersRunning int    `json:"ContainersRunning"`
+	Images            int    `json:"Images"`
+}
+
+func applyDockerInfo(c *Containers, data []byte) {
+	var info dockerInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		c.Error = fmt.Sprintf("failed to parse daemon info: %v", err)
+		return
+	}
+	c.ServerVersion = info.ServerVersion
+	c.OperatingSystem = info.OperatingSystem
+	c.CPUs = info.NCPU
+	c.MemoryBytes = info.MemTotal
+	c.ContainersTotal = info.Containers
+	c.ContainersRunning = info.ContainersRunning
+	c.Images = info.Images
+	if c.Runtime == RuntimeDocker && strings.Contains(info.OperatingSystem, "Docker Desktop") {
+		c.Runtime = RuntimeDockerDesktop
+	}
+}
+
+// dockerDF holds the fields we use from GET /system/df.
+type dockerDF struct {
+	LayersSize int64 `json:"LayersSize"`
+	Volumes    []struct {
+		UsageData struct {
+			Size int64 `json:"Size"`
+		} `json:"UsageData"`
+	} `json:"Volumes"`
+	BuildCache []struct {
+		Size int64 `json:"Size"`
+	} `json:"BuildCache"`
+}
+
+func applyDockerDF(c *Containers, data []byte) {
+	var df dockerDF
+	if err := json.Unmarshal(data, &df); err != nil {
+		return
+	}
+	c.ImagesBytes = df.LayersSize
+	c.VolumesBytes = 0
+	for _, v := range df.Volumes {
+		if v.UsageData.Size > 0 {
+			c.VolumesBytes += v.UsageData.Size
+		}
+	}
+	c.BuildCacheBytes = 0
+	for _, b := range df.BuildCache {
+		c.BuildCacheBytes += b.Size
+	}
+	c.DiskUsageKnown = true
+}
+
+// vmDiskUsage returns the capacity and allocated size of the first VM disk
+// image found. The images are sparse: the apparent size is the capacity and
+// the allocated blocks are what the VM has actually written.
+func vmDiskUsage(home string, patterns []string) (capacity, used int64) {
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(filepath.Join(home, pattern))
+		for _, m := range matches {
+			info, err := os.Stat(m)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			capacity = info.Size()
+			if st, ok := info.Sys().(*syscall.Stat_t); ok {
+				used = st.Blocks * 512
+			}
+			return capacity, used
+		}
+	}
+	return 0, 0
+}
+
+func containersStatus(c Containers, cfg ContainersConfig) Status {
+	switch {
+	case c.Installed && !c.Reachable:
+		return StatusYellow
+	case cfg.RedDiskPercent > 0 && c.VMDiskPercent >= cfg.RedDiskPercent:
+		return StatusRed
+	case cfg.YellowDiskPercent > 0 && c.VMDiskPercent >= cfg.YellowDiskPercent:
+		return StatusYellow
+	default:
+		return StatusGreen
+	}
+}
+
+// DiagnoseContainers returns diagnosis for an unreachable daemon, a socket
+// without access, or a nearly full VM disk.
+func DiagnoseContainers(c Containers) *Diagnosis {
+	if c.Status == StatusGreen {
+		return nil
+	}
+	d := &Diagnosis{
+		Subsystem: "containers",
+		Severity:  c.Status,
+	}
+
+	if c.PermissionDenied {
+		d.Summary = "No permission to use the container daemon socket"
+		d.Detail = fmt.Sprintf("%s socket %s exists but this user may not open it (%s).", c.Runtime, c.Socket, c.Error)
+		d.Action = "Add the user to the docker group ('sudo usermod -aG docker $USER', then log out and back in), or point DOCKER_HOST at a socket the user owns"
+		return d
+	}
+
+	if !c.Reachable {
+		d.Summary = "Container daemon is not reachable"
+		d.Detail = fmt.Sprintf("%s socket %s exists but the daemon does not answer (%s).", c.Runtime, c.Socket, c.Error)
+		switch c.Runtime {
+		case RuntimeColima:
+			d.Action = "Run 'colima start'"
+		case RuntimeOrbStack:
+			d.Action = "Run 'orb start' or open OrbStack"
+		case RuntimePodman:
+			d.Action = "Run 'podman machine start'"
+		case RuntimeDockerDesktop:
+			d.Action = "Open Docker Desktop ('open -a Docker') and wait for the engine to start"
+		default:
+			d.Action = "Start the Docker daemon"
+		}
+		return d
+	}
+
+	d.Summary = "Container VM disk is nearly full"
+	d.Detail = fmt.Sprintf("%s VM disk is %.0f%% full (%.1f of %.1f GB).",
+		c.Runtime, c.VMDiskPercent, bytesToGB(c.VMDiskUsedBytes), bytesToGB(c.VMDiskBytes))
+	if c.DiskUsageKnown {
+		d.Detail += fmt.Sprintf(" Images %.1f GB, volumes %.1f GB, build cache %.1f GB.",
+			bytesToGB(c.ImagesBytes), bytesToGB(c.VolumesBytes), bytesToGB(c.BuildCacheBytes))
+	}
+	d.Action = "Run 'docker system prune' (add '--volumes' only if volume data is disposable) and 'docker builder prune'; the VM disk image does not shrink until the runtime compacts it"
+	return d
+}
@@ -0,0 +1,205 @@
+package health
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenUnix creates a unix socket at dir/name; dir must be short enough for
+// the platform's socket path limit.
+func listenUnix(t *testing.T, path string) net.Listener {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("cannot listen on unix socket: %v", err)
+	}
+	return l
+}
+
+func shortTempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "mh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestDockerAPIStub(t *testing.T) {
+	dir := shortTempDir(t)
+	sock := filepath.Join(dir, "docker.sock")
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/info":
+			w.Write([]byte(`{"ServerVersion":"27.4.0","OperatingSystem":"Docker Desktop","NCPU":8,"MemTotal":8217473024,"Containers":5,"ContainersRunning":3,"Images":42}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	srv.Listener = listenUnix(t, sock)
+	srv.Start()
+	defer srv.Close()
+
+	data, err := dockerAPIGet(sock, "/info", time.Second)
+	if err != nil {
+		t.Fatalf("dockerAPIGet() error = %v", err)
+	}
+	c := Containers{Runtime: RuntimeDocker}
+	applyDockerInfo(&c, data)
+	if c.ServerVersion != "27.4.0" || c.CPUs != 8 || c.MemoryBytes != 8217473024 || c.ContainersRunning != 3 || c.ContainersTotal != 5 || c.Images != 42 {
+		t.Errorf("applyDockerInfo() = %+v", c)
+	}
+	if c.Runtime != RuntimeDockerDesktop {
+		t.Errorf("Runtime = %q, want docker-desktop from OperatingSystem", c.Runtime)
+	}
+
+	if _, err := dockerAPIGet(sock, "/nope", time.Second); err == nil {
+		t.Error("expected error for 404")
+	}
+	if _, err := dockerAPIGet(filepath.Join(dir, "missing.sock"), "/info", time.Second); err == nil {
+		t.Error("expected error for missing socket")
+	}
+}
+
+func TestDockerAPIGet_PermissionDenied(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can open any socket")
+	}
+	sock := filepath.Join(shortTempDir(t), "docker.sock")
+	l := listenUnix(t, sock)
+	defer l.Close()
+	if err := os.Chmod(sock, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dockerAPIGet(sock, "/info", time.Second); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("dockerAPIGet() error = %v, want permission denied", err)
+	}
+}
+
+func TestDetectContainerRuntime(t *testing.T) {
+	home := shortTempDir(t)
+	if rt, sock := detectContainerRuntime(home, ""); sock != "" && strings.HasPrefix(sock, home) {
+		t.Errorf("empty home detected %q at %q", rt, sock)
+	}
+
+	colima := filepath.Join(home, ".colima", "default", "docker.sock")
+	l := listenUnix(t, colima)
+	defer l.Close()
+	rt, sock := detectContainerRuntime(home, "")
+	if rt != RuntimeColima || sock != colima {
+		t.Errorf("detectContainerRuntime() = %q, %q", rt, sock)
+	}
+
+	// A regular file is not a socket.
+	orb := filepath.Join(home, ".orbstack", "run", "docker.sock")
+	os.MkdirAll(filepath.Dir(orb), 0o755)
+	os.WriteFile(orb, nil, 0o644)
+	if rt, _ := detectContainerRuntime(home, ""); rt != RuntimeColima {
+		t.Errorf("runtime = %q, want colima (orbstack path is not a socket)", rt)
+	}
+
+	// DOCKER_HOST wins and is resolved through symlinks.
+	link := filepath.Join(home, "docker.sock")
+	if err := os.Symlink(colima, link); err != nil {
+		t.Fatal(err)
+	}
+	if rt, sock := detectContainerRuntime(home, "unix://"+link); rt != RuntimeColima || sock != link {
+		t.Errorf("DOCKER_HOST = %q, %q", rt, sock)
+	}
+	if rt := runtimeForSocket(home, "/run/user/1000/podman/podman.sock"); rt != RuntimePodman {
+		t.Errorf("runtimeForSocket(podman) = %q", rt)
+	}
+}
+
+func TestApplyDockerDF(t *testing.T) {
+	input := `{
+  "LayersSize": 10737418240,
+  "Images": [{"Id": "sha256:abc", "Size": 1000}],
+  "Containers": [],
+  "Volumes": [
+    {"Name": "pgdata", "UsageData": {"RefCount": 1, "Size": 2147483648}},
+    {"Name": "unknown", "UsageData": {"RefCount": 0, "Size": -1}}
+  ],
+  "BuildCache": [{"ID": "x", "Size": 536870912}, {"ID": "y", "Size": 536870912}]
+}`
+	var c Containers
+	applyDockerDF(&c, []byte(input))
+	if !c.DiskUsageKnown || c.ImagesBytes != 10737418240 || c.VolumesBytes != 2147483648 || c.BuildCacheBytes != 1073741824 {
+		t.Errorf("applyDockerDF() = %+v", c)
+	}
+}
+
+func TestVMDiskUsage(t *testing.T) {
+	home := t.TempDir()
+	disk := filepath.Join(home, ".colima", "_lima", "colima", "diffdisk")
+	os.MkdirAll(filepath.Dir(disk), 0o755)
+	f, err := os.Create(disk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(make([]byte, 1<<20))
+	f.Truncate(100 << 30) // sparse 100 GB image with 1 MB written
+	f.Close()
+
+	capacity, used := vmDiskUsage(home, []string{".colima/_lima/colima/diffdisk"})
+	if capacity != 100<<30 {
+		t.Errorf("capacity = %d, want 100 GB", capacity)
+	}
+	if used < 1<<20 || used > 10<<20 {
+		t.Errorf("used = %d, want about 1 MB", used)
+	}
+	if c, u := vmDiskUsage(home, []string{"missing"}); c != 0 || u != 0 {
+		t.Errorf("missing disk = %d, %d", c, u)
+	}
+}
+
+func TestContainersStatusAndDiagnosis(t *testing.T) {
+	cfg := ContainersConfig{YellowDiskPercent: 80, RedDiskPercent: 90}
+	tests := []struct {
+		name string
+		c    Containers
+		want Status
+	}{
+		{"not installed", Containers{}, StatusGreen},
+		{"healthy", Containers{Installed: true, Reachable: true, VMDiskPercent: 40}, StatusGreen},
+		{"unreachable", Containers{Installed: true}, StatusYellow},
+		{"disk yellow", Containers{Installed: true, Reachable: true, VMDiskPercent: 85}, StatusYellow},
+		{"disk red", Containers{Installed: true, Reachable: true, VMDiskPercent: 95}, StatusRed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := containersStatus(tt.c, cfg); got != tt.want {
+				t.Errorf("containersStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	d := DiagnoseContainers(Containers{Status: StatusYellow, Installed: true, Runtime: RuntimeColima, Socket: "/x/docker.sock", Error: "connection refused"})
+	if d == nil || d.Summary != "Container daemon is not reachable" || d.Action != "Run 'colima start'" {
+		t.Errorf("unreachable diagnosis = %+v", d)
+	}
+	d = DiagnoseContainers(Containers{Status: StatusYellow, Installed: true, Runtime: RuntimeDocker, Socket: "/var/run/docker.sock", PermissionDenied: true})
+	if d == nil || !strings.Contains(d.Summary, "permission") || !strings.Contains(d.Action, "docker group") {
+		t.Errorf("permission diagnosis = %+v", d)
+	}
+	d = DiagnoseContainers(Containers{Status: StatusRed, Installed: true, Reachable: true, Runtime: RuntimeDockerDesktop,
+		VMDiskPercent: 95, VMDiskBytes: 64 << 30, VMDiskUsedBytes: 61 << 30, DiskUsageKnown: true, ImagesBytes: 40 << 30})
+	if d == nil || !strings.Contains(d.Detail, "95% full") || !strings.Contains(d.Detail, "Images 40.0 GB") {
+		t.Errorf("disk diagnosis = %+v", d)
+	}
+	if DiagnoseContainers(Containers{Status: StatusGreen}) != nil {
+		t.Error("expected nil for green")
+	}
+}
@@ -0,0 +1,313 @@
+package health
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Crash report kinds.
+const (
+	CrashKindCrash = "crash"
+	CrashKindHang  = "hang"
+)
+
+// crashReportDirs returns the per-user and system DiagnosticReports folders.
+func crashReportDirs() []string {
+	dirs := []string{"/Library/Logs/DiagnosticReports"}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append([]string{filepath.Join(home, "Library", "Logs", "DiagnosticReports")}, dirs...)
+	}
+	return dirs
+}
+
+// CheckCrashes scans the DiagnosticReports folders for application crash and
+// hang reports inside the configured window. A process with at least
+// RepeatThreshold reports in the window turns crashes yellow.
+func CheckCrashes(cfg CrashesConfig) Crashes {
+	since := time.Now().Add(-time.Duration(cfg.Window))
+	return summarizeCrashes(scanCrashReports(crashReportDirs(), since), cfg.RepeatThreshold)
+}
+
+// crashReport is a single parsed report file.
+type crashReport struct {
+	File          string
+	Process       string
+	Kind          string
+	ExceptionType string
+	Time          time.Time
+}
+
+// scanCrashReports parses every .ips, .crash, .hang and .spin report in dirs
+// whose timestamp is after since. Files older than since by modification time
+// are skipped without being opened.
+func scanCrashReports(dirs []string, since time.Time) []crashReport {
+	var reports []crashReport
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			ext := filepath.Ext(e.Name())
+			if ext != ".ips" && ext != ".crash" && ext != ".hang" && ext != ".spin" {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil || info.ModTime().Before(since) {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			rep, ok := parseCrashReportFile(path)
+			if !ok {
+				continue
+			}
+			if rep.Time.IsZero() {
+				rep.Time = info.ModTime().UTC()
+			}
+			if rep.Time.Before(since) {
+				continue
+			}
+			reports = append(reports, rep)
+		}
+	}
+	return reports
+}
+
+// maxCrashHeaderBytes bounds how much of a text report is read; the fields
+// we need are in the header.
+const maxCrashHeaderBytes = 64 << 10
+
+func parseCrashReportFile(path string) (crashReport, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return crashReport{}, false
+	}
+	defer f.Close()
+
+	var rep crashReport
+	var ok bool
+	switch filepath.Ext(path) {
+	case ".ips":
+		rep, ok = parseIPS(f)
+	case ".hang", ".spin":
+		rep, ok = parseTextCrashReport(io.LimitReader(f, maxCrashHeaderBytes))
+		rep.Kind = CrashKindHang
+	default:
+		rep, ok = parseTextCrashReport(io.LimitReader(f, maxCrashHeaderBytes))
+		rep.Kind = CrashKindCrash
+	}
+	if !ok {
+		return crashReport{}, false
+	}
+	if rep.Process == "" {
+		rep.Process = processFromReportName(filepath.Base(path))
+	}
+	rep.File = path
+	return rep, true
+}
+
+// ipsHeader is the single-line JSON header of an .ips report.
+type ipsHeader struct {
+	AppName   string `json:"app_name"`
+	Name      string `json:"name"`
+	BugType   string `json:"bug_type"`
+	Timestamp string `json:"timestamp"`
+}
+
+// ipsBody holds the fields we use from the JSON body of a crash (bug_type 309) report.
+type ipsBody struct {
+	ProcName  string `json:"procName"`
+	Exception struct {
+		Type   string `json:"type"`
+		Signal string `json:"signal"`
+	} `json:"exception"`
+}
+
+// ipsTimeLayouts are the timestamp formats seen in .ips headers.
+var ipsTimeLayouts = []string{
+	"2006-01-02 15:04:05.00 -0700",
+	"2006-01-02 15:04:05 -0700",
+}
+
+// parseIPS parses an .ips report: a one-line JSON header followed, for
+// crashes, by a JSON body. bug_type 309 (and legacy 109) are crashes; 288
+// (stackshot) and 298/385 (hang, spin) are hangs. Other types (kernel panics,
+// jetsam events, ...) are not application reports and are skipped.
+func parseIPS(r io.Reader) (crashReport, bool) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	var h ipsHeader
+	if err := dec.Decode(&h); err != nil {
+		return crashReport{}, false
+	}
+
+	rep := crashReport{Process: h.AppName}
+	if rep.Process == "" {
+		rep.Process = h.Name
+	}
+	for _, layout := range ipsTimeLayouts {
+		if t, err := time.Parse(layout, h.Timestamp); err == nil {
+			rep.Time = t.UTC()
+			break
+		}
+	}
+
+	switch h.BugType {
+	case "309", "109":
+		rep.Kind = CrashKindCrash
+		var body ipsBody
+		if err := dec.Decode(&body); err == nil {
+			if rep.Process == "" {
+				rep.Process = body.ProcName
+			}
+			rep.ExceptionType = body.Exception.Type
+			if body.Exception.Signal != "" && rep.ExceptionType != "" {
+				rep.ExceptionType += " (" + body.Exception.Signal + ")"
+			}
+		}
+	case "288", "298", "385":
+		rep.Kind = CrashKindHang
+	default:
+		return crashReport{}, false
+	}
+	return rep, true
+}
+
+var (
+	crashProcessRe   = regexp.MustCompile(`(?m)^(?:Process|Command):\s+(.+?)(?:\s+\[\d+\])?\s*$`)
+	crashExceptionRe = regexp.MustCompile(`(?m)^Exception Type:\s+(.+?)\s*$`)
+	crashDateRe      = regexp.MustCompile(`(?m)^Date/Time:\s+(.+?)\s*$`)
+)
+
+// crashTimeLayouts are the Date/Time formats seen in text reports.
+var crashTimeLayouts = []string{
+	"2006-01-02 15:04:05.000 -0700",
+	"2006-01-02 15:04:05 -0700",
+}
+
+// parseTextCrashReport parses the header of a legacy .crash, .hang or .spin
+// report ("Process: git [48213]", "Command: Finder", "Exception Type:
+// EXC_BAD_ACCESS (SIGSEGV)", "Date/Time: 2026-02-18 08:55:00.123 -0800").
+func parseTextCrashReport(r io.Reader) (crashReport, bool) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return crashReport{}, false
+	}
+	s := string(data)
+
+	var rep crashReport
+	if m := crashProcessRe.FindStringSubmatch(s); len(m) >= 2 {
+		rep.Process = m[1]
+	}
+	if m := crashExceptionRe.FindStringSubmatch(s); len(m) >= 2 {
+		rep.ExceptionType = m[1]
+	}
+	if m := crashDateRe.FindStringSubmatch(s); len(m) >= 2 {
+		for _, layout := range crashTimeLayouts {
+			if t, err := time.Parse(layout, m[1]); err == nil {
+				rep.Time = t.UTC()
+				break
+			}
+		}
+	}
+	return rep, rep.Process != "" || rep.ExceptionType != ""
+}
+
+// processFromReportName derives the process from a report file name such as
+// "Finder_2026-02-18-091500_host.hang" or "git-2026-02-18-085500.crash".
+func processFromReportName(name string) string {
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if i := strings.IndexAny(name, "_-"); i > 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// summarizeCrashes groups reports by process, kind and exception type, most
+// frequent first, and sets status from the busiest process.
+func summarizeCrashes(reports []crashReport, repeatThreshold int) Crashes {
+	c := Crashes{Status: StatusGreen, Total: len(reports)}
+
+	byKey := map[string]*CrashGroup{}
+	perProcess := map[string]int{}
+	for _, rep := range reports {
+		key := rep.Process + "\x00" + rep.Kind + "\x00" + rep.ExceptionType
+		g, ok := byKey[key]
+		if !ok {
+			g = &CrashGroup{Process: rep.Process, Kind: rep.Kind, ExceptionType: rep.ExceptionType}
+			byKey[key] = g
+		}
+		g.Count++
+		if rep.Time.After(g.MostRecent) {
+			g.MostRecent = rep.Time
+			g.LastReport = rep.File
+		}
+		perProcess[rep.Process]++
+	}
+
+	for _, g := range byKey {
+		c.Groups = append(c.Groups, *g)
+	}
+	sort.Slice(c.Groups, func(i, j int) bool {
+		a, b := c.Groups[i], c.Groups[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if !a.MostRecent.Equal(b.MostRecent) {
+			return a.MostRecent.After(b.MostRecent)
+		}
+		return a.Process < b.Process
+	})
+
+	for proc, n := range perProcess {
+		if n > c.TopCount || (n == c.TopCount && proc < c.TopProcess) {
+			c.TopProcess, c.TopCount = proc, n
+		}
+	}
+	if repeatThreshold > 0 && c.TopCount >= repeatThreshold {
+		c.Status = StatusYellow
+	}
+	return c
+}
+
+// DiagnoseCrashes returns diagnosis naming the process that crashed or hung most.
+func DiagnoseCrashes(c Crashes) *Diagnosis {
+	if c.Status == StatusGreen {
+		return nil
+	}
+	d := &Diagnosis{
+		Subsystem: "crashes",
+		Severity:  c.Status,
+		Summary:   fmt.Sprintf("%s crashed or hung %d times recently", c.TopProcess, c.TopCount),
+	}
+
+	var parts []string
+	var last CrashGroup
+	for _, g := range c.Groups {
+		if g.Process != c.TopProcess {
+			continue
+		}
+		desc := fmt.Sprintf("%d× %s", g.Count, g.Kind)
+		if g.ExceptionType != "" {
+			desc += " " + g.ExceptionType
+		}
+		parts = append(parts, desc)
+		if g.MostRecent.After(last.MostRecent) {
+			last = g
+		}
+	}
+	d.Detail = fmt.Sprintf("%s: %s. Most recent at %s (%s). %d report(s) in total across all apps.",
+		c.TopProcess, strings.Join(parts, ", "), last.MostRecent.Format(time.RFC3339), last.LastReport, c.Total)
+	d.Action = fmt.Sprintf("Update or reinstall %s, and open the most recent report in Console to find the faulting module", c.TopProcess)
+	return d
+}
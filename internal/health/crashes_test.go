@@ -0,0 +1,111 @@
+package health
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const crashFixtures = "testdata/diagnosticreports"
+
+func TestParseIPS(t *testing.T) {
+	rep, ok := parseCrashReportFile(filepath.Join(crashFixtures, "xcodebuild-2026-02-18-080000.ips"))
+	if !ok {
+		t.Fatal("parseCrashReportFile() failed")
+	}
+	if rep.Process != "xcodebuild" || rep.Kind != CrashKindCrash {
+		t.Errorf("process/kind = %q/%q", rep.Process, rep.Kind)
+	}
+	if rep.ExceptionType != "EXC_BAD_ACCESS (SIGSEGV)" {
+		t.Errorf("ExceptionType = %q", rep.ExceptionType)
+	}
+	want := time.Date(2026, 2, 18, 16, 0, 0, 0, time.UTC)
+	if !rep.Time.Equal(want) {
+		t.Errorf("Time = %v, want %v", rep.Time, want)
+	}
+
+	// Non-application reports (kernel panics, jetsam) are skipped.
+	if _, ok := parseIPS(strings.NewReader(`{"bug_type":"210","timestamp":"2026-02-18 08:00:00.00 -0800"}` + "\n{}")); ok {
+		t.Error("expected panic report to be skipped")
+	}
+	if _, ok := parseIPS(strings.NewReader("not json")); ok {
+		t.Error("expected failure on garbage")
+	}
+}
+
+func TestParseTextCrashReport(t *testing.T) {
+	rep, ok := parseCrashReportFile(filepath.Join(crashFixtures, "git-2026-02-18-085500.crash"))
+	if !ok {
+		t.Fatal("parseCrashReportFile() failed")
+	}
+	if rep.Process != "git" || rep.Kind != CrashKindCrash || rep.ExceptionType != "EXC_BAD_ACCESS (SIGSEGV)" {
+		t.Errorf("got %+v", rep)
+	}
+	if !rep.Time.Equal(time.Date(2026, 2, 18, 16, 55, 0, 123e6, time.UTC)) {
+		t.Errorf("Time = %v", rep.Time)
+	}
+
+	rep, ok = parseCrashReportFile(filepath.Join(crashFixtures, "Finder_2026-02-18-091500_host.hang"))
+	if !ok {
+		t.Fatal("parseCrashReportFile() failed on hang")
+	}
+	if rep.Process != "Finder" || rep.Kind != CrashKindHang || rep.ExceptionType != "" {
+		t.Errorf("got %+v", rep)
+	}
+}
+
+func TestProcessFromReportName(t *testing.T) {
+	tests := map[string]string{
+		"Finder_2026-02-18-091500_host.hang": "Finder",
+		"git-2026-02-18-085500.crash":        "git",
+		"plain.spin":                         "plain",
+	}
+	for in, want := range tests {
+		if got := processFromReportName(in); got != want {
+			t.Errorf("processFromReportName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestScanCrashReportsFixtures(t *testing.T) {
+	since := time.Date(2026, 2, 18, 0, 0, 0, 0, time.UTC)
+	reports := scanCrashReports([]string{crashFixtures, "testdata/missing"}, since)
+	if len(reports) != 5 {
+		t.Fatalf("got %d reports, want 5 (old report and notes.txt excluded): %+v", len(reports), reports)
+	}
+
+	c := summarizeCrashes(reports, 3)
+	if c.Status != StatusYellow {
+		t.Errorf("Status = %v, want yellow", c.Status)
+	}
+	if c.Total != 5 || c.TopProcess != "xcodebuild" || c.TopCount != 3 {
+		t.Errorf("Total/TopProcess/TopCount = %d/%q/%d", c.Total, c.TopProcess, c.TopCount)
+	}
+	if len(c.Groups) != 4 {
+		t.Fatalf("got %d groups, want 4: %+v", len(c.Groups), c.Groups)
+	}
+	g := c.Groups[0]
+	if g.Process != "xcodebuild" || g.ExceptionType != "EXC_BAD_ACCESS (SIGSEGV)" || g.Count != 2 {
+		t.Errorf("Groups[0] = %+v", g)
+	}
+	if !g.MostRecent.Equal(time.Date(2026, 2, 18, 17, 0, 0, 0, time.UTC)) || !strings.HasSuffix(g.LastReport, "xcodebuild-2026-02-18-090000.ips") {
+		t.Errorf("Groups[0] most recent = %v %s", g.MostRecent, g.LastReport)
+	}
+
+	d := DiagnoseCrashes(c)
+	if d == nil {
+		t.Fatal("expected diagnosis")
+	}
+	if !strings.Contains(d.Summary, "xcodebuild") || !strings.Contains(d.Detail, "2× crash EXC_BAD_ACCESS (SIGSEGV)") ||
+		!strings.Contains(d.Detail, "xcodebuild-2026-02-18-093000.ips") {
+		t.Errorf("diagnosis = %+v", d)
+	}
+
+	if c := summarizeCrashes(reports, 4); c.Status != StatusGreen || DiagnoseCrashes(c) != nil {
+		t.Errorf("threshold 4: Status = %v, want green", c.Status)
+	}
+	if c := summarizeCrashes(reports, 0); c.Status != StatusGreen {
+		t.Errorf("threshold 0 should disable, got %v", c.Status)
+	}
+}
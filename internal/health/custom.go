@@ -0,0 +1,246 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// customOutputLimit caps how much of a script's stdout is kept.
+const customOutputLimit = 64 << 10
+
+// CheckCustom runs the user-defined check scripts in parallel, each bounded
+// by its timeout. A script reports either by printing a JSON object to
+// stdout:
+//
+//	{"status": "yellow", "summary": "...", "detail": "...", "action": "...", "metrics": {"latency_ms": 42}}
+//
+// or Nagios-style, through its exit code (0 OK, 1 WARNING, 2 CRITICAL,
+// 3 UNKNOWN) and a "SUMMARY | perfdata" first line. Scripts that cannot be
+// started, time out or report unknown are yellow.
+func CheckCustom(cfg CustomConfig) []CustomResult {
+	if len(cfg.Checks) == 0 {
+		return nil
+	}
+	results := make([]CustomResult, len(cfg.Checks))
+	var wg sync.WaitGroup
+	for i, c := range cfg.Checks {
+		timeout := time.Duration(c.Timeout)
+		if timeout <= 0 {
+			timeout = time.Duration(cfg.Timeout)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runCustomCheck(c, timeout)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func runCustomCheck(c CustomCheck, timeout time.Duration) CustomResult {
+	res := CustomResult{Name: c.Name, Weight: c.Weight, ExitCode: -1}
+	if len(c.Command) == 0 {
+		res.Status = StatusYellow
+		res.Error = "no command configured"
+		return res
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, c.Command[0], c.Command[1:]...)
+	// Don't wait on grandchildren that inherited stdout after a timeout.
+	cmd.WaitDelay = 100 * time.Millisecond
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	res.DurationMs = time.Since(start).Milliseconds()
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		res.Status = StatusYellow
+		res.Error = "timed out after " + FormatDuration(timeout)
+		return res
+	case errors.As(err, &exitErr):
+		res.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		res.Status = StatusYellow
+		res.Error = err.Error()
+		return res
+	default:
+		res.ExitCode = 0
+	}
+
+	out := stdout.Bytes()
+	if len(out) > customOutputLimit {
+		out = out[:customOutputLimit]
+	}
+	if !applyCustomJSON(&res, out) {
+		applyNagiosOutput(&res, string(out))
+	}
+	if res.Status == StatusYellow && res.Summary == "" && res.Error == "" {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			res.Error = firstLine(msg)
+		}
+	}
+	return res
+}
+
+// customOutput is the JSON contract for check scripts.
+type customOutput struct {
+	Status  string             `json:"status"`
+	Summary string             `json:"summary"`
+	Detail  string             `json:"detail"`
+	Action  string             `json:"action"`
+	Metrics map[string]float64 `json:"metrics"`
+}
+
+// applyCustomJSON applies stdout as the JSON contract. It reports false if
+// stdout is not a JSON object with a status, so the exit code applies.
+func applyCustomJSON(res *CustomResult, out []byte) bool {
+	trimmed := bytes.TrimSpace(out)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return false
+	}
+	var o customOutput
+	if err := json.Unmarshal(trimmed, &o); err != nil || o.Status == "" {
+		return false
+	}
+	status, ok := parseCustomStatus(o.Status)
+	if !ok {
+		res.Status = StatusYellow
+		res.Error = fmt.Sprintf("unknown status %q", o.Status)
+	} else {
+		res.Status = status
+	}
+	res.Summary = o.Summary
+	res.Detail = o.Detail
+	res.Action = o.Action
+	res.Metrics = o.Metrics
+	return true
+}
+
+// parseCustomStatus accepts machealth and Nagios status names.
+func parseCustomStatus(s string) (Status, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "green", "ok":
+		return StatusGreen, true
+	case "yellow", "warning", "warn":
+		return StatusYellow, true
+	case "red", "critical", "crit":
+		return StatusRed, true
+	default:
+		return StatusYellow, false
+	}
+}
+
+// applyNagiosOutput maps the exit code to a status and parses the plugin
+// output: the first line is "SUMMARY | perfdata", further lines are detail.
+func applyNagiosOutput(res *CustomResult, out string) {
+	switch res.ExitCode {
+	case 0:
+		res.Status = StatusGreen
+	case 1:
+		res.Status = StatusYellow
+	case 2:
+		res.Status = StatusRed
+	default:
+		res.Status = StatusYellow
+		res.Error = fmt.Sprintf("unknown state (exit %d)", res.ExitCode)
+	}
+
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return
+	}
+	first, rest, _ := strings.Cut(out, "\n")
+	summary, perf, _ := strings.Cut(first, "|")
+	res.Summary = strings.TrimSpace(summary)
+	res.Detail = strings.TrimSpace(rest)
+	res.Metrics = parsePerfdata(perf)
+}
+
+// parsePerfdata parses Nagios performance data:
+// "time=0.21s;1;2;0 'cache hits'=93%;;" -> {"time": 0.21, "cache hits": 93}.
+func parsePerfdata(s string) map[string]float64 {
+	var metrics map[string]float64
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		var label string
+		if strings.HasPrefix(s, "'") {
+			end := strings.Index(s[1:], "'=")
+			if end < 0 {
+				break
+			}
+			label, s = s[1:end+1], s[end+3:]
+		} else {
+			eq := strings.IndexByte(s, '=')
+			if eq < 0 {
+				break
+			}
+			label, s = s[:eq], s[eq+1:]
+		}
+		value, next, _ := strings.Cut(s, " ")
+		s = next
+		value, _, _ = strings.Cut(value, ";")
+		value = strings.TrimRight(value, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ%")
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			if metrics == nil {
+				metrics = map[string]float64{}
+			}
+			metrics[label] = f
+		}
+	}
+	return metrics
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// DiagnoseCustom returns one diagnosis per custom check that is not green,
+// using the script's own summary, detail and action when it provides them.
+func DiagnoseCustom(results []CustomResult) []Diagnosis {
+	var diags []Diagnosis
+	for _, c := range results {
+		if c.Status == StatusGreen {
+			continue
+		}
+		d := Diagnosis{
+			Subsystem: "custom:" + c.Name,
+			Severity:  c.Status,
+			Summary:   c.Summary,
+			Detail:    c.Detail,
+			Action:    c.Action,
+		}
+		if d.Summary == "" {
+			d.Summary = fmt.Sprintf("Custom check %s is %s", c.Name, c.Status)
+		}
+		if c.Error != "" {
+			if d.Detail != "" {
+				d.Detail += " "
+			}
+			d.Detail += "Check error: " + c.Error + "."
+		}
+		if d.Detail == "" {
+			d.Detail = fmt.Sprintf("The %s check exited %d.", c.Name, c.ExitCode)
+		}
+		if d.Action == "" {
+			d.Action = fmt.Sprintf("Run the %s check script by hand to see its full output", c.Name)
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
@@ -0,0 +1,114 @@
+package health
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckCustom(t *testing.T) {
+	cfg := CustomConfig{
+		Timeout: Duration(5 * time.Second),
+		Checks: []CustomCheck{
+			{Name: "json", Command: []string{"/bin/sh", "-c", `echo '{"status":"warning","summary":"Slow","action":"Check VPN","metrics":{"latency_ms":2100}}'`}, Weight: 5},
+			{Name: "nagios-ok", Command: []string{"/bin/sh", "-c", `echo 'LICENSE OK - 12 seats free | seats=12;2;1;0'`}},
+			{Name: "nagios-crit", Command: []string{"/bin/sh", "-c", "echo 'CACHE CRITICAL - not mounted'; exit 2"}},
+			{Name: "unknown", Command: []string{"/bin/sh", "-c", "echo 'UNKNOWN - bad args' ; exit 3"}},
+			{Name: "slow", Command: []string{"/bin/sleep", "5"}, Timeout: Duration(100 * time.Millisecond)},
+			{Name: "missing", Command: []string{"/nonexistent/check"}},
+			{Name: "empty"},
+		},
+	}
+
+	start := time.Now()
+	results := CheckCustom(cfg)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("CheckCustom() took %v; checks should run in parallel and honour timeouts", elapsed)
+	}
+	if len(results) != len(cfg.Checks) {
+		t.Fatalf("got %d results, want %d", len(results), len(cfg.Checks))
+	}
+
+	byName := map[string]CustomResult{}
+	for _, r := range results {
+		byName[r.Name] = r
+	}
+
+	if r := byName["json"]; r.Status != StatusYellow || r.Summary != "Slow" || r.Action != "Check VPN" ||
+		r.Metrics["latency_ms"] != 2100 || r.Weight != 5 || r.ExitCode != 0 {
+		t.Errorf("json = %+v", r)
+	}
+	if r := byName["nagios-ok"]; r.Status != StatusGreen || r.Summary != "LICENSE OK - 12 seats free" || r.Metrics["seats"] != 12 {
+		t.Errorf("nagios-ok = %+v", r)
+	}
+	if r := byName["nagios-crit"]; r.Status != StatusRed || r.ExitCode != 2 || r.Summary != "CACHE CRITICAL - not mounted" {
+		t.Errorf("nagios-crit = %+v", r)
+	}
+	if r := byName["unknown"]; r.Status != StatusYellow || !strings.Contains(r.Error, "exit 3") {
+		t.Errorf("unknown = %+v", r)
+	}
+	if r := byName["slow"]; r.Status != StatusYellow || !strings.Contains(r.Error, "timed out") || r.ExitCode != -1 {
+		t.Errorf("slow = %+v", r)
+	}
+	if r := byName["missing"]; r.Status != StatusYellow || r.Error == "" {
+		t.Errorf("missing = %+v", r)
+	}
+	if r := byName["empty"]; r.Status != StatusYellow || r.Error != "no command configured" {
+		t.Errorf("empty = %+v", r)
+	}
+
+	if got := CheckCustom(CustomConfig{}); got != nil {
+		t.Errorf("no checks = %+v, want nil", got)
+	}
+}
+
+func TestApplyCustomJSON(t *testing.T) {
+	var r CustomResult
+	if applyCustomJSON(&r, []byte("OK - fine")) {
+		t.Error("plain text should fall back to the exit code")
+	}
+	if applyCustomJSON(&r, []byte(`{"summary":"no status"}`)) {
+		t.Error("JSON without status should fall back to the exit code")
+	}
+	if !applyCustomJSON(&r, []byte(`{"status":"purple"}`)) || r.Status != StatusYellow || r.Error == "" {
+		t.Errorf("unknown status = %+v", r)
+	}
+}
+
+func TestParsePerfdata(t *testing.T) {
+	got := parsePerfdata(" time=0.21s;1;2;0 'cache hits'=93%;; size=1.5GB bogus")
+	want := map[string]float64{"time": 0.21, "cache hits": 93, "size": 1.5}
+	if len(got) != len(want) {
+		t.Fatalf("parsePerfdata() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %v, want %v", k, got[k], v)
+		}
+	}
+	if got := parsePerfdata(""); got != nil {
+		t.Errorf("empty perfdata = %v", got)
+	}
+}
+
+func TestDiagnoseCustom(t *testing.T) {
+	results := []CustomResult{
+		{Name: "ok", Status: StatusGreen},
+		{Name: "license", Status: StatusRed, Summary: "License server down", Action: "Restart lmgrd", ExitCode: 2},
+		{Name: "slow", Status: StatusYellow, Error: "timed out after 5s", ExitCode: -1},
+	}
+	diags := DiagnoseCustom(results)
+	if len(diags) != 2 {
+		t.Fatalf("got %d diagnoses, want 2", len(diags))
+	}
+	if d := diags[0]; d.Subsystem != "custom:license" || d.Summary != "License server down" || d.Action != "Restart lmgrd" {
+		t.Errorf("license = %+v", d)
+	}
+	if d := diags[1]; d.Severity != StatusYellow || !strings.Contains(d.Summary, "slow") ||
+		!strings.Contains(d.Detail, "timed out") || d.Action == "" {
+		t.Errorf("slow = %+v", d)
+	}
+	if DiagnoseCustom(nil) != nil {
+		t.Error("expected nil for no results")
+	}
+}
@@ -1,24 +1,37 @@
 package health
 
 import (
+	"fmt"
+	"io/fs"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
 )
 
-// CheckICloud collects iCloud sync state.
-func CheckICloud() ICloud {
+// CheckICloud collects iCloud sync state for every container, plus the
+// materialisation state of any configured folders.
+func CheckICloud(cfg ICloudConfig) ICloud {
 	ic := ICloud{Status: StatusGreen, CaughtUp: true}
 
-	out, err := exec.Command("/usr/bin/brctl", "status", "com.apple.CloudDocs").Output()
+	for _, p := range cfg.Paths {
+		ip := scanICloudPath(expandHome(p))
+		ic.Paths = append(ic.Paths, ip)
+		if ip.Error != "" || ip.Evicted > 0 {
+			ic.Status = StatusYellow
+		}
+	}
+
+	out, err := exec.Command("/usr/bin/brctl", "status").Output()
 	if err != nil {
 		// brctl not available or failed — assume OK
 		return ic
 	}
 
-	ic.Syncing, ic.CaughtUp, ic.LastSync = parseICloud(string(out))
+	ic.Containers = parseBrctlStatus(string(out))
+	applyICloudContainers(&ic, string(out))
 
-	if !ic.CaughtUp {
+	if !ic.CaughtUp || ic.ErrorCount > 0 {
 		ic.Status = StatusYellow
 	}
 
@@ -27,10 +40,32 @@ func CheckICloud() ICloud {
 
 var (
 	// Strip ANSI escape codes from brctl output
-	ansiRe    = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	ansiRe     = regexp.MustCompile(`\x1b\[[0-9;]*m`)
 	syncTimeRe = regexp.MustCompile(`last-sync:(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})`)
+
+	// Container header, e.g.
+	// <com.apple.CloudDocs[1] foreground {client:idle server:full-sync ... caught-up, token:abc}>
+	brContainerRe   = regexp.MustCompile(`^<([^\[\s>]+)\[\d+\][^{]*\{(.*)\}>`)
+	brClientStateRe = regexp.MustCompile(`client:([\w-]+)`)
+
+	// State token naming an error, e.g. "error:quota-exceeded" or
+	// "st:upload-failed". Only whitespace-, brace- or comma-delimited
+	// key:value tokens count, so item paths such as
+	// /Documents/failover-notes.md are not mistaken for errors.
+	brErrorTokenRe = regexp.MustCompile(`(?i)(?:^|[\s{,])([\w-]*(?:error|fail)[\w-]*:[\w.-]+|[\w-]+:[\w-]*(?:error|fail)[\w-]*)`)
 )
 
+// brErrorTokens returns the error state tokens in a brctl header or item line.
+func brErrorTokens(s string) []string {
+	var tokens []string
+	for _, m := range brErrorTokenRe.FindAllStringSubmatch(s, -1) {
+		tokens = append(tokens, m[1])
+	}
+	return tokens
+}
+
+const cloudDocsContainer = "com.apple.CloudDocs"
+
 func parseICloud(s string) (syncing, caughtUp bool, lastSync string) {
 	// Strip ANSI codes
 	s = ansiRe.ReplaceAllString(s, "")
@@ -45,6 +80,138 @@ func parseICloud(s string) (syncing, caughtUp bool, lastSync string) {
 	return
 }
 
+// parseBrctlStatus splits `brctl status` output into per-container entries.
+// Each container starts with a "<id[n] ... {state}>" header; the item lines
+// that follow (until the next header) are counted as pending uploads or
+// downloads by their state tokens, and any error/failure tokens are kept.
+func parseBrctlStatus(s string) []ICloudContainer {
+	s = ansiRe.ReplaceAllString(s, "")
+
+	var containers []ICloudContainer
+	var cur *ICloudContainer
+
+	flush := func() {
+		if cur != nil {
+			containers = append(containers, *cur)
+			cur = nil
+		}
+	}
+
+	for _, line := range strings.Split(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "---") {
+			continue
+		}
+
+		if m := brContainerRe.FindStringSubmatch(trimmed); len(m) >= 3 {
+			flush()
+			state := m[2]
+			cur = &ICloudContainer{
+				ID:       m[1],
+				CaughtUp: strings.Contains(state, "caught-up"),
+			}
+			if sm := brClientStateRe.FindStringSubmatch(state); len(sm) >= 2 {
+				cur.State = sm[1]
+			}
+			if sm := syncTimeRe.FindStringSubmatch(state); len(sm) >= 2 {
+				cur.LastSync = sm[1]
+			}
+			cur.Errors = append(cur.Errors, brErrorTokens(state)...)
+			continue
+		}
+
+		if cur == nil {
+			continue
+		}
+
+		lower := strings.ToLower(trimmed)
+		switch {
+		case strings.Contains(lower, "needs-upload") || strings.Contains(lower, "uploading"):
+			cur.PendingUploads++
+		case strings.Contains(lower, "needs-download") || strings.Contains(lower, "downloading"):
+			cur.PendingDownloads++
+		}
+		if errs := brErrorTokens(trimmed); len(errs) > 0 {
+			cur.Errors = append(cur.Errors, errs...)
+		}
+	}
+	flush()
+
+	return containers
+}
+
+// applyICloudContainers derives the top-level fields from parsed containers.
+// Syncing/CaughtUp/LastSync keep describing iCloud Drive (CloudDocs); if no
+// container header could be parsed, the raw output is matched as before.
+func applyICloudContainers(ic *ICloud, raw string) {
+	foundDocs := false
+	for _, c := range ic.Containers {
+		ic.PendingUploads += c.PendingUploads
+		ic.PendingDownloads += c.PendingDownloads
+		ic.ErrorCount += len(c.Errors)
+		if c.ID == cloudDocsContainer {
+			foundDocs = true
+			ic.CaughtUp = c.CaughtUp
+			ic.Syncing = c.State == "needs-sync" || !c.CaughtUp
+			ic.LastSync = c.LastSync
+		}
+	}
+	if !foundDocs {
+		ic.Syncing, ic.CaughtUp, ic.LastSync = parseICloud(raw)
+	}
+}
+
+// maxICloudScanFiles bounds the walk of a configured folder so a huge tree
+// cannot stall the health check.
+const maxICloudScanFiles = 20000
+
+// scanICloudPath walks p and counts files that have been evicted from local
+// storage: legacy ".name.icloud" placeholders on every macOS release and
+// APFS dataless files on macOS Sonoma and later. Unreadable entries below p
+// are skipped and counted; only a partial scan that found nothing evicted
+// leaves Materialized false without degrading status.
+func scanICloudPath(p string) ICloudPath {
+	ip := ICloudPath{Path: p}
+
+	err := filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == p {
+				return err
+			}
+			ip.Skipped++
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if ip.Files >= maxICloudScanFiles {
+			ip.Truncated = true
+			return filepath.SkipAll
+		}
+		ip.Files++
+
+		name := d.Name()
+		if strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".icloud") {
+			ip.Evicted++
+			return nil
+		}
+		if info, err := d.Info(); err == nil && isDataless(info) {
+			ip.Evicted++
+		}
+		return nil
+	})
+	if err != nil {
+		ip.Error = fmt.Sprintf("failed to scan %s: %v", p, err)
+		return ip
+	}
+
+	ip.Materialized = ip.Evicted == 0 && !ip.Truncated && ip.Skipped == 0
+	return ip
+}
+
 // DiagnoseICloud returns diagnosis for iCloud issues.
 func DiagnoseICloud(ic ICloud) *Diagnosis {
 	if ic.Status == StatusGreen {
@@ -53,9 +220,44 @@ func DiagnoseICloud(ic ICloud) *Diagnosis {
 	d := &Diagnosis{
 		Subsystem: "icloud",
 		Severity:  ic.Status,
-		Summary:   "iCloud Drive is actively syncing",
-		Detail:    "CloudDocs container is not caught up.",
-		Action:    "Wait for sync to complete, or pause iCloud Drive in System Settings > Apple Account > iCloud",
+	}
+
+	var evicted []string
+	for _, p := range ic.Paths {
+		if p.Error == "" && p.Evicted == 0 {
+			continue
+		}
+		if p.Error != "" {
+			evicted = append(evicted, p.Path+" (unreadable)")
+		} else {
+			evicted = append(evicted, fmt.Sprintf("%s (%d of %d files)", p.Path, p.Evicted, p.Files))
+		}
+	}
+
+	var failing []string
+	for _, c := range ic.Containers {
+		if len(c.Errors) > 0 {
+			failing = append(failing, fmt.Sprintf("%s (%s)", c.ID, strings.Join(c.Errors, ", ")))
+		}
+	}
+
+	switch {
+	case len(evicted) > 0:
+		d.Summary = "iCloud folder is not fully downloaded"
+		d.Detail = "Files evicted by Optimize Mac Storage under: " + strings.Join(evicted, "; ") + "."
+		d.Action = "Run 'brctl download <path>' or right-click the folder in Finder and choose Download Now before reading it"
+	case len(failing) > 0:
+		d.Summary = "iCloud containers report sync errors"
+		d.Detail = "Errors in: " + strings.Join(failing, "; ") + "."
+		d.Action = "Check System Settings > Apple Account > iCloud, or sign out and back in if errors persist"
+	default:
+		d.Summary = "iCloud Drive is actively syncing"
+		d.Detail = "CloudDocs container is not caught up."
+		d.Action = "Wait for sync to complete, or pause iCloud Drive in System Settings > Apple Account > iCloud"
+	}
+
+	if ic.PendingUploads > 0 || ic.PendingDownloads > 0 {
+		d.Detail += fmt.Sprintf(" Pending: %d upload(s), %d download(s).", ic.PendingUploads, ic.PendingDownloads)
 	}
 	if ic.LastSync != "" {
 		d.Detail += " Last sync: " + ic.LastSync + "."
@@ -0,0 +1,15 @@
+package health
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// sfDataless is the st_flags bit APFS sets on files whose contents have been
+// evicted to iCloud (SF_DATALESS in <sys/stat.h>).
+const sfDataless = 0x40000000
+
+func isDataless(info fs.FileInfo) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && st.Flags&sfDataless != 0
+}
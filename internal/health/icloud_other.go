@@ -0,0 +1,10 @@
+//go:build !darwin
+
+package health
+
+import "io/fs"
+
+// isDataless always reports false off macOS; only placeholder files are counted.
+func isDataless(info fs.FileInfo) bool {
+	return false
+}
@@ -1,6 +1,12 @@
 package health
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
 
 func TestParseICloud(t *testing.T) {
 	tests := []struct {
@@ -55,3 +61,141 @@ func TestParseICloud(t *testing.T) {
 		})
 	}
 }
+
+const brctlSampleMulti = `<com.apple.CloudDocs[1] foreground {client:idle server:full-sync sync:has-synced-down last-sync:2026-02-15 09:08:01.861, caught-up, token:abc}>
+--------------------------------------------------------
+<iCloud.com.apple.Pages[2] background {client:needs-sync server:full-sync last-sync:2026-02-15 08:00:00.000}>
+--------------------------------------------------------
+ o /Documents/Report.pages sz:1.2 MB st:needs-upload
+ o /Documents/Draft.pages sz:300 KB st:uploading
+ o /Documents/Old.pages sz:2.0 MB st:needs-download
+ o /Documents/failover-notes.md sz:4 KB st:idle
+<iCloud.com.example.Notes[3] background {client:idle server:full-sync caught-up, error:quota-exceeded}>
+`
+
+func TestParseBrctlStatus(t *testing.T) {
+	containers := parseBrctlStatus(brctlSampleMulti)
+	if len(containers) != 3 {
+		t.Fatalf("len(containers) = %d, want 3", len(containers))
+	}
+
+	docs := containers[0]
+	if docs.ID != "com.apple.CloudDocs" || !docs.CaughtUp || docs.State != "idle" {
+		t.Errorf("CloudDocs = %+v, want caught-up idle", docs)
+	}
+	if docs.LastSync != "2026-02-15 09:08:01" {
+		t.Errorf("CloudDocs LastSync = %q", docs.LastSync)
+	}
+
+	pages := containers[1]
+	if pages.CaughtUp || pages.State != "needs-sync" {
+		t.Errorf("Pages = %+v, want not caught-up needs-sync", pages)
+	}
+	if pages.PendingUploads != 2 || pages.PendingDownloads != 1 {
+		t.Errorf("Pages pending = %d up/%d down, want 2/1", pages.PendingUploads, pages.PendingDownloads)
+	}
+
+	notes := containers[2]
+	if len(notes.Errors) != 1 || notes.Errors[0] != "error:quota-exceeded" {
+		t.Errorf("Notes errors = %v, want [error:quota-exceeded]", notes.Errors)
+	}
+	if len(pages.Errors) != 0 {
+		t.Errorf("Pages errors = %v, want none from file names", pages.Errors)
+	}
+}
+
+func TestBrErrorTokens(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{" o /Documents/failover-notes.md sz:4 KB st:idle", nil},
+		{" o /Documents/error-log.txt sz:4 KB st:upload-failed", []string{"st:upload-failed"}},
+		{"{client:idle caught-up, error:quota-exceeded}", []string{"error:quota-exceeded"}},
+	}
+	for _, tt := range tests {
+		if got := brErrorTokens(tt.line); !slices.Equal(got, tt.want) {
+			t.Errorf("brErrorTokens(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestApplyICloudContainers(t *testing.T) {
+	ic := ICloud{Containers: parseBrctlStatus(brctlSampleMulti)}
+	applyICloudContainers(&ic, brctlSampleMulti)
+
+	if !ic.CaughtUp || ic.Syncing {
+		t.Errorf("top-level should follow CloudDocs: caughtUp=%v syncing=%v", ic.CaughtUp, ic.Syncing)
+	}
+	if ic.PendingUploads != 2 || ic.PendingDownloads != 1 {
+		t.Errorf("pending = %d/%d, want 2/1", ic.PendingUploads, ic.PendingDownloads)
+	}
+	if ic.ErrorCount != 1 {
+		t.Errorf("ErrorCount = %d, want 1", ic.ErrorCount)
+	}
+}
+
+func TestScanICloudPath(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.go", "go.mod", ".README.md.icloud"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ip := scanICloudPath(dir)
+	if ip.Files != 3 || ip.Evicted != 1 || ip.Materialized {
+		t.Errorf("scan = %+v, want 3 files, 1 evicted, not materialized", ip)
+	}
+
+	if err := os.Remove(filepath.Join(dir, ".README.md.icloud")); err != nil {
+		t.Fatal(err)
+	}
+	if ip := scanICloudPath(dir); !ip.Materialized {
+		t.Errorf("scan = %+v, want materialized", ip)
+	}
+
+	if ip := scanICloudPath(filepath.Join(dir, "missing")); ip.Error == "" || ip.Materialized {
+		t.Errorf("scan of missing path = %+v, want error", ip)
+	}
+}
+
+func TestScanICloudPath_Unreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read any directory")
+	}
+	dir := t.TempDir()
+	locked := filepath.Join(dir, "locked")
+	if err := os.Mkdir(locked, 0o000); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0o755) })
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ip := scanICloudPath(dir)
+	if ip.Error != "" || ip.Files != 1 || ip.Skipped != 1 || ip.Materialized {
+		t.Errorf("scan = %+v, want 1 file, 1 skipped, not materialized", ip)
+	}
+}
+
+func TestDiagnoseICloud(t *testing.T) {
+	if d := DiagnoseICloud(ICloud{Status: StatusGreen}); d != nil {
+		t.Error("expected nil diagnosis for green status")
+	}
+
+	d := DiagnoseICloud(ICloud{
+		Status: StatusYellow,
+		Paths:  []ICloudPath{{Path: "/Users/me/src", Files: 10, Evicted: 4}},
+	})
+	if d == nil {
+		t.Fatal("expected non-nil diagnosis")
+	}
+	if !strings.Contains(d.Detail, "/Users/me/src (4 of 10 files)") {
+		t.Errorf("Detail = %q, want evicted path", d.Detail)
+	}
+	if !strings.Contains(d.Action, "brctl download") {
+		t.Errorf("Action = %q, want brctl download hint", d.Action)
+	}
+}
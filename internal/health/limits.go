@@ -0,0 +1,350 @@
+package health
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// CheckLimits compares open files, processes and ephemeral ports in use
+// against the kernel and per-user limits. Status follows the most utilised
+// limit against the configured yellow/red percentages.
+func CheckLimits(cfg LimitsConfig) Limits {
+	l := Limits{Status: StatusGreen, UlimitProcs: -1}
+
+	l.UlimitProcs = userProcessLimit()
+
+	var err error
+	if runtime.GOOS == "linux" {
+		err = checkLimitsLinux(&l)
+	} else {
+		err = checkLimitsDarwin(&l)
+	}
+	if err != nil {
+		l.Error = err.Error()
+		return l
+	}
+
+	l.Status = limitsStatus(l, cfg)
+	return l
+}
+
+func checkLimitsDarwin(l *Limits) error {
+	out, err := exec.Command("/usr/sbin/sysctl",
+		"kern.num_files", "kern.maxfiles", "kern.maxproc", "kern.maxprocperuid",
+		"net.inet.ip.portrange.first", "net.inet.ip.portrange.last").Output()
+	if err != nil {
+		return fmt.Errorf("failed to read kernel limits: %v", err)
+	}
+	sys := parseSysctlValues(string(out))
+	l.Files = newLimitUsage(sys["kern.num_files"], sys["kern.maxfiles"])
+
+	if out, err := exec.Command("/bin/ps", "-axo", "uid=").Output(); err == nil {
+		total, mine := countProcesses(string(out), os.Getuid())
+		l.Processes = newLimitUsage(total, sys["kern.maxproc"])
+		l.UserProcesses = newLimitUsage(mine, minLimit(sys["kern.maxprocperuid"], l.UlimitProcs))
+	}
+
+	if out, err := exec.Command("/usr/sbin/netstat", "-an", "-p", "tcp").Output(); err == nil {
+		socks := parseNetstatSockets(string(out))
+		first, last := sys["net.inet.ip.portrange.first"], sys["net.inet.ip.portrange.last"]
+		l.SocketStates = socketStates(socks)
+		l.TimeWait = l.SocketStates["TIME_WAIT"]
+		l.EphemeralPorts = newLimitUsage(ephemeralSockets(socks, first, last), last-first+1)
+	}
+	return nil
+}
+
+func checkLimitsLinux(l *Limits) error {
+	data, err := os.ReadFile("/proc/sys/fs/file-nr")
+	if err != nil {
+		return fmt.Errorf("failed to read file-nr: %v", err)
+	}
+	l.Files = parseFileNr(string(data))
+
+	pidMax := readProcInt("/proc/sys/kernel/pid_max")
+	if threadsMax := readProcInt("/proc/sys/kernel/threads-max"); threadsMax > 0 && (pidMax == 0 || threadsMax < pidMax) {
+		pidMax = threadsMax
+	}
+	total, mine := countProcDirs("/proc", os.Getuid())
+	l.Processes = newLimitUsage(total, pidMax)
+	l.UserProcesses = newLimitUsage(mine, minLimit(0, l.UlimitProcs))
+
+	var socks []tcpSocket
+	for _, f := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		if data, err := os.ReadFile(f); err == nil {
+			socks = append(socks, parseProcNetTCP(string(data))...)
+		}
+	}
+	l.SocketStates = socketStates(socks)
+	l.TimeWait = l.SocketStates["TIME_WAIT"]
+	if data, err := os.ReadFile("/proc/sys/net/ipv4/ip_local_port_range"); err == nil {
+		if f := strings.Fields(string(data)); len(f) == 2 {
+			first, _ := strconv.ParseInt(f[0], 10, 64)
+			last, _ := strconv.ParseInt(f[1], 10, 64)
+			l.EphemeralPorts = newLimitUsage(ephemeralSockets(socks, first, last), last-first+1)
+		}
+	}
+	return nil
+}
+
+func newLimitUsage(used, limit int64) LimitUsage {
+	u := LimitUsage{Used: used, Limit: limit}
+	if limit > 0 {
+		u.Percent = float64(used) / float64(limit) * 100
+	}
+	return u
+}
+
+// minLimit returns the smaller positive limit; -1 and 0 mean unlimited/unknown.
+func minLimit(a, b int64) int64 {
+	switch {
+	case a <= 0:
+		return max(b, 0)
+	case b <= 0:
+		return a
+	default:
+		return min(a, b)
+	}
+}
+
+// rlimitValue converts a resource limit to int64, returning -1 for
+// RLIM_INFINITY (all ones on Linux, 2^63-1 on macOS).
+func rlimitValue(v uint64) int64 {
+	if v >= math.MaxInt64 {
+		return -1
+	}
+	return int64(v)
+}
+
+// parseSysctlValues parses "name: value" lines from `sysctl name...`.
+func parseSysctlValues(s string) map[string]int64 {
+	values := map[string]int64{}
+	for _, line := range strings.Split(s, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil {
+			values[strings.TrimSpace(key)] = n
+		}
+	}
+	return values
+}
+
+// countProcesses counts `ps -axo uid=` lines, in total and for uid.
+func countProcesses(s string, uid int) (total, mine int64) {
+	want := strconv.Itoa(uid)
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		total++
+		if line == want {
+			mine++
+		}
+	}
+	return
+}
+
+// countProcDirs counts numeric /proc entries, in total and owned by uid.
+func countProcDirs(proc string, uid int) (total, mine int64) {
+	entries, err := os.ReadDir(proc)
+	if err != nil {
+		return 0, 0
+	}
+	for _, e := range entries {
+		if _, err := strconv.Atoi(e.Name()); err != nil || !e.IsDir() {
+			continue
+		}
+		total++
+		if info, err := e.Info(); err == nil && fileOwner(info) == uid {
+			mine++
+		}
+	}
+	return
+}
+
+// parseFileNr parses /proc/sys/fs/file-nr: "allocated unused max".
+func parseFileNr(s string) LimitUsage {
+	f := strings.Fields(s)
+	if len(f) < 3 {
+		return LimitUsage{}
+	}
+	allocated, _ := strconv.ParseInt(f[0], 10, 64)
+	unused, _ := strconv.ParseInt(f[1], 10, 64)
+	maxFiles, _ := strconv.ParseInt(f[2], 10, 64)
+	return newLimitUsage(allocated-unused, maxFiles)
+}
+
+// fileOwner returns the uid owning info, or -1.
+func fileOwner(info os.FileInfo) int {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int(st.Uid)
+	}
+	return -1
+}
+
+func readProcInt(path string) int64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	return n
+}
+
+// tcpSocket is one TCP socket's state and local port.
+type tcpSocket struct {
+	state     string
+	localPort int64
+}
+
+// parseNetstatSockets parses the TCP sockets in `netstat -an -p tcp`:
+//
+//	tcp4       0      0  192.168.1.5.52341      17.57.146.20.443       ESTABLISHED
+//
+// The local port is the last dot-separated part of the local address; "*"
+// is returned as 0.
+func parseNetstatSockets(s string) []tcpSocket {
+	var socks []tcpSocket
+	for _, line := range strings.Split(s, "\n") {
+		f := strings.Fields(line)
+		if len(f) < 6 || !strings.HasPrefix(f[0], "tcp") {
+			continue
+		}
+		local := f[3]
+		port, _ := strconv.ParseInt(local[strings.LastIndex(local, ".")+1:], 10, 64)
+		socks = append(socks, tcpSocket{state: f[len(f)-1], localPort: port})
+	}
+	return socks
+}
+
+// procTCPStates maps the hex st column of /proc/net/tcp to netstat names.
+var procTCPStates = map[string]string{
+	"01": "ESTABLISHED",
+	"02": "SYN_SENT",
+	"03": "SYN_RECEIVED",
+	"04": "FIN_WAIT_1",
+	"05": "FIN_WAIT_2",
+	"06": "TIME_WAIT",
+	"07": "CLOSED",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+}
+
+// parseProcNetTCP parses the sockets in /proc/net/tcp or tcp6, whose
+// local_address column ends in the hex port ("0100007F:C350").
+func parseProcNetTCP(s string) []tcpSocket {
+	var socks []tcpSocket
+	for i, line := range strings.Split(s, "\n") {
+		f := strings.Fields(line)
+		if i == 0 || len(f) < 4 {
+			continue
+		}
+		name, ok := procTCPStates[strings.ToUpper(f[3])]
+		if !ok {
+			continue
+		}
+		_, hexPort, _ := strings.Cut(f[1], ":")
+		port, _ := strconv.ParseInt(hexPort, 16, 64)
+		socks = append(socks, tcpSocket{state: name, localPort: port})
+	}
+	return socks
+}
+
+// socketStates counts sockets by state.
+func socketStates(socks []tcpSocket) map[string]int {
+	states := map[string]int{}
+	for _, s := range socks {
+		states[s.state]++
+	}
+	return states
+}
+
+// ephemeralSockets counts sockets holding a local port in the ephemeral
+// range first..last, i.e. everything except listeners and closed sockets.
+// Inbound connections to fixed server ports do not use up the range.
+func ephemeralSockets(socks []tcpSocket, first, last int64) int64 {
+	var n int64
+	for _, s := range socks {
+		if s.state != "LISTEN" && s.state != "CLOSED" && s.localPort >= first && s.localPort <= last {
+			n++
+		}
+	}
+	return n
+}
+
+// limitsStatus rates the most utilised limit.
+func limitsStatus(l Limits, cfg LimitsConfig) Status {
+	_, worst := worstLimit(l)
+	switch {
+	case cfg.RedPercent > 0 && worst.Percent >= cfg.RedPercent:
+		return StatusRed
+	case cfg.YellowPercent > 0 && worst.Percent >= cfg.YellowPercent:
+		return StatusYellow
+	default:
+		return StatusGreen
+	}
+}
+
+// worstLimit returns the name and usage of the most utilised limit.
+func worstLimit(l Limits) (string, LimitUsage) {
+	candidates := []struct {
+		name string
+		u    LimitUsage
+	}{
+		{"files", l.Files},
+		{"processes", l.Processes},
+		{"user_processes", l.UserProcesses},
+		{"ephemeral_ports", l.EphemeralPorts},
+	}
+	name, worst := "", LimitUsage{}
+	for _, c := range candidates {
+		if name == "" || c.u.Percent > worst.Percent {
+			name, worst = c.name, c.u
+		}
+	}
+	return name, worst
+}
+
+// DiagnoseLimits returns diagnosis for the most utilised limit.
+func DiagnoseLimits(l Limits) *Diagnosis {
+	if l.Status == StatusGreen {
+		return nil
+	}
+	d := &Diagnosis{
+		Subsystem: "limits",
+		Severity:  l.Status,
+	}
+	name, u := worstLimit(l)
+	usage := fmt.Sprintf("%d of %d (%.0f%%)", u.Used, u.Limit, u.Percent)
+
+	switch name {
+	case "files":
+		d.Summary = "System-wide open file limit is nearly exhausted"
+		d.Detail = "Open files: " + usage + "."
+		d.Action = "Find the process leaking descriptors with 'lsof | awk '{print $1}' | sort | uniq -c | sort -rn | head'; raise the limit with 'sudo sysctl -w kern.maxfiles=<n>' (Linux: fs.file-max)"
+	case "processes":
+		d.Summary = "System-wide process limit is nearly exhausted"
+		d.Detail = "Processes: " + usage + "."
+		d.Action = "Reduce build parallelism or kill runaway process trees; raise the limit with 'sudo sysctl -w kern.maxproc=<n>' (Linux: kernel.pid_max)"
+	case "user_processes":
+		d.Summary = "Per-user process limit is nearly exhausted"
+		d.Detail = fmt.Sprintf("Processes for this user: %s (ulimit -u %d).", usage, l.UlimitProcs)
+		d.Action = "Reduce build parallelism; raise the limit with 'ulimit -u <n>' in the job's shell and 'sudo sysctl -w kern.maxprocperuid=<n>'"
+	default:
+		d.Summary = "Ephemeral ports are nearly exhausted"
+		d.Detail = fmt.Sprintf("TCP sockets holding an ephemeral port: %s, %d in TIME_WAIT.", usage, l.TimeWait)
+		d.Action = "Reuse connections (HTTP keep-alive, connection pools) instead of opening one per request; widen net.inet.ip.portrange.first/last (Linux: net.ipv4.ip_local_port_range)"
+	}
+	return d
+}
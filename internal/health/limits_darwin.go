@@ -0,0 +1,17 @@
+package health
+
+import "syscall"
+
+// rlimitNproc is RLIMIT_NPROC in <sys/resource.h>; package syscall does not
+// export it.
+const rlimitNproc = 7
+
+// userProcessLimit returns the soft per-user process limit (ulimit -u), or
+// -1 if it is unlimited or unknown.
+func userProcessLimit() int64 {
+	var rl syscall.Rlimit
+	if err := syscall.Getrlimit(rlimitNproc, &rl); err != nil {
+		return -1
+	}
+	return rlimitValue(rl.Cur)
+}
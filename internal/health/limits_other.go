@@ -0,0 +1,8 @@
+//go:build !darwin && !linux
+
+package health
+
+// userProcessLimit reports the per-user process limit as unknown.
+func userProcessLimit() int64 {
+	return -1
+}
@@ -0,0 +1,158 @@
+package health
+
+import (
+	"math"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseSysctlValues(t *testing.T) {
+	input := `kern.num_files: 8123
+kern.maxfiles: 122880
+kern.maxproc: 8000
+kern.maxprocperuid: 5333
+net.inet.ip.portrange.first: 49152
+net.inet.ip.portrange.last: 65535
+`
+	v := parseSysctlValues(input)
+	if v["kern.num_files"] != 8123 || v["kern.maxfiles"] != 122880 || v["kern.maxprocperuid"] != 5333 {
+		t.Errorf("parseSysctlValues() = %v", v)
+	}
+	if got := v["net.inet.ip.portrange.last"] - v["net.inet.ip.portrange.first"] + 1; got != 16384 {
+		t.Errorf("port range = %d, want 16384", got)
+	}
+}
+
+func TestRlimitValue(t *testing.T) {
+	tests := []struct {
+		v    uint64
+		want int64
+	}{
+		{2666, 2666},
+		{math.MaxInt64, -1},  // RLIM_INFINITY on macOS
+		{math.MaxUint64, -1}, // RLIM_INFINITY on Linux
+	}
+	for _, tt := range tests {
+		if got := rlimitValue(tt.v); got != tt.want {
+			t.Errorf("rlimitValue(%d) = %d, want %d", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestUserProcessLimit(t *testing.T) {
+	if runtime.GOOS != "darwin" && runtime.GOOS != "linux" {
+		t.Skip("per-user process limit is only read on macOS and Linux")
+	}
+	// Read straight from getrlimit, so it works whatever /bin/sh is.
+	if got := userProcessLimit(); got == 0 || got < -1 {
+		t.Errorf("userProcessLimit() = %d", got)
+	}
+}
+
+func TestMinLimit(t *testing.T) {
+	tests := []struct{ a, b, want int64 }{
+		{5333, 2666, 2666},
+		{5333, -1, 5333},
+		{0, 2666, 2666},
+		{0, -1, 0},
+	}
+	for _, tt := range tests {
+		if got := minLimit(tt.a, tt.b); got != tt.want {
+			t.Errorf("minLimit(%d, %d) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCountProcesses(t *testing.T) {
+	total, mine := countProcesses("    0\n    0\n  501\n  501\n  501\n   88\n", 501)
+	if total != 6 || mine != 3 {
+		t.Errorf("countProcesses() = %d, %d, want 6, 3", total, mine)
+	}
+}
+
+func TestParseNetstatSockets(t *testing.T) {
+	input := `Active Internet connections (including servers)
+Proto Recv-Q Send-Q  Local Address          Foreign Address        (state)
+tcp4       0      0  192.168.1.5.52341      17.57.146.20.443       ESTABLISHED
+tcp4       0      0  192.168.1.5.52340      17.57.146.20.443       TIME_WAIT
+tcp4       0      0  192.168.1.5.52339      140.82.112.4.443       TIME_WAIT
+tcp6       0      0  *.22                   *.*                    LISTEN
+tcp4       0      0  127.0.0.1.8080         127.0.0.1.52000        CLOSE_WAIT
+tcp4       0      0  192.168.1.5.22         192.168.1.9.61000      ESTABLISHED
+`
+	socks := parseNetstatSockets(input)
+	states := socketStates(socks)
+	if states["TIME_WAIT"] != 2 || states["ESTABLISHED"] != 2 || states["LISTEN"] != 1 {
+		t.Errorf("socketStates() = %v", states)
+	}
+	// The inbound ssh and local 8080 connections hold server ports, not
+	// ephemeral ones.
+	if got := ephemeralSockets(socks, 49152, 65535); got != 3 {
+		t.Errorf("ephemeralSockets() = %d, want 3", got)
+	}
+}
+
+func TestParseProcNetTCP(t *testing.T) {
+	input := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 12345 1
+   1: 0100007F:C350 0100007F:1F90 06 00000000:00000000 03:00000A2B 00000000     0        0 0 3
+   2: 0500A8C0:CC35 14923911:01BB 01 00000000:00000000 02:0000012C 00000000  1000        0 23456 2
+`
+	socks := parseProcNetTCP(input)
+	states := socketStates(socks)
+	if states["LISTEN"] != 1 || states["TIME_WAIT"] != 1 || states["ESTABLISHED"] != 1 {
+		t.Errorf("parseProcNetTCP() = %v", states)
+	}
+	if got := ephemeralSockets(socks, 32768, 60999); got != 2 || socks[1].localPort != 0xC350 {
+		t.Errorf("ephemeralSockets() = %d, sockets = %v", got, socks)
+	}
+}
+
+func TestParseFileNr(t *testing.T) {
+	u := parseFileNr("9344\t0\t9223372036854775807\n")
+	if u.Used != 9344 || u.Limit != 9223372036854775807 {
+		t.Errorf("parseFileNr() = %+v", u)
+	}
+	u = parseFileNr("1200 200 2000")
+	if u.Used != 1000 || u.Percent != 50 {
+		t.Errorf("parseFileNr() = %+v, want 1000 used, 50%%", u)
+	}
+}
+
+func TestLimitsStatus(t *testing.T) {
+	cfg := LimitsConfig{YellowPercent: 70, RedPercent: 90}
+	tests := []struct {
+		name string
+		l    Limits
+		want Status
+	}{
+		{"all low", Limits{Files: newLimitUsage(10, 100), EphemeralPorts: newLimitUsage(1, 100)}, StatusGreen},
+		{"ports yellow", Limits{Files: newLimitUsage(10, 100), EphemeralPorts: newLimitUsage(75, 100)}, StatusYellow},
+		{"user procs red", Limits{UserProcesses: newLimitUsage(2600, 2666)}, StatusRed},
+		{"unknown limit ignored", Limits{Processes: newLimitUsage(500, 0)}, StatusGreen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := limitsStatus(tt.l, cfg); got != tt.want {
+				t.Errorf("limitsStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiagnoseLimits(t *testing.T) {
+	if DiagnoseLimits(Limits{Status: StatusGreen}) != nil {
+		t.Error("expected nil for green")
+	}
+	l := Limits{
+		Status:         StatusRed,
+		Files:          newLimitUsage(10, 100),
+		EphemeralPorts: newLimitUsage(15000, 16384),
+		TimeWait:       14000,
+	}
+	d := DiagnoseLimits(l)
+	if d == nil || !strings.Contains(d.Summary, "Ephemeral ports") || !strings.Contains(d.Detail, "14000 in TIME_WAIT") {
+		t.Errorf("DiagnoseLimits() = %+v", d)
+	}
+}
@@ -1,15 +1,39 @@
 package health
 
 import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
 	"os/exec"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
-// CheckNetwork collects network connectivity state.
-func CheckNetwork() Network {
+// hostsPath is the static host table checked for overrides.
+var hostsPath = "/etc/hosts"
+
+// CheckNetwork collects network connectivity state, proxy settings, DNS
+// resolver configuration and /etc/hosts overrides. When ProbeTimeout is set
+// and the network is reachable, the PAC file is fetched and every nameserver
+// is sent a DNS query, concurrently with the Wi-Fi scan. An unreachable
+// network is red; an unreachable PAC file or a nameserver that does not
+// answer is yellow.
+func CheckNetwork(cfg NetworkConfig) Network {
 	n := Network{Status: StatusGreen}
+	if data, err := os.ReadFile(hostsPath); err == nil {
+		n.HostsOverrides = parseHosts(string(data))
+	}
 
 	// Check network interface info via scutil
 	out, err := exec.Command("/usr/sbin/scutil", "--nwi").Output()
@@ -20,14 +44,39 @@ func CheckNetwork() Network {
 	}
 
 	n.Reachable, n.Interface, n.IP = parseScutil(string(out))
+	if out, err := exec.Command("/usr/sbin/scutil", "--proxy").Output(); err == nil {
+		n.Proxy = parseScutilProxy(string(out))
+	}
+	if out, err := exec.Command("/usr/sbin/scutil", "--dns").Output(); err == nil {
+		n.DNS = parseScutilDNS(string(out))
+	}
 
-	if !n.Reachable {
-		n.Status = StatusRed
+	var probes sync.WaitGroup
+	if timeout := time.Duration(cfg.ProbeTimeout); timeout > 0 && n.Reachable {
+		probes.Add(1)
+		go func() {
+			defer probes.Done()
+			probeNetworkConfig(&n.Proxy, &n.DNS, timeout)
+		}()
 	}
+	n.WiFi = checkWiFi()
+	probes.Wait()
 
+	n.Status = networkStatus(n)
 	return n
 }
 
+func networkStatus(n Network) Status {
+	switch {
+	case !n.Reachable:
+		return StatusRed
+	case n.Proxy.PACError != "", len(n.DNS.Unresponsive) > 0:
+		return StatusYellow
+	default:
+		return StatusGreen
+	}
+}
+
 var (
 	nwiInterfaceRe = regexp.MustCompile(`(\w+)\s*:\s*flags\s*:\s*\S+\s*\(.*IPv4`)
 	nwiAddressRe   = regexp.MustCompile(`address\s*:\s*([\d.]+)`)
@@ -53,16 +102,503 @@ func parseScutil(s string) (reachable bool, iface, ip string) {
 	return
 }
 
+// checkWiFi collects Wi-Fi interface details from cached system_profiler
+// output, preferring JSON and falling back to text. Wi-Fi is informational
+// and never affects network status.
+func checkWiFi() WiFi {
+	var w WiFi
+	if out, err := cachedSystemProfiler("SPAirPortDataType", true); err == nil && parseWiFiJSON(out, &w) == nil {
+		w.Parser = ParserJSON
+		return w
+	}
+	if out, err := cachedSystemProfiler("SPAirPortDataType", false); err == nil {
+		parseWiFiText(string(out), &w)
+		if w.Interface != "" {
+			w.Parser = ParserText
+		}
+	}
+	return w
+}
+
+type spAirPortNetwork struct {
+	Name        string          `json:"_name"`
+	PHYMode     string          `json:"spairport_network_phymode"`
+	Channel     json.RawMessage `json:"spairport_network_channel"`
+	Rate        json.RawMessage `json:"spairport_network_rate"`
+	SignalNoise string          `json:"spairport_signal_noise"`
+}
+
+type spAirPortJSON struct {
+	SPAirPortDataType []struct {
+		Interfaces []struct {
+			Name    string            `json:"_name"`
+			Status  string            `json:"spairport_status_information"`
+			Current *spAirPortNetwork `json:"spairport_current_network_information"`
+		} `json:"spairport_airport_interfaces"`
+	} `json:"SPAirPortDataType"`
+}
+
+var (
+	wifiSignalNoiseRe = regexp.MustCompile(`(-?\d+)\s*dBm\s*/\s*(-?\d+)\s*dBm`)
+	wifiIfaceRe       = regexp.MustCompile(`^(en\d+):$`)
+)
+
+// parseWiFiJSON fills w from `system_profiler SPAirPortDataType -json`,
+// picking the first en* interface (AWDL and low-latency interfaces are skipped).
+func parseWiFiJSON(data []byte, w *WiFi) error {
+	var sp spAirPortJSON
+	if err := json.Unmarshal(data, &sp); err != nil {
+		return err
+	}
+	for _, entry := range sp.SPAirPortDataType {
+		for _, iface := range entry.Interfaces {
+			if !strings.HasPrefix(iface.Name, "en") {
+				continue
+			}
+			w.Interface = iface.Name
+			w.Connected = strings.HasSuffix(iface.Status, "_connected")
+			if c := iface.Current; c != nil {
+				w.SSID = c.Name
+				w.PHYMode = c.PHYMode
+				w.Channel = strings.Trim(string(c.Channel), `"`)
+				w.TransmitRateMbps, _ = strconv.Atoi(strings.Trim(string(c.Rate), `"`))
+				w.SignalDBm, w.NoiseDBm = parseSignalNoise(c.SignalNoise)
+			}
+			return nil
+		}
+	}
+	return errors.New("no Wi-Fi interface")
+}
+
+// parseWiFiText fills w from the text output of `system_profiler SPAirPortDataType`:
+//
+//	Interfaces:
+//	  en0:
+//	    Status: Connected
+//	    Current Network Information:
+//	      HomeNet:
+//	        PHY Mode: 802.11ax
+//	        Channel: 149 (5GHz, 80MHz)
+//	        Signal / Noise: -55 dBm / -95 dBm
+//	        Transmit Rate: 1200
+//	    Other Local Wi-Fi Networks:
+func parseWiFiText(s string, w *WiFi) {
+	inInterfaces, inCurrent := false, false
+	ifaceIndent, currentIndent := 0, 0
+
+	for _, line := range strings.Split(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+
+		if trimmed == "Interfaces:" {
+			inInterfaces = true
+			continue
+		}
+		if !inInterfaces {
+			continue
+		}
+
+		if w.Interface == "" {
+			if m := wifiIfaceRe.FindStringSubmatch(trimmed); len(m) >= 2 {
+				w.Interface = m[1]
+				ifaceIndent = indent
+			}
+			continue
+		}
+		// A sibling entry (e.g. awdl0:) ends the first Wi-Fi interface.
+		if indent <= ifaceIndent {
+			return
+		}
+
+		if trimmed == "Current Network Information:" {
+			inCurrent = true
+			currentIndent = indent
+			continue
+		}
+		if inCurrent && indent <= currentIndent {
+			inCurrent = false
+		}
+
+		key, value, _ := strings.Cut(trimmed, ":")
+		value = strings.TrimSpace(value)
+		switch {
+		case inCurrent && value == "" && w.SSID == "":
+			w.SSID = key
+		case key == "Status" && !inCurrent:
+			w.Connected = strings.EqualFold(value, "connected")
+		case inCurrent && key == "PHY Mode":
+			w.PHYMode = value
+		case inCurrent && key == "Channel":
+			w.Channel = value
+		case inCurrent && key == "Transmit Rate":
+			w.TransmitRateMbps, _ = strconv.Atoi(value)
+		case inCurrent && key == "Signal / Noise":
+			w.SignalDBm, w.NoiseDBm = parseSignalNoise(value)
+		}
+	}
+}
+
+// parseSignalNoise parses "-55 dBm / -95 dBm".
+func parseSignalNoise(s string) (signal, noise int) {
+	if m := wifiSignalNoiseRe.FindStringSubmatch(s); len(m) >= 3 {
+		signal, _ = strconv.Atoi(m[1])
+		noise, _ = strconv.Atoi(m[2])
+	}
+	return
+}
+
+// parseScutilProxy parses `scutil --proxy`:
+//
+//	<dictionary> {
+//	  ExceptionsList : <array> {
+//	    0 : *.local
+//	  }
+//	  HTTPEnable : 1
+//	  HTTPPort : 8080
+//	  HTTPProxy : proxy.corp.example
+//	  ProxyAutoConfigEnable : 1
+//	  ProxyAutoConfigURLString : http://wpad.corp.example/wpad.dat
+//	}
+func parseScutilProxy(s string) ProxySettings {
+	values := map[string]string{}
+	var p ProxySettings
+	inExceptions := false
+	for _, line := range strings.Split(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "}" {
+			inExceptions = false
+			continue
+		}
+		key, value, ok := strings.Cut(trimmed, " : ")
+		if !ok {
+			continue
+		}
+		if inExceptions {
+			p.Exceptions = append(p.Exceptions, value)
+			continue
+		}
+		if key == "ExceptionsList" {
+			inExceptions = true
+			continue
+		}
+		values[key] = value
+	}
+
+	proxy := func(prefix string) string {
+		if values[prefix+"Enable"] != "1" || values[prefix+"Proxy"] == "" {
+			return ""
+		}
+		if port := values[prefix+"Port"]; port != "" {
+			return net.JoinHostPort(values[prefix+"Proxy"], port)
+		}
+		return values[prefix+"Proxy"]
+	}
+	p.HTTP = proxy("HTTP")
+	p.HTTPS = proxy("HTTPS")
+	p.SOCKS = proxy("SOCKS")
+	if values["ProxyAutoConfigEnable"] == "1" {
+		p.PACURL = values["ProxyAutoConfigURLString"]
+	}
+	p.AutoDiscovery = values["ProxyAutoDiscoveryEnable"] == "1"
+	return p
+}
+
+var (
+	dnsResolverRe = regexp.MustCompile(`^resolver #\d+`)
+	dnsIndexedRe  = regexp.MustCompile(`^(nameserver|search domain)\[\d+\]$`)
+)
+
+// parseScutilDNS parses `scutil --dns`. Resolvers without nameservers (mDNS)
+// are skipped; those listed after "DNS configuration (for scoped queries)"
+// are marked scoped. The default resolver is the first unscoped one without
+// a domain.
+//
+//	resolver #1
+//	  search domain[0] : corp.example
+//	  nameserver[0] : 10.0.0.2
+//	  if_index : 15 (en0)
+//	  reach    : 0x00000002 (Reachable)
+func parseScutilDNS(s string) DNSConfig {
+	var cfg DNSConfig
+	var cur *DNSResolver
+	scoped := false
+	flush := func() {
+		if cur != nil && len(cur.Nameservers) > 0 {
+			cfg.Resolvers = append(cfg.Resolvers, *cur)
+		}
+		cur = nil
+	}
+
+	for _, line := range strings.Split(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "DNS configuration"):
+			flush()
+			scoped = strings.Contains(trimmed, "scoped")
+			continue
+		case dnsResolverRe.MatchString(trimmed):
+			flush()
+			cur = &DNSResolver{Scoped: scoped}
+			continue
+		case cur == nil:
+			continue
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if m := dnsIndexedRe.FindStringSubmatch(key); len(m) >= 2 {
+			if m[1] == "nameserver" {
+				cur.Nameservers = append(cur.Nameservers, value)
+			} else {
+				cur.SearchDomains = append(cur.SearchDomains, value)
+			}
+			continue
+		}
+		switch key {
+		case "domain":
+			cur.Domain = value
+		case "if_index":
+			if _, name, ok := strings.Cut(value, "("); ok {
+				cur.Interface = strings.TrimSuffix(name, ")")
+			}
+		case "reach":
+			cur.Reachable = strings.Contains(value, "(Reachable")
+		case "order":
+			cur.Order, _ = strconv.Atoi(value)
+		}
+	}
+	flush()
+
+	for _, r := range cfg.Resolvers {
+		if !r.Scoped && r.Domain == "" {
+			cfg.Nameservers = r.Nameservers
+			cfg.SearchDomains = r.SearchDomains
+			break
+		}
+	}
+	return cfg
+}
+
+// defaultHostnames are the names macOS and Linux distributions ship in
+// /etc/hosts; they are not reported as overrides.
+var defaultHostnames = map[string]bool{
+	"localhost":             true,
+	"localhost.localdomain": true,
+	"broadcasthost":         true,
+	"ip6-localhost":         true,
+	"ip6-loopback":          true,
+	"ip6-localnet":          true,
+	"ip6-mcastprefix":       true,
+	"ip6-allnodes":          true,
+	"ip6-allrouters":        true,
+}
+
+// parseHosts returns /etc/hosts entries other than the stock ones.
+func parseHosts(s string) []HostsEntry {
+	var entries []HostsEntry
+	for _, line := range strings.Split(s, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		f := strings.Fields(line)
+		// 127.0.1.1 is the Debian/Ubuntu entry for the machine's own name.
+		if len(f) < 2 || f[0] == "127.0.1.1" {
+			continue
+		}
+		var names []string
+		for _, name := range f[1:] {
+			if !defaultHostnames[name] {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			entries = append(entries, HostsEntry{IP: f[0], Hostnames: names})
+		}
+	}
+	return entries
+}
+
+// probeNetworkConfig fetches the PAC file and queries each unscoped
+// nameserver in parallel, recording failures on p and d.
+func probeNetworkConfig(p *ProxySettings, d *DNSConfig, timeout time.Duration) {
+	var servers []string
+	for _, r := range d.Resolvers {
+		for _, ns := range r.Nameservers {
+			if !r.Scoped && !slices.Contains(servers, ns) {
+				servers = append(servers, ns)
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	if p.PACURL != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := probePAC(p.PACURL, timeout); err != nil {
+				p.PACError = err.Error()
+			}
+		}()
+	}
+	for _, ns := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := probeDNS(net.JoinHostPort(ns, "53"), timeout); err != nil {
+				mu.Lock()
+				d.Unresponsive = append(d.Unresponsive, ns)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	// Keep resolver order regardless of which probe failed first.
+	slices.SortFunc(d.Unresponsive, func(a, b string) int {
+		return slices.Index(servers, a) - slices.Index(servers, b)
+	})
+}
+
+// probePAC fetches the proxy auto-config file directly (not through a proxy).
+func probePAC(pacURL string, timeout time.Duration) error {
+	u, err := url.Parse(pacURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "file" {
+		_, err := os.Stat(u.Path)
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	client := &http.Client{Transport: &http.Transport{Proxy: nil, DisableKeepAlives: true}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pacURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", pacURL, resp.Status)
+	}
+	return nil
+}
+
+var errBadDNSResponse = errors.New("invalid DNS response")
+
+// probeDNS sends a recursive NS query for the root zone to addr over UDP
+// and succeeds on any well-formed reply, including REFUSED: the point is
+// whether the server answers at all. A single lost packet should not flag a
+// server, so the query is sent again if no reply arrives in the first half
+// of timeout.
+func probeDNS(addr string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("udp", addr, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(timeout)
+
+	id := uint16(time.Now().UnixNano())
+	query := make([]byte, 17)
+	binary.BigEndian.PutUint16(query[0:], id)
+	binary.BigEndian.PutUint16(query[2:], 0x0100) // RD
+	binary.BigEndian.PutUint16(query[4:], 1)      // QDCOUNT
+	// query[12] = 0: root name
+	binary.BigEndian.PutUint16(query[13:], 2) // QTYPE NS
+	binary.BigEndian.PutUint16(query[15:], 1) // QCLASS IN
+
+	resp := make([]byte, 512)
+	for attempt := range 2 {
+		if _, err := conn.Write(query); err != nil {
+			return err
+		}
+		wait := deadline
+		if attempt == 0 {
+			wait = time.Now().Add(timeout / 2)
+		}
+		if err := conn.SetReadDeadline(wait); err != nil {
+			return err
+		}
+		n, err := conn.Read(resp)
+		if ne, ok := err.(net.Error); ok && ne.Timeout() && attempt == 0 {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if n < 12 || binary.BigEndian.Uint16(resp[0:]) != id || resp[2]&0x80 == 0 {
+			return errBadDNSResponse
+		}
+		return nil
+	}
+	return nil
+}
+
 // DiagnoseNetwork returns diagnosis for network issues.
 func DiagnoseNetwork(n Network) *Diagnosis {
 	if n.Status == StatusGreen {
 		return nil
 	}
-	return &Diagnosis{
+	if !n.Reachable {
+		return &Diagnosis{
+			Subsystem: "network",
+			Severity:  StatusRed,
+			Summary:   "Network is unreachable",
+			Detail:    "No active network interface with reachability detected.",
+			Action:    "Check Wi-Fi or Ethernet connection. Run 'netwhiz diagnose' for detailed network diagnostics",
+		}
+	}
+
+	d := &Diagnosis{
 		Subsystem: "network",
-		Severity:  StatusRed,
-		Summary:   "Network is unreachable",
-		Detail:    "No active network interface with reachability detected.",
-		Action:    "Check Wi-Fi or Ethernet connection. Run 'netwhiz diagnose' for detailed network diagnostics",
+		Severity:  n.Status,
+	}
+	var details []string
+	if n.Proxy.PACError != "" {
+		d.Summary = "Proxy auto-config file is unreachable"
+		details = append(details, fmt.Sprintf("PAC file %s could not be fetched (%s); connections that depend on it fail or fall back to direct.", n.Proxy.PACURL, n.Proxy.PACError))
+		d.Action = "Connect to the network or VPN that serves the PAC file, or turn off 'Automatic proxy configuration' in System Settings > Network > Details > Proxies"
+	}
+	if len(n.DNS.Unresponsive) > 0 {
+		var stale []string
+		for _, r := range n.DNS.Resolvers {
+			if r.Domain != "" && !r.Scoped && slices.ContainsFunc(r.Nameservers, func(ns string) bool { return slices.Contains(n.DNS.Unresponsive, ns) }) {
+				stale = append(stale, r.Domain)
+			}
+		}
+		if d.Summary == "" {
+			d.Summary = "DNS servers are not answering"
+		}
+		detail := fmt.Sprintf("Nameserver(s) %s did not answer a DNS query.", strings.Join(n.DNS.Unresponsive, ", "))
+		if len(stale) > 0 {
+			detail += fmt.Sprintf(" They serve the %s domain(s), which usually means a VPN left its resolver behind after disconnecting.", strings.Join(stale, ", "))
+		}
+		details = append(details, detail)
+		if d.Action == "" {
+			if len(stale) > 0 {
+				d.Action = "Reconnect or fully quit the VPN client; if the resolver remains, remove its files from /etc/resolver and run 'sudo killall -HUP mDNSResponder'"
+			} else {
+				d.Action = "Check the DNS servers in System Settings > Network > Details > DNS, or renew the DHCP lease"
+			}
+		}
+	}
+	if len(n.HostsOverrides) > 0 {
+		var names []string
+		for _, h := range n.HostsOverrides {
+			names = append(names, h.Hostnames...)
+		}
+		details = append(details, fmt.Sprintf("/etc/hosts also overrides: %s.", strings.Join(names, ", ")))
 	}
+	d.Detail = strings.Join(details, " ")
+	return d
 }
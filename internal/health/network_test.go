@@ -1,6 +1,16 @@
 package health
 
-import "testing"
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestParseScutil(t *testing.T) {
 	tests := []struct {
@@ -53,9 +63,328 @@ IPv4 network interface information
 }
 
 func TestCheckNetwork_Integration(t *testing.T) {
-	n := CheckNetwork()
+	n := CheckNetwork(DefaultConfig().Network)
 	// On a dev machine, network should generally be up
-	if n.Status != StatusGreen && n.Status != StatusRed {
+	if n.Status != StatusGreen && n.Status != StatusYellow && n.Status != StatusRed {
 		t.Errorf("unexpected status: %s", n.Status)
 	}
 }
+
+func TestParseWiFiJSON(t *testing.T) {
+	input := `{"SPAirPortDataType": [{
+		"spairport_airport_interfaces": [
+			{
+				"_name": "en0",
+				"spairport_status_information": "spairport_status_connected",
+				"spairport_current_network_information": {
+					"_name": "HomeNet",
+					"spairport_network_channel": "149 (5GHz, 80MHz)",
+					"spairport_network_phymode": "802.11ax",
+					"spairport_network_rate": 1200,
+					"spairport_signal_noise": "-55 dBm / -95 dBm"
+				}
+			},
+			{"_name": "awdl0", "spairport_status_information": "spairport_status_off"}
+		]
+	}]}`
+
+	var w WiFi
+	if err := parseWiFiJSON([]byte(input), &w); err != nil {
+		t.Fatalf("parseWiFiJSON() error = %v", err)
+	}
+	want := WiFi{
+		Interface: "en0", Connected: true, SSID: "HomeNet", PHYMode: "802.11ax",
+		Channel: "149 (5GHz, 80MHz)", SignalDBm: -55, NoiseDBm: -95, TransmitRateMbps: 1200,
+	}
+	if w != want {
+		t.Errorf("parseWiFiJSON() = %+v, want %+v", w, want)
+	}
+
+	if err := parseWiFiJSON([]byte(`{"SPAirPortDataType": [{}]}`), &WiFi{}); err == nil {
+		t.Error("expected error when no interfaces are present")
+	}
+}
+
+func TestParseWiFiText(t *testing.T) {
+	input := `Wi-Fi:
+
+      Software Versions:
+          CoreWLAN: 16.0 (1657)
+      Interfaces:
+        en0:
+          Card Type: Wi-Fi  (0x14E4, 0x4387)
+          Status: Connected
+          Current Network Information:
+            HomeNet:
+              PHY Mode: 802.11ax
+              Channel: 149 (5GHz, 80MHz)
+              Signal / Noise: -61 dBm / -92 dBm
+              Transmit Rate: 864
+          Other Local Wi-Fi Networks:
+            Neighbour:
+              PHY Mode: 802.11n
+              Signal / Noise: -80 dBm / -92 dBm
+        awdl0:
+          Status: Off
+`
+	var w WiFi
+	parseWiFiText(input, &w)
+	want := WiFi{
+		Interface: "en0", Connected: true, SSID: "HomeNet", PHYMode: "802.11ax",
+		Channel: "149 (5GHz, 80MHz)", SignalDBm: -61, NoiseDBm: -92, TransmitRateMbps: 864,
+	}
+	if w != want {
+		t.Errorf("parseWiFiText() = %+v, want %+v", w, want)
+	}
+}
+
+func TestParseScutilProxy(t *testing.T) {
+	input := `<dictionary> {
+  ExceptionsList : <array> {
+    0 : *.local
+    1 : 169.254/16
+  }
+  FTPPassive : 1
+  HTTPEnable : 1
+  HTTPPort : 8080
+  HTTPProxy : proxy.corp.example
+  HTTPSEnable : 1
+  HTTPSPort : 8443
+  HTTPSProxy : proxy.corp.example
+  ProxyAutoConfigEnable : 1
+  ProxyAutoConfigURLString : http://wpad.corp.example/wpad.dat
+  ProxyAutoDiscoveryEnable : 0
+  SOCKSEnable : 0
+  SOCKSPort : 1080
+  SOCKSProxy : socks.corp.example
+}`
+	p := parseScutilProxy(input)
+	if p.HTTP != "proxy.corp.example:8080" || p.HTTPS != "proxy.corp.example:8443" || p.SOCKS != "" {
+		t.Errorf("proxies = %+v", p)
+	}
+	if p.PACURL != "http://wpad.corp.example/wpad.dat" || p.AutoDiscovery {
+		t.Errorf("auto config = %+v", p)
+	}
+	if !slices.Equal(p.Exceptions, []string{"*.local", "169.254/16"}) {
+		t.Errorf("exceptions = %q", p.Exceptions)
+	}
+
+	none := parseScutilProxy("<dictionary> {\n  ExceptionsList : <array> {\n    0 : *.local\n  }\n  FTPPassive : 1\n}")
+	if none.HTTP != "" || none.PACURL != "" || len(none.Exceptions) != 1 {
+		t.Errorf("no proxies = %+v", none)
+	}
+}
+
+func TestParseScutilDNS(t *testing.T) {
+	input := `DNS configuration
+
+resolver #1
+  search domain[0] : corp.example
+  search domain[1] : example.com
+  nameserver[0] : 192.168.1.1
+  nameserver[1] : 2001:db8::1
+  if_index : 15 (en0)
+  flags    : Request A records, Request AAAA records
+  reach    : 0x00020002 (Reachable,Directly Reachable Address)
+
+resolver #2
+  domain   : vpn.corp.example
+  nameserver[0] : 10.8.0.1
+  flags    : Request A records
+  reach    : 0x00000000 (Not Reachable)
+  order    : 102400
+
+resolver #3
+  domain   : local
+  options  : mdns
+  timeout  : 5
+  flags    : Request A records, Request AAAA records
+  reach    : 0x00000000 (Not Reachable)
+  order    : 300000
+
+DNS configuration (for scoped queries)
+
+resolver #1
+  search domain[0] : corp.example
+  nameserver[0] : 192.168.1.1
+  if_index : 15 (en0)
+  flags    : Scoped, Request A records
+  reach    : 0x00020002 (Reachable,Directly Reachable Address)
+`
+	d := parseScutilDNS(input)
+	if len(d.Resolvers) != 3 {
+		t.Fatalf("got %d resolvers, want 3: %+v", len(d.Resolvers), d.Resolvers)
+	}
+	if !slices.Equal(d.Nameservers, []string{"192.168.1.1", "2001:db8::1"}) ||
+		!slices.Equal(d.SearchDomains, []string{"corp.example", "example.com"}) {
+		t.Errorf("default resolver = %q / %q", d.Nameservers, d.SearchDomains)
+	}
+	if r := d.Resolvers[0]; r.Interface != "en0" || !r.Reachable || r.Scoped {
+		t.Errorf("resolver 1 = %+v", r)
+	}
+	if r := d.Resolvers[1]; r.Domain != "vpn.corp.example" || r.Reachable || r.Order != 102400 {
+		t.Errorf("resolver 2 = %+v", r)
+	}
+	if r := d.Resolvers[2]; !r.Scoped || r.Domain != "" {
+		t.Errorf("scoped resolver = %+v", r)
+	}
+}
+
+func TestParseHosts(t *testing.T) {
+	input := `##
+# Host Database
+##
+127.0.0.1	localhost
+255.255.255.255	broadcasthost
+::1             localhost
+127.0.1.1	builder01
+10.1.2.3	registry.corp.example   artifacts.corp.example # pinned for the outage
+127.0.0.1	localhost myapp.test
+`
+	got := parseHosts(input)
+	if len(got) != 2 {
+		t.Fatalf("parseHosts() = %+v", got)
+	}
+	if got[0].IP != "10.1.2.3" || !slices.Equal(got[0].Hostnames, []string{"registry.corp.example", "artifacts.corp.example"}) {
+		t.Errorf("entry 0 = %+v", got[0])
+	}
+	if got[1].IP != "127.0.0.1" || !slices.Equal(got[1].Hostnames, []string{"myapp.test"}) {
+		t.Errorf("entry 1 = %+v", got[1])
+	}
+}
+
+func TestProbePAC(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/wpad.dat" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("function FindProxyForURL(url, host) { return \"DIRECT\"; }"))
+	}))
+	defer srv.Close()
+
+	if err := probePAC(srv.URL+"/wpad.dat", time.Second); err != nil {
+		t.Errorf("probePAC() error = %v", err)
+	}
+	if err := probePAC(srv.URL+"/missing.pac", time.Second); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("missing PAC error = %v", err)
+	}
+
+	pac := filepath.Join(t.TempDir(), "proxy.pac")
+	os.WriteFile(pac, []byte("function FindProxyForURL() {}"), 0o644)
+	if err := probePAC("file://"+pac, time.Second); err != nil {
+		t.Errorf("file PAC error = %v", err)
+	}
+	if err := probePAC("file:///nonexistent/proxy.pac", time.Second); err == nil {
+		t.Error("expected error for missing file PAC")
+	}
+}
+
+func TestProbeDNS(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on UDP: %v", err)
+	}
+	defer conn.Close()
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			// Echo the query back as a REFUSED response.
+			resp := append([]byte(nil), buf[:n]...)
+			resp[2] |= 0x80
+			resp[3] = 5
+			conn.WriteTo(resp, addr)
+		}
+	}()
+
+	if err := probeDNS(conn.LocalAddr().String(), time.Second); err != nil {
+		t.Errorf("probeDNS() error = %v", err)
+	}
+
+	// A server that drops the first query answers the retry.
+	lossy, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lossy.Close()
+	go func() {
+		buf := make([]byte, 512)
+		for i := 0; ; i++ {
+			n, addr, err := lossy.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if i == 0 {
+				continue
+			}
+			resp := append([]byte(nil), buf[:n]...)
+			resp[2] |= 0x80
+			lossy.WriteTo(resp, addr)
+		}
+	}()
+	if err := probeDNS(lossy.LocalAddr().String(), 400*time.Millisecond); err != nil {
+		t.Errorf("probeDNS() with one lost query error = %v", err)
+	}
+
+	// A server that never answers times out.
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	if err := probeDNS(silent.LocalAddr().String(), 100*time.Millisecond); err == nil {
+		t.Error("expected timeout from silent server")
+	}
+}
+
+func TestNetworkStatus(t *testing.T) {
+	if s := networkStatus(Network{Reachable: true}); s != StatusGreen {
+		t.Errorf("healthy = %s", s)
+	}
+	if s := networkStatus(Network{Reachable: true, Proxy: ProxySettings{PACURL: "http://wpad/wpad.dat", PACError: "timeout"}}); s != StatusYellow {
+		t.Errorf("PAC unreachable = %s", s)
+	}
+	if s := networkStatus(Network{Reachable: true, DNS: DNSConfig{Unresponsive: []string{"10.8.0.1"}}}); s != StatusYellow {
+		t.Errorf("DNS unresponsive = %s", s)
+	}
+	if s := networkStatus(Network{DNS: DNSConfig{Unresponsive: []string{"10.8.0.1"}}}); s != StatusRed {
+		t.Errorf("unreachable = %s", s)
+	}
+}
+
+func TestDiagnoseNetwork(t *testing.T) {
+	if d := DiagnoseNetwork(Network{Status: StatusGreen, Reachable: true}); d != nil {
+		t.Errorf("expected nil for green, got %+v", d)
+	}
+	if d := DiagnoseNetwork(Network{Status: StatusRed}); d == nil || d.Summary != "Network is unreachable" {
+		t.Errorf("unreachable = %+v", d)
+	}
+
+	n := Network{
+		Status:    StatusYellow,
+		Reachable: true,
+		DNS: DNSConfig{
+			Resolvers: []DNSResolver{
+				{Nameservers: []string{"192.168.1.1"}},
+				{Domain: "vpn.corp.example", Nameservers: []string{"10.8.0.1"}},
+			},
+			Unresponsive: []string{"10.8.0.1"},
+		},
+		HostsOverrides: []HostsEntry{{IP: "10.1.2.3", Hostnames: []string{"registry.corp.example"}}},
+	}
+	d := DiagnoseNetwork(n)
+	if d == nil || d.Summary != "DNS servers are not answering" || !strings.Contains(d.Detail, "vpn.corp.example") ||
+		!strings.Contains(d.Action, "VPN") || !strings.Contains(d.Detail, "registry.corp.example") {
+		t.Errorf("stale VPN resolver = %+v", d)
+	}
+
+	n.Proxy = ProxySettings{PACURL: "http://wpad/wpad.dat", PACError: "connection refused"}
+	d = DiagnoseNetwork(n)
+	if d.Summary != "Proxy auto-config file is unreachable" || !strings.Contains(d.Detail, "wpad.dat") || !strings.Contains(d.Detail, "10.8.0.1") {
+		t.Errorf("PAC unreachable = %+v", d)
+	}
+}
@@ -0,0 +1,215 @@
+package health
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// updatesCacheName is the cache entry for `softwareupdate --list` output.
+const updatesCacheName = "softwareupdate.txt"
+
+// CheckOS collects the OS product, version, build, architecture and uptime,
+// plus pending software updates on macOS. Updates are read from a cache that
+// is refreshed in the background, since `softwareupdate --list` can take
+// tens of seconds. Restart-required or security updates turn it yellow.
+func CheckOS(cfg OSConfig) OS {
+	o := OS{Status: StatusGreen}
+
+	if out, err := exec.Command("/usr/bin/uname", "-m").Output(); err == nil {
+		o.Arch = strings.TrimSpace(string(out))
+	} else {
+		o.Arch = runtime.GOARCH
+	}
+
+	if runtime.GOOS == "linux" {
+		checkOSLinux(&o)
+		return o
+	}
+
+	out, err := exec.Command("/usr/bin/sw_vers").Output()
+	if err != nil {
+		o.Error = fmt.Sprintf("failed to get OS version: %v", err)
+		return o
+	}
+	o.ProductName, o.ProductVersion, o.BuildVersion = parseSwVers(string(out))
+
+	if boot, ok := bootTime(); ok {
+		o.UptimeSec = int64(time.Since(boot).Seconds())
+	}
+
+	if cfg.CheckUpdates {
+		dir := cacheDir()
+		data, fetched, stale := readCache(dir, updatesCacheName, time.Duration(cfg.UpdatesCacheTTL))
+		if stale {
+			refreshCacheAsync(dir, updatesCacheName, "/usr/sbin/softwareupdate", "--list")
+		}
+		if !fetched.IsZero() {
+			o.UpdatesCheckedAt = fetched.UTC()
+			o.PendingUpdates = parseSoftwareUpdate(string(data))
+		}
+	}
+
+	for _, u := range o.PendingUpdates {
+		if u.RestartRequired {
+			o.RestartRequired = true
+		}
+		if u.Security {
+			o.SecurityUpdates++
+		}
+	}
+	if o.RestartRequired || o.SecurityUpdates > 0 {
+		o.Status = StatusYellow
+	}
+
+	return o
+}
+
+// checkOSLinux fills o from /etc/os-release and the boot time in /proc/stat.
+func checkOSLinux(o *OS) {
+	data, err := os.ReadFile("/etc/os-release")
+	if err != nil {
+		o.Error = fmt.Sprintf("failed to read os-release: %v", err)
+	} else {
+		o.ProductName, o.ProductVersion, o.BuildVersion = parseOSRelease(string(data))
+	}
+
+	if boot, ok := bootTime(); ok {
+		o.UptimeSec = int64(time.Since(boot).Seconds())
+	}
+}
+
+// parseSwVers parses `sw_vers` output:
+//
+//	ProductName:		macOS
+//	ProductVersion:		15.3.1
+//	BuildVersion:		24D70
+func parseSwVers(s string) (name, version, build string) {
+	for _, line := range strings.Split(s, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "ProductName":
+			name = value
+		case "ProductVersion":
+			version = value
+		case "BuildVersion":
+			build = value
+		}
+	}
+	return
+}
+
+// parseOSRelease parses /etc/os-release KEY=value pairs (values may be quoted).
+func parseOSRelease(s string) (name, version, build string) {
+	for _, line := range strings.Split(s, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"'`)
+		switch key {
+		case "NAME":
+			name = value
+		case "VERSION_ID":
+			version = value
+		case "BUILD_ID":
+			build = value
+		}
+	}
+	return
+}
+
+var (
+	suLabelRe    = regexp.MustCompile(`^\*\s*Label:\s*(.+)$`)
+	suSizeRe     = regexp.MustCompile(`(\d+)\s*Ki?B?$`)
+	suSecurityRe = regexp.MustCompile(`(?i)security`)
+)
+
+// parseSoftwareUpdate parses `softwareupdate --list` output, where each update
+// is a "* Label: macOS Sequoia 15.3.2-24D81" line followed by a detail line like
+// "Title: macOS Sequoia 15.3.2, Version: 15.3.2, Size: 1634296KiB, Recommended: YES, Action: restart,".
+// "No new software available." (or empty output) yields no updates.
+func parseSoftwareUpdate(s string) []SoftwareUpdate {
+	var updates []SoftwareUpdate
+	var cur *SoftwareUpdate
+
+	for _, line := range strings.Split(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if m := suLabelRe.FindStringSubmatch(trimmed); len(m) >= 2 {
+			if cur != nil {
+				updates = append(updates, *cur)
+			}
+			cur = &SoftwareUpdate{Label: strings.TrimSpace(m[1])}
+			continue
+		}
+		if cur == nil || !strings.HasPrefix(trimmed, "Title:") {
+			continue
+		}
+		for _, field := range strings.Split(trimmed, ",") {
+			key, value, ok := strings.Cut(field, ":")
+			if !ok {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			switch strings.TrimSpace(key) {
+			case "Title":
+				cur.Title = value
+			case "Version":
+				cur.Version = value
+			case "Size":
+				if m := suSizeRe.FindStringSubmatch(value); len(m) >= 2 {
+					cur.SizeKB, _ = strconv.ParseInt(m[1], 10, 64)
+				}
+			case "Recommended":
+				cur.Recommended = strings.EqualFold(value, "yes")
+			case "Action":
+				cur.RestartRequired = strings.EqualFold(value, "restart")
+			}
+		}
+		cur.Security = suSecurityRe.MatchString(cur.Label) || suSecurityRe.MatchString(cur.Title)
+	}
+	if cur != nil {
+		updates = append(updates, *cur)
+	}
+	return updates
+}
+
+// DiagnoseOS returns diagnosis for pending OS updates.
+func DiagnoseOS(o OS) *Diagnosis {
+	if o.Status == StatusGreen {
+		return nil
+	}
+	d := &Diagnosis{
+		Subsystem: "os",
+		Severity:  o.Status,
+	}
+
+	var titles []string
+	for _, u := range o.PendingUpdates {
+		if u.RestartRequired || u.Security {
+			titles = append(titles, u.Title)
+		}
+	}
+
+	if o.SecurityUpdates > 0 {
+		d.Summary = "Security updates are pending"
+	} else {
+		d.Summary = "Software updates requiring a restart are pending"
+	}
+	d.Detail = fmt.Sprintf("%s %s (%s) has pending updates: %s.",
+		o.ProductName, o.ProductVersion, o.BuildVersion, strings.Join(titles, ", "))
+	if !o.UpdatesCheckedAt.IsZero() {
+		d.Detail += " Last checked " + o.UpdatesCheckedAt.Format(time.RFC3339) + "."
+	}
+	d.Action = "Install updates via System Settings > General > Software Update or 'softwareupdate --install --all'; schedule the restart outside long-running jobs"
+	return d
+}
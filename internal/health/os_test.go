@@ -0,0 +1,80 @@
+package health
+
+import (
+	"testing"
+)
+
+func TestParseSwVers(t *testing.T) {
+	name, version, build := parseSwVers("ProductName:\t\tmacOS\nProductVersion:\t\t15.3.1\nBuildVersion:\t\t24D70\n")
+	if name != "macOS" || version != "15.3.1" || build != "24D70" {
+		t.Errorf("parseSwVers() = %q, %q, %q", name, version, build)
+	}
+}
+
+func TestParseOSRelease(t *testing.T) {
+	input := `PRETTY_NAME="Ubuntu 24.04.1 LTS"
+NAME="Ubuntu"
+VERSION_ID="24.04"
+VERSION="24.04.1 LTS (Noble Numbat)"
+ID=ubuntu
+`
+	name, version, build := parseOSRelease(input)
+	if name != "Ubuntu" || version != "24.04" || build != "" {
+		t.Errorf("parseOSRelease() = %q, %q, %q", name, version, build)
+	}
+}
+
+func TestParseSoftwareUpdate(t *testing.T) {
+	input := `Software Update Tool
+
+Finding available software
+Software Update found the following new or updated software:
+* Label: macOS Sequoia 15.3.2-24D81
+	Title: macOS Sequoia 15.3.2, Version: 15.3.2, Size: 1634296KiB, Recommended: YES, Action: restart,
+* Label: Background Security Improvement (a)-24D81a
+	Title: Background Security Improvement (a), Version: 15.3.2 (a), Size: 86000KiB, Recommended: YES,
+* Label: Safari18.3.1SequoiaAuto-18.3.1
+	Title: Safari, Version: 18.3.1, Size: 189264K, Recommended: YES,
+`
+	updates := parseSoftwareUpdate(input)
+	if len(updates) != 3 {
+		t.Fatalf("len(updates) = %d, want 3", len(updates))
+	}
+
+	macos := updates[0]
+	if macos.Title != "macOS Sequoia 15.3.2" || macos.Version != "15.3.2" || macos.SizeKB != 1634296 {
+		t.Errorf("macOS update = %+v", macos)
+	}
+	if !macos.RestartRequired || !macos.Recommended || macos.Security {
+		t.Errorf("macOS flags = %+v", macos)
+	}
+	if !updates[1].Security || updates[1].RestartRequired {
+		t.Errorf("security update flags = %+v", updates[1])
+	}
+	if updates[2].SizeKB != 189264 || updates[2].RestartRequired {
+		t.Errorf("Safari update = %+v", updates[2])
+	}
+
+	if got := parseSoftwareUpdate("Software Update Tool\n\nFinding available software\nNo new software available.\n"); len(got) != 0 {
+		t.Errorf("expected no updates, got %v", got)
+	}
+}
+
+func TestDiagnoseOS(t *testing.T) {
+	if d := DiagnoseOS(OS{Status: StatusGreen}); d != nil {
+		t.Error("expected nil diagnosis for green status")
+	}
+	d := DiagnoseOS(OS{
+		Status:          StatusYellow,
+		ProductName:     "macOS",
+		ProductVersion:  "15.3.1",
+		SecurityUpdates: 1,
+		PendingUpdates:  []SoftwareUpdate{{Title: "Background Security Improvement (a)", Security: true}},
+	})
+	if d == nil {
+		t.Fatal("expected non-nil diagnosis")
+	}
+	if d.Subsystem != "os" || d.Summary != "Security updates are pending" {
+		t.Errorf("diagnosis = %+v", d)
+	}
+}
@@ -0,0 +1,239 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Package managers.
+const (
+	ManagerHomebrew = "homebrew"
+	ManagerApt      = "apt"
+)
+
+// Cache entries for package manager output.
+const (
+	brewOutdatedCacheName = "brew-outdated.json"
+	brewDoctorCacheName   = "brew-doctor.txt"
+	brewConfigCacheName   = "brew-config.txt"
+	aptUpgradeCacheName   = "apt-upgradable.txt"
+)
+
+// brewPaths are the default Homebrew locations (Apple silicon, Intel, Linux).
+var brewPaths = []string{"/opt/homebrew/bin/brew", "/usr/local/bin/brew", "/home/linuxbrew/.linuxbrew/bin/brew"}
+
+// CheckPackages reports Homebrew health (doctor warnings, outdated formulae
+// and casks, version) on macOS, or upgradable apt packages on Linux. All
+// package manager commands are slow, so results come from caches refreshed in
+// the background and the first check reports no data. Doctor warnings,
+// pending apt security updates or more outdated packages than MaxOutdated
+// are yellow.
+func CheckPackages(cfg PackagesConfig) Packages {
+	p := Packages{Status: StatusGreen}
+	dir := cacheDir()
+	ttl := time.Duration(cfg.CacheTTL)
+
+	if runtime.GOOS == "linux" {
+		if _, err := os.Stat("/usr/bin/apt"); err == nil {
+			p.Manager = ManagerApt
+			data, fetched := cachedOutput(dir, aptUpgradeCacheName, ttl, "/usr/bin/apt", "list", "--upgradable")
+			if !fetched.IsZero() {
+				p.CheckedAt = fetched.UTC()
+				p.Outdated = parseAptUpgradable(string(data))
+			}
+			p.finish(cfg)
+			return p
+		}
+	}
+
+	brew := findBrew()
+	if brew == "" {
+		return p
+	}
+	p.Manager = ManagerHomebrew
+
+	// Keep background runs from auto-updating taps, and capture doctor's
+	// warnings (stderr) even though it exits 1 when it has any.
+	noUpdate := []string{"/usr/bin/env", "HOMEBREW_NO_AUTO_UPDATE=1", "HOMEBREW_NO_ENV_HINTS=1"}
+	if data, fetched := cachedOutput(dir, brewOutdatedCacheName, ttl, append(noUpdate, brew, "outdated", "--json=v2")...); !fetched.IsZero() {
+		p.CheckedAt = fetched.UTC()
+		p.Outdated, p.OutdatedCasks = parseBrewOutdated(data)
+	}
+	if data, fetched := cachedOutput(dir, brewDoctorCacheName, ttl,
+		append(noUpdate, "/bin/sh", "-c", `"$0" doctor 2>&1; exit 0`, brew)...); !fetched.IsZero() {
+		p.DoctorWarnings = parseBrewDoctor(string(data))
+	}
+	if data, fetched := cachedOutput(dir, brewConfigCacheName, ttl, append(noUpdate, brew, "config")...); !fetched.IsZero() {
+		p.Version, p.Prefix, p.LastUpdate = parseBrewConfig(string(data))
+	}
+
+	p.finish(cfg)
+	return p
+}
+
+func findBrew() string {
+	for _, p := range brewPaths {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+func (p *Packages) finish(cfg PackagesConfig) {
+	p.OutdatedCount = len(p.Outdated) + len(p.OutdatedCasks)
+	for _, o := range p.Outdated {
+		if o.Security {
+			p.SecurityUpdates++
+		}
+	}
+	if len(p.DoctorWarnings) > 0 || p.SecurityUpdates > 0 ||
+		cfg.MaxOutdated > 0 && p.OutdatedCount > cfg.MaxOutdated {
+		p.Status = StatusYellow
+	}
+}
+
+// brewOutdated is the shape of `brew outdated --json=v2`.
+type brewOutdated struct {
+	Formulae []brewOutdatedItem `json:"formulae"`
+	Casks    []brewOutdatedItem `json:"casks"`
+}
+
+type brewOutdatedItem struct {
+	Name              string   `json:"name"`
+	InstalledVersions []string `json:"installed_versions"`
+	CurrentVersion    string   `json:"current_version"`
+	Pinned            bool     `json:"pinned"`
+}
+
+func parseBrewOutdated(data []byte) (formulae, casks []OutdatedPackage) {
+	var raw brewOutdated
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil
+	}
+	convert := func(items []brewOutdatedItem) []OutdatedPackage {
+		var out []OutdatedPackage
+		for _, it := range items {
+			installed := ""
+			if n := len(it.InstalledVersions); n > 0 {
+				installed = it.InstalledVersions[n-1]
+			}
+			out = append(out, OutdatedPackage{
+				Name:      it.Name,
+				Installed: installed,
+				Latest:    it.CurrentVersion,
+				Pinned:    it.Pinned,
+			})
+		}
+		return out
+	}
+	return convert(raw.Formulae), convert(raw.Casks)
+}
+
+// parseBrewDoctor returns the first line of each "Warning:" block in
+// `brew doctor` output. "Your system is ready to brew." yields none.
+func parseBrewDoctor(s string) []string {
+	var warnings []string
+	for _, line := range strings.Split(s, "\n") {
+		if w, ok := strings.CutPrefix(line, "Warning: "); ok {
+			warnings = append(warnings, strings.TrimSpace(w))
+		}
+	}
+	return warnings
+}
+
+// parseBrewConfig reads HOMEBREW_VERSION, HOMEBREW_PREFIX and the
+// "Core tap JSON" (or "Last commit") update time from `brew config`.
+func parseBrewConfig(s string) (version, prefix, lastUpdate string) {
+	for _, line := range strings.Split(s, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "HOMEBREW_VERSION":
+			version = value
+		case "HOMEBREW_PREFIX":
+			prefix = value
+		case "Core tap JSON", "Core tap last commit":
+			lastUpdate = value
+		case "Last commit":
+			if lastUpdate == "" {
+				lastUpdate = value
+			}
+		}
+	}
+	return
+}
+
+var aptUpgradableRe = regexp.MustCompile(`^(\S+?)/(\S+)\s+(\S+)\s+\S+\s+\[upgradable from: ([^\]]+)\]`)
+
+// parseAptUpgradable parses `apt list --upgradable`:
+//
+//	openssl/jammy-updates,jammy-security 3.0.2-0ubuntu1.18 amd64 [upgradable from: 3.0.2-0ubuntu1.17]
+func parseAptUpgradable(s string) []OutdatedPackage {
+	var pkgs []OutdatedPackage
+	for _, line := range strings.Split(s, "\n") {
+		m := aptUpgradableRe.FindStringSubmatch(strings.TrimSpace(line))
+		if len(m) < 5 {
+			continue
+		}
+		pkgs = append(pkgs, OutdatedPackage{
+			Name:      m[1],
+			Installed: m[4],
+			Latest:    m[3],
+			Security:  strings.Contains(m[2], "-security"),
+		})
+	}
+	return pkgs
+}
+
+// DiagnosePackages returns diagnosis summarising the most important
+// package manager problems.
+func DiagnosePackages(p Packages) *Diagnosis {
+	if p.Status == StatusGreen {
+		return nil
+	}
+	d := &Diagnosis{
+		Subsystem: "packages",
+		Severity:  p.Status,
+	}
+
+	var details []string
+	switch {
+	case len(p.DoctorWarnings) > 0:
+		d.Summary = fmt.Sprintf("brew doctor reports %d warning(s)", len(p.DoctorWarnings))
+		limit := min(len(p.DoctorWarnings), 3)
+		details = append(details, "Warnings: "+strings.Join(p.DoctorWarnings[:limit], "; ")+".")
+		d.Action = "Run 'brew doctor' and follow each warning's instructions (commonly 'brew link <formula>', 'brew cleanup' or 'brew update-reset')"
+	case p.SecurityUpdates > 0:
+		d.Summary = fmt.Sprintf("%d security update(s) are pending", p.SecurityUpdates)
+		var names []string
+		for _, o := range p.Outdated {
+			if o.Security {
+				names = append(names, o.Name)
+			}
+		}
+		sort.Strings(names)
+		details = append(details, "Security updates: "+strings.Join(names, ", ")+".")
+		d.Action = "Run 'sudo apt update && sudo apt upgrade'"
+	default:
+		d.Summary = fmt.Sprintf("%d packages are outdated", p.OutdatedCount)
+		d.Action = "Run 'brew upgrade' (or 'sudo apt upgrade') between jobs"
+	}
+	if p.OutdatedCount > 0 {
+		details = append(details, fmt.Sprintf("%d outdated (%d formulae/packages, %d casks).", p.OutdatedCount, len(p.Outdated), len(p.OutdatedCasks)))
+	}
+	if !p.CheckedAt.IsZero() {
+		details = append(details, "Last checked "+p.CheckedAt.Format(time.RFC3339)+".")
+	}
+	d.Detail = strings.Join(details, " ")
+	return d
+}
@@ -0,0 +1,126 @@
+package health
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBrewOutdated(t *testing.T) {
+	input := `{
+  "formulae": [
+    {"name": "git", "installed_versions": ["2.47.0"], "current_version": "2.47.1", "pinned": false, "pinned_version": null},
+    {"name": "python@3.12", "installed_versions": ["3.12.7", "3.12.8"], "current_version": "3.12.9", "pinned": true, "pinned_version": "3.12.8"}
+  ],
+  "casks": [
+    {"name": "docker", "installed_versions": ["4.36.0"], "current_version": "4.37.0"}
+  ]
+}`
+	formulae, casks := parseBrewOutdated([]byte(input))
+	if len(formulae) != 2 || len(casks) != 1 {
+		t.Fatalf("got %d formulae, %d casks", len(formulae), len(casks))
+	}
+	if f := formulae[1]; f.Name != "python@3.12" || f.Installed != "3.12.8" || f.Latest != "3.12.9" || !f.Pinned {
+		t.Errorf("formulae[1] = %+v", f)
+	}
+	if casks[0].Name != "docker" || casks[0].Latest != "4.37.0" {
+		t.Errorf("casks[0] = %+v", casks[0])
+	}
+	if f, c := parseBrewOutdated([]byte("Error: ...")); f != nil || c != nil {
+		t.Error("expected nil on invalid JSON")
+	}
+}
+
+func TestParseBrewDoctor(t *testing.T) {
+	input := `Please note that these warnings are just used to help the Homebrew maintainers
+with debugging if you file an issue. If everything you use Homebrew for is
+working fine: please don't worry or file an issue; just ignore this. Thanks!
+
+Warning: You have unlinked kegs in your Cellar.
+Leaving kegs unlinked can lead to build-trouble and cause formulae that depend on
+those kegs to fail to run properly once built. Run ` + "`brew link`" + ` on these:
+  openssl@3
+
+Warning: Some installed formulae are deprecated or disabled.
+You should find replacements for the following formulae:
+  python@3.8
+`
+	warnings := parseBrewDoctor(input)
+	if len(warnings) != 2 || warnings[0] != "You have unlinked kegs in your Cellar." {
+		t.Errorf("parseBrewDoctor() = %q", warnings)
+	}
+	if got := parseBrewDoctor("Your system is ready to brew.\n"); got != nil {
+		t.Errorf("healthy doctor = %q", got)
+	}
+}
+
+func TestParseBrewConfig(t *testing.T) {
+	input := `HOMEBREW_VERSION: 4.4.15
+ORIGIN: https://github.com/Homebrew/brew
+HEAD: 0c8e8a1b1a1d0a0e6c5b4a3f2e1d0c9b8a7f6e5d
+Last commit: 3 days ago
+Core tap JSON: 18 Feb 09:00 UTC
+HOMEBREW_PREFIX: /opt/homebrew
+CPU: dodeca-core 64-bit arm_firestorm_icestorm
+macOS: 15.3.1-arm64
+`
+	version, prefix, last := parseBrewConfig(input)
+	if version != "4.4.15" || prefix != "/opt/homebrew" || last != "18 Feb 09:00 UTC" {
+		t.Errorf("parseBrewConfig() = %q, %q, %q", version, prefix, last)
+	}
+}
+
+func TestParseAptUpgradable(t *testing.T) {
+	input := `Listing...
+openssl/jammy-updates,jammy-security 3.0.2-0ubuntu1.18 amd64 [upgradable from: 3.0.2-0ubuntu1.17]
+git/jammy-updates 1:2.34.1-1ubuntu1.12 amd64 [upgradable from: 1:2.34.1-1ubuntu1.11]
+`
+	pkgs := parseAptUpgradable(input)
+	if len(pkgs) != 2 {
+		t.Fatalf("got %d packages, want 2", len(pkgs))
+	}
+	if p := pkgs[0]; p.Name != "openssl" || p.Latest != "3.0.2-0ubuntu1.18" || p.Installed != "3.0.2-0ubuntu1.17" || !p.Security {
+		t.Errorf("pkgs[0] = %+v", p)
+	}
+	if pkgs[1].Security {
+		t.Error("git update is not a security update")
+	}
+}
+
+func TestPackagesFinishAndDiagnose(t *testing.T) {
+	cfg := PackagesConfig{MaxOutdated: 2}
+
+	p := Packages{Status: StatusGreen, Outdated: []OutdatedPackage{{Name: "git"}}}
+	p.finish(cfg)
+	if p.Status != StatusGreen || p.OutdatedCount != 1 || DiagnosePackages(p) != nil {
+		t.Errorf("one outdated = %+v", p)
+	}
+
+	p = Packages{Status: StatusGreen, Outdated: make([]OutdatedPackage, 2), OutdatedCasks: make([]OutdatedPackage, 1)}
+	p.finish(cfg)
+	if p.Status != StatusYellow {
+		t.Errorf("over MaxOutdated: Status = %v", p.Status)
+	}
+
+	p = Packages{
+		Status:         StatusGreen,
+		DoctorWarnings: []string{"You have unlinked kegs in your Cellar."},
+		Outdated:       []OutdatedPackage{{Name: "git"}},
+		CheckedAt:      time.Date(2026, 2, 18, 9, 0, 0, 0, time.UTC),
+	}
+	p.finish(cfg)
+	d := DiagnosePackages(p)
+	if d == nil || d.Summary != "brew doctor reports 1 warning(s)" || !strings.Contains(d.Detail, "unlinked kegs") ||
+		!strings.Contains(d.Detail, "2026-02-18T09:00:00Z") {
+		t.Errorf("doctor diagnosis = %+v", d)
+	}
+
+	p = Packages{Status: StatusGreen, Outdated: parseAptUpgradable("openssl/jammy-security 3 amd64 [upgradable from: 2]\n")}
+	p.finish(cfg)
+	if p.SecurityUpdates != 1 || p.Status != StatusYellow {
+		t.Fatalf("apt security = %+v", p)
+	}
+	if d := DiagnosePackages(p); d == nil || !strings.Contains(d.Detail, "openssl") {
+		t.Errorf("security diagnosis = %+v", d)
+	}
+}
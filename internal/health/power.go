@@ -0,0 +1,217 @@
+package health
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Assertion types that keep the system or display awake.
+var (
+	systemSleepAssertions  = []string{"PreventUserIdleSystemSleep", "PreventSystemSleep", "NoIdleSleepAssertion"}
+	displaySleepAssertions = []string{"PreventUserIdleDisplaySleep", "NoDisplaySleepAssertion"}
+)
+
+// CheckPower collects sleep settings, power assertions, clamshell state and
+// Low Power Mode. An assertion preventing sleep held longer than the
+// configured StaleAssertion age (a forgotten caffeinate, a stuck media
+// player) is yellow. SleepRisk is informational and never affects status.
+func CheckPower(cfg PowerConfig) Power {
+	p := Power{Status: StatusGreen}
+
+	out, err := exec.Command("/usr/bin/pmset", "-g").Output()
+	if err != nil {
+		p.Error = fmt.Sprintf("failed to read power settings: %v", err)
+		return p
+	}
+	parsePmsetSettings(string(out), &p)
+
+	if out, err := exec.Command("/usr/bin/pmset", "-g", "assertions").Output(); err == nil {
+		parsePmsetAssertions(string(out), &p)
+	}
+
+	if out, err := exec.Command("/usr/sbin/ioreg", "-r", "-k", "AppleClamshellState", "-d", "1").Output(); err == nil {
+		p.LidClosed, p.ClamshellCausesSleep = parseClamshell(string(out))
+	}
+
+	p.SleepRisk, p.SleepRiskReason = sleepRisk(p)
+
+	if stale := time.Duration(cfg.StaleAssertion); stale > 0 {
+		for _, a := range p.Assertions {
+			if a.PreventsSleep && time.Duration(a.AgeSec)*time.Second > stale {
+				p.Status = StatusYellow
+				break
+			}
+		}
+	}
+
+	return p
+}
+
+var pmsetSettingRe = regexp.MustCompile(`^\s*(\w+)\s+(\d+)`)
+
+// parsePmsetSettings parses the "Currently in use" block of `pmset -g`:
+//
+//	sleep                1 (sleep prevented by caffeinate, coreaudiod)
+//	displaysleep         10
+//	lowpowermode         0
+func parsePmsetSettings(s string, p *Power) {
+	for _, line := range strings.Split(s, "\n") {
+		m := pmsetSettingRe.FindStringSubmatch(line)
+		if len(m) < 3 {
+			continue
+		}
+		v, _ := strconv.Atoi(m[2])
+		switch m[1] {
+		case "sleep":
+			p.SleepMinutes = v
+			if strings.Contains(line, "sleep prevented by ") {
+				p.SystemSleepPrevented = true
+			}
+		case "displaysleep":
+			p.DisplaySleepMinutes = v
+			if strings.Contains(line, "display sleep prevented by ") {
+				p.DisplaySleepPrevented = true
+			}
+		case "lowpowermode", "powermode":
+			// Apple silicon reports "powermode 1" for Low Power Mode.
+			p.LowPowerMode = v == 1
+		}
+	}
+}
+
+var (
+	assertionSummaryRe = regexp.MustCompile(`^\s+(\w+)\s+(\d+)\s*$`)
+	assertionOwnerRe   = regexp.MustCompile(`^\s*pid (\d+)\((.*?)\): \[0x[0-9a-fA-F]+\] (\d+:\d{2}:\d{2}) (\w+) named: "(.*)"`)
+)
+
+// parsePmsetAssertions parses `pmset -g assertions`. The "Assertion status
+// system-wide" block gives the aggregate flags; each "Listed by owning
+// process" line looks like:
+//
+//	pid 412(caffeinate): [0x0000abcd00018e4f] 01:02:03 PreventUserIdleSystemSleep named: "caffeinate command-line tool"
+func parsePmsetAssertions(s string, p *Power) {
+	section := ""
+	for _, line := range strings.Split(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "Assertion status system-wide"):
+			section = "summary"
+			continue
+		case strings.HasPrefix(trimmed, "Listed by owning process"):
+			section = "owners"
+			continue
+		case strings.HasPrefix(trimmed, "Kernel Assertions"), strings.HasPrefix(trimmed, "Idle sleep preventers"):
+			section = ""
+			continue
+		}
+
+		switch section {
+		case "summary":
+			m := assertionSummaryRe.FindStringSubmatch(line)
+			if len(m) < 3 || m[2] == "0" {
+				continue
+			}
+			if slices.Contains(systemSleepAssertions, m[1]) {
+				p.SystemSleepPrevented = true
+			}
+			if slices.Contains(displaySleepAssertions, m[1]) {
+				p.DisplaySleepPrevented = true
+			}
+		case "owners":
+			m := assertionOwnerRe.FindStringSubmatch(line)
+			if len(m) < 6 {
+				continue
+			}
+			pid, _ := strconv.Atoi(m[1])
+			p.Assertions = append(p.Assertions, PowerAssertion{
+				PID:           pid,
+				Process:       m[2],
+				Type:          m[4],
+				Name:          m[5],
+				AgeSec:        parseAssertionAge(m[3]),
+				PreventsSleep: slices.Contains(systemSleepAssertions, m[4]) || slices.Contains(displaySleepAssertions, m[4]),
+			})
+		}
+	}
+}
+
+// parseAssertionAge parses an "hh:mm:ss" assertion age; hours may exceed 24.
+func parseAssertionAge(s string) int64 {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0
+	}
+	var total int64
+	for _, part := range parts {
+		v, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return 0
+		}
+		total = total*60 + v
+	}
+	return total
+}
+
+var (
+	clamshellStateRe = regexp.MustCompile(`"AppleClamshellState"\s*=\s*(Yes|No)`)
+	clamshellSleepRe = regexp.MustCompile(`"AppleClamshellCausesSleep"\s*=\s*(Yes|No)`)
+)
+
+// parseClamshell parses `ioreg -r -k AppleClamshellState -d 1`. Desktops have
+// no clamshell and yield false, false.
+func parseClamshell(s string) (closed, causesSleep bool) {
+	if m := clamshellStateRe.FindStringSubmatch(s); len(m) >= 2 {
+		closed = m[1] == "Yes"
+	}
+	if m := clamshellSleepRe.FindStringSubmatch(s); len(m) >= 2 {
+		causesSleep = m[1] == "Yes"
+	}
+	return
+}
+
+// sleepRisk reports whether a long-running task started now could be
+// interrupted by sleep, and why.
+func sleepRisk(p Power) (bool, string) {
+	switch {
+	case p.LidClosed && p.ClamshellCausesSleep:
+		return true, "lid is closed without an external display; the Mac will sleep regardless of assertions"
+	case p.SystemSleepPrevented:
+		return false, ""
+	case p.SleepMinutes > 0:
+		return true, fmt.Sprintf("system idle sleep after %d min and no assertion prevents it", p.SleepMinutes)
+	default:
+		return false, ""
+	}
+}
+
+// DiagnosePower returns diagnosis for long-held sleep assertions.
+func DiagnosePower(p Power) *Diagnosis {
+	if p.Status == StatusGreen {
+		return nil
+	}
+	d := &Diagnosis{
+		Subsystem: "power",
+		Severity:  p.Status,
+		Summary:   "A process has been preventing sleep for a long time",
+	}
+
+	var holders []string
+	for _, a := range p.Assertions {
+		if !a.PreventsSleep {
+			continue
+		}
+		holders = append(holders, fmt.Sprintf("%s (pid %d) %s for %s",
+			a.Process, a.PID, a.Type, FormatDuration(time.Duration(a.AgeSec)*time.Second)))
+	}
+	d.Detail = "Active sleep assertions: " + strings.Join(holders, "; ") + ". The Mac stays awake, drains battery and runs warm."
+	if p.LowPowerMode {
+		d.Detail += " Low Power Mode is on."
+	}
+	d.Action = "If the assertion is not intentional, quit the owning app or 'kill' the caffeinate process; 'pmset -g assertions' shows the current holders"
+	return d
+}
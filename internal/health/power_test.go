@@ -0,0 +1,148 @@
+package health
+
+import (
+	"strings"
+	"testing"
+)
+
+const samplePmsetSettings = `System-wide power settings:
+Currently in use:
+ standby              1
+ Sleep On Power Button 1
+ hibernatefile        /var/vm/sleepimage
+ powernap             1
+ disksleep            10
+ sleep                1 (sleep prevented by caffeinate, coreaudiod)
+ hibernatemode        3
+ ttyskeepawake        1
+ displaysleep         10
+ tcpkeepalive         1
+ lowpowermode         1
+ womp                 0
+`
+
+const samplePmsetAssertions = `2026-02-18 09:00:00 -0800
+Assertion status system-wide:
+   BackgroundTask                 0
+   ApplePushServiceTask           0
+   UserIsActive                   1
+   PreventUserIdleDisplaySleep    0
+   PreventSystemSleep             0
+   ExternalMedia                  1
+   PreventUserIdleSystemSleep     1
+   NetworkClientActive            0
+Listed by owning process:
+   pid 412(caffeinate): [0x0000abcd00018e4f] 14:02:03 PreventUserIdleSystemSleep named: "caffeinate command-line tool"
+	Details: caffeinate asserting forever
+	Localized=THE CAFFEINATE TOOL IS PREVENTING SLEEP.
+   pid 98(powerd): [0x0000abcd00018e50] 00:00:29 ExternalMedia named: "com.apple.powermanagement.externalmediamounted"
+   pid 321(coreaudiod): [0x0000abcd00018e51] 00:10:00 PreventUserIdleSystemSleep named: "com.apple.audio.context.preventuseridlesleep"
+	Created for PID: 789.
+Kernel Assertions: 0x4=USB
+   id=500  level=255 0x4=USB mod=2/18/26, 8:00 AM description=com.apple.usb.externaldevice.14200000 owner=Apple Keyboard
+Idle sleep preventers: IODisplayWrangler
+`
+
+func TestParsePmsetSettings(t *testing.T) {
+	var p Power
+	parsePmsetSettings(samplePmsetSettings, &p)
+	if p.SleepMinutes != 1 || p.DisplaySleepMinutes != 10 {
+		t.Errorf("sleep/displaysleep = %d/%d, want 1/10", p.SleepMinutes, p.DisplaySleepMinutes)
+	}
+	if !p.SystemSleepPrevented || p.DisplaySleepPrevented {
+		t.Errorf("prevented system/display = %v/%v, want true/false", p.SystemSleepPrevented, p.DisplaySleepPrevented)
+	}
+	if !p.LowPowerMode {
+		t.Error("expected Low Power Mode")
+	}
+}
+
+func TestParsePmsetAssertions(t *testing.T) {
+	var p Power
+	parsePmsetAssertions(samplePmsetAssertions, &p)
+	if !p.SystemSleepPrevented || p.DisplaySleepPrevented {
+		t.Errorf("prevented system/display = %v/%v, want true/false", p.SystemSleepPrevented, p.DisplaySleepPrevented)
+	}
+	if len(p.Assertions) != 3 {
+		t.Fatalf("got %d assertions, want 3: %+v", len(p.Assertions), p.Assertions)
+	}
+	a := p.Assertions[0]
+	if a.PID != 412 || a.Process != "caffeinate" || a.Type != "PreventUserIdleSystemSleep" ||
+		a.Name != "caffeinate command-line tool" || a.AgeSec != 14*3600+2*60+3 || !a.PreventsSleep {
+		t.Errorf("Assertions[0] = %+v", a)
+	}
+	if p.Assertions[1].Process != "powerd" || p.Assertions[1].PreventsSleep {
+		t.Errorf("Assertions[1] = %+v, ExternalMedia does not prevent sleep", p.Assertions[1])
+	}
+}
+
+func TestParseAssertionAge(t *testing.T) {
+	tests := map[string]int64{
+		"00:00:29":  29,
+		"01:02:03":  3723,
+		"123:00:00": 123 * 3600,
+		"bad":       0,
+		"1:xx:00":   0,
+	}
+	for in, want := range tests {
+		if got := parseAssertionAge(in); got != want {
+			t.Errorf("parseAssertionAge(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestParseClamshell(t *testing.T) {
+	input := `+-o AppleSMC  <class AppleSMC, id 0x100000123>
+    {
+      "AppleClamshellState" = Yes
+      "AppleClamshellCausesSleep" = No
+    }`
+	closed, causesSleep := parseClamshell(input)
+	if !closed || causesSleep {
+		t.Errorf("parseClamshell() = %v, %v, want true, false", closed, causesSleep)
+	}
+	if closed, causesSleep := parseClamshell(""); closed || causesSleep {
+		t.Error("desktop without clamshell should be open")
+	}
+}
+
+func TestSleepRisk(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Power
+		want bool
+	}{
+		{"idle sleep, no assertion", Power{SleepMinutes: 10}, true},
+		{"assertion held", Power{SleepMinutes: 10, SystemSleepPrevented: true}, false},
+		{"sleep disabled", Power{SleepMinutes: 0}, false},
+		{"lid closed overrides assertion", Power{SleepMinutes: 0, SystemSleepPrevented: true, LidClosed: true, ClamshellCausesSleep: true}, true},
+		{"clamshell mode with external display", Power{SleepMinutes: 10, SystemSleepPrevented: true, LidClosed: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := sleepRisk(tt.p)
+			if got != tt.want {
+				t.Errorf("sleepRisk() = %v, want %v", got, tt.want)
+			}
+			if got && reason == "" {
+				t.Error("expected a reason")
+			}
+		})
+	}
+}
+
+func TestDiagnosePower(t *testing.T) {
+	if DiagnosePower(Power{Status: StatusGreen}) != nil {
+		t.Error("expected nil for green")
+	}
+	var p Power
+	parsePmsetAssertions(samplePmsetAssertions, &p)
+	p.Status = StatusYellow
+	d := DiagnosePower(p)
+	if d == nil {
+		t.Fatal("expected diagnosis")
+	}
+	if !strings.Contains(d.Detail, "caffeinate (pid 412)") || strings.Contains(d.Detail, "powerd") {
+		t.Errorf("Detail = %q", d.Detail)
+	}
+}
@@ -0,0 +1,309 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// processSnapshotName is the cache entry holding per-process CPU time from
+// the previous check, used to tell whether a process is making progress.
+const processSnapshotName = "processes.json"
+
+// CheckProcesses lists zombie and uninterruptible processes, processes that
+// made no CPU progress for longer than the configured stuck age, and watched
+// tools whose parent exited. Progress and parents are compared against a
+// snapshot from the previous check, so stuck detection needs two checks at
+// least StuckAge apart and orphans are found from the second check on. A
+// stuck process in uninterruptible sleep is red (only a reboot clears it);
+// other stuck or orphaned processes, or more zombies than MaxZombies, are
+// yellow.
+func CheckProcesses(cfg ProcessesConfig) Processes {
+	out, err := exec.Command("/bin/ps", "-axo", "pid=,ppid=,uid=,stat=,etime=,time=,comm=").Output()
+	if err != nil {
+		return Processes{Status: StatusGreen, Error: fmt.Sprintf("failed to list processes: %v", err)}
+	}
+
+	dir := cacheDir()
+	prev := loadProcessSnapshot(dir)
+	p, next := analyzeProcesses(parsePs(string(out)), prev, time.Now(), cfg)
+	saveProcessSnapshot(dir, next)
+	return p
+}
+
+// psEntry is one row of ps output.
+type psEntry struct {
+	PID     int
+	PPID    int
+	UID     int
+	State   string
+	AgeSec  int64
+	CPUSec  float64
+	Command string
+}
+
+// parsePs parses `ps -axo pid=,ppid=,uid=,stat=,etime=,time=,comm=`. The
+// command is last because it may contain spaces.
+func parsePs(s string) []psEntry {
+	var entries []psEntry
+	for _, line := range strings.Split(s, "\n") {
+		f := strings.Fields(line)
+		if len(f) < 7 {
+			continue
+		}
+		pid, err1 := strconv.Atoi(f[0])
+		ppid, err2 := strconv.Atoi(f[1])
+		uid, err3 := strconv.Atoi(f[2])
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		age, _ := parsePsDuration(f[4])
+		cpu, _ := parsePsDuration(f[5])
+		entries = append(entries, psEntry{
+			PID:     pid,
+			PPID:    ppid,
+			UID:     uid,
+			State:   f[3],
+			AgeSec:  int64(age),
+			CPUSec:  cpu,
+			Command: strings.Join(f[6:], " "),
+		})
+	}
+	return entries
+}
+
+// parsePsDuration parses ps elapsed and CPU times, "[[dd-]hh:]mm:ss[.xx]",
+// into seconds.
+func parsePsDuration(s string) (float64, bool) {
+	var days float64
+	if d, rest, ok := strings.Cut(s, "-"); ok {
+		n, err := strconv.Atoi(d)
+		if err != nil {
+			return 0, false
+		}
+		days, s = float64(n), rest
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, false
+	}
+	var total float64
+	for _, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, false
+		}
+		total = total*60 + v
+	}
+	return days*86400 + total, true
+}
+
+// processSample is what the snapshot remembers about a process.
+type processSample struct {
+	Start    int64   `json:"start"`   // unix seconds, identifies the process across PID reuse
+	PPID     int     `json:"ppid"`    // parent at the last check
+	CPUSec   float64 `json:"cpu_sec"` // CPU time of the process and its descendants when first seen at this value
+	Since    int64   `json:"since"`   // unix seconds since CPU time stopped changing
+	Orphaned bool    `json:"orphaned,omitempty"`
+}
+
+// analyzeProcesses classifies entries and returns the snapshot for the next check.
+func analyzeProcesses(entries []psEntry, prev map[int]processSample, now time.Time, cfg ProcessesConfig) (Processes, map[int]processSample) {
+	p := Processes{Status: StatusGreen, Total: len(entries)}
+	next := map[int]processSample{}
+	stuckAge := time.Duration(cfg.StuckAge)
+
+	children := map[int][]int{}
+	cpu := map[int]float64{}
+	for _, e := range entries {
+		if e.PID != e.PPID {
+			children[e.PPID] = append(children[e.PPID], e.PID)
+		}
+		cpu[e.PID] = e.CPUSec
+	}
+
+	stuckUninterruptible := false
+	for _, e := range entries {
+		info := ProcessInfo{
+			PID:     e.PID,
+			PPID:    e.PPID,
+			Command: e.Command,
+			State:   e.State,
+			AgeSec:  e.AgeSec,
+			CPUSec:  e.CPUSec,
+		}
+		name := filepath.Base(e.Command)
+		watched := slices.Contains(cfg.Watch, name)
+		uninterruptible := strings.HasPrefix(e.State, "U") || strings.HasPrefix(e.State, "D")
+
+		switch {
+		case strings.HasPrefix(e.State, "Z"):
+			p.Zombies = append(p.Zombies, info)
+		case uninterruptible:
+			p.Uninterruptible = append(p.Uninterruptible, info)
+		}
+
+		// Track only processes worth flagging; idle daemons legitimately
+		// use no CPU for days.
+		if !watched && !uninterruptible {
+			continue
+		}
+
+		// A build driver (make, xcodebuild) idles while its children
+		// compile, so CPU time anywhere in the tree counts as progress.
+		start := now.Unix() - e.AgeSec
+		sample := processSample{Start: start, PPID: e.PPID, CPUSec: treeCPU(children, cpu, e.PID), Since: now.Unix()}
+		if old, ok := prev[e.PID]; ok && abs64(old.Start-start) <= 2 {
+			if old.CPUSec == sample.CPUSec {
+				sample.Since = old.Since
+			}
+			// Reparenting only happens when the parent exits; the new
+			// parent is launchd/init or a subreaper.
+			sample.Orphaned = old.Orphaned || (old.PPID != 0 && old.PPID != e.PPID)
+		}
+		next[e.PID] = sample
+
+		if watched && sample.Orphaned {
+			orphan := info
+			orphan.Descendants = countDescendants(children, e.PID)
+			p.Orphans = append(p.Orphans, orphan)
+		}
+
+		stalled := now.Sub(time.Unix(sample.Since, 0))
+		if stuckAge > 0 && stalled >= stuckAge {
+			info.StalledSec = int64(stalled.Seconds())
+			p.Stuck = append(p.Stuck, info)
+			if uninterruptible {
+				stuckUninterruptible = true
+			}
+		}
+	}
+
+	for _, list := range [][]ProcessInfo{p.Zombies, p.Uninterruptible, p.Stuck, p.Orphans} {
+		sort.Slice(list, func(i, j int) bool { return list[i].AgeSec > list[j].AgeSec })
+	}
+
+	switch {
+	case stuckUninterruptible:
+		p.Status = StatusRed
+	case len(p.Stuck) > 0, len(p.Orphans) > 0, cfg.MaxZombies > 0 && len(p.Zombies) >= cfg.MaxZombies:
+		p.Status = StatusYellow
+	}
+	return p, next
+}
+
+// treeCPU returns the CPU time of pid and all its descendants.
+func treeCPU(children map[int][]int, cpu map[int]float64, pid int) float64 {
+	total := cpu[pid]
+	for _, c := range children[pid] {
+		total += treeCPU(children, cpu, c)
+	}
+	return total
+}
+
+func countDescendants(children map[int][]int, pid int) int {
+	n := 0
+	for _, c := range children[pid] {
+		n += 1 + countDescendants(children, c)
+	}
+	return n
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func loadProcessSnapshot(dir string) map[int]processSample {
+	data, _, _ := readCache(dir, processSnapshotName, 0)
+	var prev map[int]processSample
+	if json.Unmarshal(data, &prev) != nil {
+		return nil
+	}
+	return prev
+}
+
+// saveProcessSnapshot writes the snapshot atomically, since concurrent
+// checks (watch, serve) may read it at any time.
+func saveProcessSnapshot(dir string, next map[int]processSample) {
+	if dir == "" {
+		return
+	}
+	data, err := json.Marshal(next)
+	if err != nil || os.MkdirAll(dir, 0o755) != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(dir, processSnapshotName+".*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if os.Rename(tmp.Name(), filepath.Join(dir, processSnapshotName)) != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// DiagnoseProcesses returns diagnosis for stuck, orphaned and zombie processes.
+func DiagnoseProcesses(p Processes) *Diagnosis {
+	if p.Status == StatusGreen {
+		return nil
+	}
+	d := &Diagnosis{
+		Subsystem: "processes",
+		Severity:  p.Status,
+	}
+
+	describe := func(list []ProcessInfo, limit int) string {
+		var parts []string
+		for i, pi := range list {
+			if i == limit {
+				parts = append(parts, fmt.Sprintf("and %d more", len(list)-limit))
+				break
+			}
+			parts = append(parts, fmt.Sprintf("%s (pid %d, state %s, age %s)",
+				filepath.Base(pi.Command), pi.PID, pi.State, FormatDuration(time.Duration(pi.AgeSec)*time.Second)))
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	var details []string
+	switch {
+	case len(p.Stuck) > 0:
+		d.Summary = "Processes are stuck without CPU progress"
+		stalled := FormatDuration(time.Duration(p.Stuck[0].StalledSec) * time.Second)
+		details = append(details, fmt.Sprintf("No CPU progress for %s: %s.", stalled, describe(p.Stuck, 5)))
+		if p.Status == StatusRed {
+			d.Action = "A process stuck in uninterruptible sleep (state U/D) cannot be killed; check the disk or network mount it is waiting on, and reboot if it does not recover. Kill other stuck processes with 'kill <pid>'"
+		} else {
+			d.Action = "Kill the stuck processes with 'kill <pid>' ('kill -9' if they ignore SIGTERM) and rerun the job"
+		}
+	case len(p.Orphans) > 0:
+		d.Summary = "Build tools were orphaned by a dead parent"
+		details = append(details, "Parent exited, now reparented: "+describe(p.Orphans, 5)+".")
+		d.Action = "Kill the orphaned trees with 'pkill -P <pid>; kill <pid>' so they stop holding locks and CPU"
+	default:
+		d.Summary = "Zombie processes are piling up"
+		d.Action = "Zombies are cleared when their parent reaps or exits; restart the parent process shown by 'ps -o ppid= -p <pid>'"
+	}
+	if len(p.Zombies) > 0 {
+		details = append(details, fmt.Sprintf("%d zombie process(es): %s.", len(p.Zombies), describe(p.Zombies, 3)))
+	}
+	d.Detail = strings.Join(details, " ")
+	return d
+}
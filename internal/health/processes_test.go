@@ -0,0 +1,148 @@
+package health
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePsDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"00:29", 29, true},
+		{"1:02:03", 3723, true},
+		{"2-01:00:00", 2*86400 + 3600, true},
+		{"0:01.23", 1.23, true},
+		{"123:45.67", 123*60 + 45.67, true},
+		{"bad", 0, false},
+		{"x-01:00", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parsePsDuration(tt.in)
+		if ok != tt.ok || (ok && (got < tt.want-0.001 || got > tt.want+0.001)) {
+			t.Errorf("parsePsDuration(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParsePs(t *testing.T) {
+	input := `    1     0     0 Ss   2-01:00:00   1:23.45 /sbin/launchd
+  812     1   501 U       45:00   0:00.12 /usr/bin/git
+  900   812   501 Z       44:59   0:00.00 (git-remote-https)
+  901     1   501 S       10:00   0:00.50 /Applications/My App.app/Contents/MacOS/My App
+`
+	entries := parsePs(input)
+	if len(entries) != 4 {
+		t.Fatalf("got %d entries, want 4", len(entries))
+	}
+	e := entries[1]
+	if e.PID != 812 || e.PPID != 1 || e.UID != 501 || e.State != "U" || e.AgeSec != 2700 || e.Command != "/usr/bin/git" {
+		t.Errorf("entries[1] = %+v", e)
+	}
+	if entries[3].Command != "/Applications/My App.app/Contents/MacOS/My App" {
+		t.Errorf("command with spaces = %q", entries[3].Command)
+	}
+}
+
+func TestAnalyzeProcesses(t *testing.T) {
+	cfg := ProcessesConfig{StuckAge: Duration(10 * time.Minute), Watch: []string{"git", "xcodebuild"}, MaxZombies: 2}
+	now := time.Unix(1771430000, 0)
+	entries := []psEntry{
+		{PID: 1, PPID: 0, State: "Ss", AgeSec: 86400, CPUSec: 83, Command: "/sbin/launchd"},
+		{PID: 812, PPID: 500, State: "U", AgeSec: 2700, CPUSec: 0.12, Command: "/usr/bin/git"},
+		{PID: 900, PPID: 812, State: "Z", AgeSec: 2699, Command: "git-remote-https"},
+		{PID: 950, PPID: 700, State: "S", AgeSec: 600, CPUSec: 4, Command: "/usr/bin/xcodebuild"},
+		{PID: 951, PPID: 950, State: "R", AgeSec: 590, CPUSec: 30, Command: "/usr/bin/clang"},
+		{PID: 952, PPID: 951, State: "S", AgeSec: 580, CPUSec: 1, Command: "/usr/bin/ld"},
+		{PID: 960, PPID: 1, State: "S", AgeSec: 90000, CPUSec: 2, Command: "/usr/libexec/idled"},
+	}
+
+	// First check: nothing can be stuck yet.
+	p, snap := analyzeProcesses(entries, nil, now, cfg)
+	if len(p.Stuck) != 0 {
+		t.Errorf("first check reported stuck: %+v", p.Stuck)
+	}
+	if len(p.Zombies) != 1 || len(p.Uninterruptible) != 1 || p.Total != 7 {
+		t.Errorf("zombies/uninterruptible/total = %d/%d/%d", len(p.Zombies), len(p.Uninterruptible), p.Total)
+	}
+	if len(p.Orphans) != 0 {
+		t.Errorf("first check reported orphans: %+v", p.Orphans)
+	}
+	if _, ok := snap[960]; ok {
+		t.Error("idle unwatched daemon should not be tracked")
+	}
+
+	// 15 minutes later git is still in U with no CPU progress. xcodebuild
+	// itself used no CPU, but clang under it did; its parent shell exited
+	// and it was reparented to a subreaper.
+	later := now.Add(15 * time.Minute)
+	for i := range entries {
+		entries[i].AgeSec += 900
+	}
+	entries[3].PPID = 42
+	entries[4].CPUSec = 75
+	p, snap = analyzeProcesses(entries, snap, later, cfg)
+	if len(p.Stuck) != 1 || p.Stuck[0].PID != 812 || p.Stuck[0].StalledSec != 900 {
+		t.Fatalf("Stuck = %+v", p.Stuck)
+	}
+	if len(p.Orphans) != 1 || p.Orphans[0].PID != 950 || p.Orphans[0].Descendants != 2 {
+		t.Errorf("Orphans = %+v", p.Orphans)
+	}
+	if !snap[950].Orphaned {
+		t.Error("orphaned state should be kept in the snapshot")
+	}
+	if p.Status != StatusRed {
+		t.Errorf("Status = %v, want red for stuck uninterruptible process", p.Status)
+	}
+	if snap[812].Since != now.Unix() {
+		t.Errorf("snapshot Since = %d, want first-seen time", snap[812].Since)
+	}
+
+	// A reused PID (different start time) starts a fresh sample.
+	reused := []psEntry{{PID: 812, PPID: 500, State: "S", AgeSec: 5, CPUSec: 0.12, Command: "/usr/bin/git"}}
+	p, _ = analyzeProcesses(reused, snap, later, cfg)
+	if len(p.Stuck) != 0 || p.Status != StatusGreen {
+		t.Errorf("reused PID reported stuck: %+v", p)
+	}
+}
+
+func TestProcessSnapshotRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	if loadProcessSnapshot(dir) != nil {
+		t.Error("expected nil snapshot before first save")
+	}
+	saveProcessSnapshot(dir, map[int]processSample{812: {Start: 100, CPUSec: 0.5, Since: 200}})
+	got := loadProcessSnapshot(dir)
+	if got[812] != (processSample{Start: 100, CPUSec: 0.5, Since: 200}) {
+		t.Errorf("loadProcessSnapshot() = %+v", got)
+	}
+}
+
+func TestDiagnoseProcesses(t *testing.T) {
+	if DiagnoseProcesses(Processes{Status: StatusGreen}) != nil {
+		t.Error("expected nil for green")
+	}
+	p := Processes{
+		Status:  StatusRed,
+		Stuck:   []ProcessInfo{{PID: 812, Command: "/usr/bin/git", State: "U", AgeSec: 3600, StalledSec: 900}},
+		Zombies: []ProcessInfo{{PID: 900, Command: "git-remote-https", State: "Z", AgeSec: 3599}},
+	}
+	d := DiagnoseProcesses(p)
+	if d == nil {
+		t.Fatal("expected diagnosis")
+	}
+	if !strings.Contains(d.Detail, "git (pid 812, state U, age 1h00m)") || !strings.Contains(d.Detail, "1 zombie") {
+		t.Errorf("Detail = %q", d.Detail)
+	}
+	if !strings.Contains(d.Action, "reboot") {
+		t.Errorf("Action = %q", d.Action)
+	}
+
+	p = Processes{Status: StatusYellow, Zombies: make([]ProcessInfo, 12)}
+	if d := DiagnoseProcesses(p); d == nil || !strings.Contains(d.Summary, "Zombie") {
+		t.Errorf("zombie diagnosis = %+v", d)
+	}
+}
@@ -0,0 +1,236 @@
+package health
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PrometheusContentType is the content type of the text exposition format.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// promLabelFields are string fields that identify an element of a slice
+// (a Bluetooth device, a service, a certificate) and become its labels.
+var promLabelFields = map[string]bool{
+	"name": true, "id": true, "path": true, "process": true, "kind": true,
+	"interface": true, "source": true, "location": true, "manager": true,
+	"domain": true, "type": true, "identifier": true, "ip": true,
+}
+
+var (
+	promTimeType   = reflect.TypeOf(time.Time{})
+	promStatusType = reflect.TypeOf(StatusGreen)
+)
+
+// WritePrometheus writes r in the Prometheus text exposition format. Every
+// numeric and boolean field becomes a gauge named after its JSON path
+// (machealth_cpu_load_avg_1m, machealth_battery_cycle_count); elements of
+// slices are labelled by their identifying fields (name, path, ...). Status
+// values are exported as 0 (green), 1 (yellow) or 2 (red).
+func WritePrometheus(w io.Writer, r Report) error {
+	p := &promWriter{byName: map[string]*promFamily{}, seen: map[string]bool{}}
+
+	p.add("machealth_score", "Composite health score (0-100).", nil, float64(r.Score.Value))
+	p.add("machealth_score_status", "Overall status (0 green, 1 yellow, 2 red).", nil, statusSeverity(r.Score.Status))
+	if !r.Timestamp.IsZero() {
+		p.add("machealth_last_check_timestamp_seconds", "Unix time of the check.", nil, float64(r.Timestamp.UnixNano())/1e9)
+	}
+
+	v := reflect.ValueOf(r)
+	t := v.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name := promJSONName(f)
+		if name == "" || name == "timestamp" || name == "score" {
+			continue
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			if s := fv.FieldByName("Status"); s.IsValid() && s.Type() == promStatusType && s.String() != "" {
+				p.add("machealth_subsystem_status", "Subsystem status (0 green, 1 yellow, 2 red).",
+					[]promLabel{{"subsystem", name}}, statusSeverity(Status(s.String())))
+			}
+			p.walkStruct("machealth_"+name, name, fv, nil, true)
+			continue
+		}
+		p.walkValue("machealth_"+name, name, fv, nil)
+	}
+	return p.write(w)
+}
+
+// statusSeverity maps a status to 0 (green), 1 (yellow) or 2 (red).
+func statusSeverity(s Status) float64 {
+	switch s {
+	case StatusRed:
+		return 2
+	case StatusYellow:
+		return 1
+	default:
+		return 0
+	}
+}
+
+type promLabel struct{ name, value string }
+
+type promFamily struct {
+	name, help string
+	samples    []string
+}
+
+type promWriter struct {
+	families []*promFamily
+	byName   map[string]*promFamily
+	seen     map[string]bool // series already written, to drop duplicates
+}
+
+func promJSONName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// walkStruct exports the fields of v. The subsystem's own status is
+// exported as machealth_subsystem_status instead, so top skips it.
+func (p *promWriter) walkStruct(name, path string, v reflect.Value, labels []promLabel, top bool) {
+	t := v.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := promJSONName(f)
+		if tag == "" || top && tag == "status" {
+			continue
+		}
+		p.walkValue(name+"_"+tag, path+"."+tag, v.Field(i), labels)
+	}
+}
+
+func (p *promWriter) walkValue(name, path string, v reflect.Value, labels []promLabel) {
+	help := "Report field " + path + "."
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			p.walkValue(name, path, v.Elem(), labels)
+		}
+	case reflect.Bool:
+		value := 0.0
+		if v.Bool() {
+			value = 1
+		}
+		p.add(name, help+" (1 true, 0 false)", labels, value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		p.add(name, help, labels, float64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		p.add(name, help, labels, float64(v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		p.add(name, help, labels, v.Float())
+	case reflect.String:
+		if v.Type() == promStatusType && v.String() != "" {
+			p.add(name, help+" (0 green, 1 yellow, 2 red)", labels, statusSeverity(Status(v.String())))
+		}
+	case reflect.Struct:
+		if v.Type() != promTimeType {
+			p.walkStruct(name, path, v, labels, false)
+		}
+	case reflect.Slice:
+		elem := v.Type().Elem()
+		if elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct || elem == promTimeType {
+			return
+		}
+		for i := range v.Len() {
+			e := reflect.Indirect(v.Index(i))
+			p.walkStruct(name, path+"[]", e, append(labels[:len(labels):len(labels)], promElementLabels(e, labels, i)...), false)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			p.walkValue(name, path+"{}", v.MapIndex(k), append(labels[:len(labels):len(labels)], promLabel{"key", k.String()}))
+		}
+	}
+}
+
+// promElementLabels returns the identifying labels of slice element e,
+// falling back to its index. The label names depend only on the element
+// type, so every series of a family carries the same names; an empty field
+// is exported as "". Names already used by parent labels are skipped.
+func promElementLabels(e reflect.Value, parent []promLabel, index int) []promLabel {
+	used := map[string]bool{}
+	for _, l := range parent {
+		used[l.name] = true
+	}
+	var labels []promLabel
+	t := e.Type()
+	for i := range t.NumField() {
+		tag := promJSONName(t.Field(i))
+		fv := e.Field(i)
+		if !promLabelFields[tag] || used[tag] || fv.Kind() != reflect.String {
+			continue
+		}
+		labels = append(labels, promLabel{tag, fv.String()})
+	}
+	if len(labels) == 0 && !used["index"] {
+		labels = append(labels, promLabel{"index", strconv.Itoa(index)})
+	}
+	return labels
+}
+
+func (p *promWriter) add(name, help string, labels []promLabel, value float64) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, "%s=\"%s\"", l.name, promEscape(l.value))
+		}
+		b.WriteByte('}')
+	}
+	series := b.String()
+	if p.seen[series] {
+		return
+	}
+	p.seen[series] = true
+
+	fam, ok := p.byName[name]
+	if !ok {
+		fam = &promFamily{name: name, help: help}
+		p.byName[name] = fam
+		p.families = append(p.families, fam)
+	}
+	fam.samples = append(fam.samples, series+" "+strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func promEscape(s string) string {
+	return promEscaper.Replace(s)
+}
+
+func (p *promWriter) write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range p.families {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s gauge\n", f.name, f.help, f.name)
+		for _, s := range f.samples {
+			bw.WriteString(s)
+			bw.WriteByte('\n')
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,121 @@
+package health
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	code := 78
+	r := Report{
+		Timestamp: time.Unix(1700000000, 0).UTC(),
+		Score:     Score{Status: StatusYellow, Value: 87},
+		CPU:       CPU{Status: StatusGreen, LoadAvg1m: 2.5, LogicalCores: 10},
+		Memory:    Memory{Status: StatusYellow, PressurePercent: 35, SwapUsedMB: 512},
+		Battery:   Battery{Status: StatusGreen, Percent: 80, HealthPercent: 91.5, CycleCount: 312, Charging: true},
+		Bluetooth: Bluetooth{
+			Status: StatusGreen,
+			Devices: []BluetoothDevice{
+				{Name: `Jane's "AirPods"`, Connected: true, BatteryLeft: 70, BatteryRight: 65},
+				{Name: "Magic Keyboard", BatteryMain: 40},
+				{Name: "Magic Keyboard", BatteryMain: 41}, // duplicate series is dropped
+			},
+		},
+		Services: Services{
+			Status: StatusRed,
+			Services: []ServiceState{
+				{Name: "com.example.agent", Manager: "launchd", State: ServiceCrashLooping, Status: StatusRed, Runs: 37, LastExitCode: &code},
+				{Name: "com.example.helper", Manager: "launchd", Domain: "gui/501", State: ServiceRunning, Status: StatusGreen, Runs: 1},
+			},
+		},
+		Custom: []CustomResult{{Name: "license", Status: StatusYellow, Metrics: map[string]float64{"seats": 12, "latency_ms": 2100}}},
+	}
+
+	var b strings.Builder
+	if err := WritePrometheus(&b, r); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"# HELP machealth_score Composite health score (0-100).\n# TYPE machealth_score gauge\nmachealth_score 87\n",
+		"machealth_score_status 1\n",
+		"machealth_last_check_timestamp_seconds 1.7e+09\n",
+		`machealth_subsystem_status{subsystem="memory"} 1` + "\n",
+		`machealth_subsystem_status{subsystem="services"} 2` + "\n",
+		"machealth_cpu_load_avg_1m 2.5\n",
+		"machealth_memory_swap_used_mb 512\n",
+		"machealth_battery_health_percent 91.5\n",
+		"machealth_battery_cycle_count 312\n",
+		"machealth_battery_charging 1\n",
+		`machealth_bluetooth_devices_battery_left{name="Jane's \"AirPods\""} 70` + "\n",
+		`machealth_bluetooth_devices_battery_main{name="Magic Keyboard"} 40` + "\n",
+		`machealth_services_services_runs{name="com.example.agent",manager="launchd",domain=""} 37` + "\n",
+		`machealth_services_services_runs{name="com.example.helper",manager="launchd",domain="gui/501"} 1` + "\n",
+		`machealth_services_services_last_exit_code{name="com.example.agent",manager="launchd",domain=""} 78` + "\n",
+		`machealth_services_services_status{name="com.example.agent",manager="launchd",domain=""} 2` + "\n",
+		`machealth_custom_status{name="license"} 1` + "\n",
+		`machealth_custom_metrics{name="license",key="latency_ms"} 2100` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+	if strings.Contains(out, `battery_main{name="Magic Keyboard"} 41`) {
+		t.Error("duplicate series should be dropped")
+	}
+	if strings.Contains(out, "machealth_cpu_status") || strings.Contains(out, "machealth_timestamp") {
+		t.Error("subsystem status and timestamp should only be exported once")
+	}
+
+	// Every family has exactly one HELP and TYPE, directly before its samples,
+	// every sample line is well formed, and all samples of a family carry the
+	// same label names, as a Prometheus registry requires when gathering.
+	sampleRe := regexp.MustCompile(`^([a-z_][a-z0-9_]*)(\{[a-z_]+="(?:[^"\\]|\\.)*"(?:,[a-z_]+="(?:[^"\\]|\\.)*")*\})? -?[0-9.e+-]+$`)
+	labelNameRe := regexp.MustCompile(`([a-z_]+)="(?:[^"\\]|\\.)*"`)
+	seen := map[string]bool{}
+	labelNames := map[string]string{}
+	current := ""
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "# HELP "):
+			name := strings.Fields(line)[2]
+			if seen[name] {
+				t.Errorf("family %s declared twice", name)
+			}
+			seen[name] = true
+			current = name
+		case strings.HasPrefix(line, "# TYPE "):
+			if f := strings.Fields(line); f[2] != current || f[3] != "gauge" {
+				t.Errorf("bad TYPE line %q", line)
+			}
+		default:
+			m := sampleRe.FindStringSubmatch(line)
+			if m == nil {
+				t.Errorf("malformed sample %q", line)
+			} else if m[1] != current {
+				t.Errorf("sample %q outside its family %s", line, current)
+			} else {
+				var names []string
+				for _, lm := range labelNameRe.FindAllStringSubmatch(m[2], -1) {
+					names = append(names, lm[1])
+				}
+				got := strings.Join(names, ",")
+				if want, ok := labelNames[current]; ok && got != want {
+					t.Errorf("family %s mixes label names {%s} and {%s}", current, want, got)
+				}
+				labelNames[current] = got
+			}
+		}
+	}
+}
+
+func TestStatusSeverity(t *testing.T) {
+	for s, want := range map[Status]float64{StatusGreen: 0, StatusYellow: 1, StatusRed: 2, "": 0} {
+		if got := statusSeverity(s); got != want {
+			t.Errorf("statusSeverity(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
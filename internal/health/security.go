@@ -0,0 +1,166 @@
+package health
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// Security control states.
+const (
+	ControlEnabled  = "enabled"
+	ControlDisabled = "disabled"
+	ControlUnknown  = "unknown"
+)
+
+// CheckSecurity collects the state of FileVault, System Integrity Protection,
+// Gatekeeper and the application firewall. FileVault and SIP being off is
+// red; Gatekeeper or the firewall being off is yellow. Controls whose state
+// cannot be read are reported as unknown and do not affect status.
+func CheckSecurity() Security {
+	s := Security{Status: StatusGreen}
+
+	s.FileVault = runSecurityControl(parseFileVault, "/usr/bin/fdesetup", "status")
+	s.SIP = runSecurityControl(parseCSRUtil, "/usr/bin/csrutil", "status")
+	s.Gatekeeper = runSecurityControl(parseSpctl, "/usr/sbin/spctl", "--status")
+	s.Firewall = runSecurityControl(parseFirewall, "/usr/libexec/ApplicationFirewall/socketfilterfw", "--getglobalstate")
+
+	if s.Gatekeeper.State == ControlDisabled || s.Firewall.State == ControlDisabled {
+		s.Status = StatusYellow
+	}
+	if s.FileVault.State == ControlDisabled || s.SIP.State == ControlDisabled {
+		s.Status = StatusRed
+	}
+
+	return s
+}
+
+func runSecurityControl(parse func(string) SecurityControl, name string, args ...string) SecurityControl {
+	// spctl exits 1 when assessments are disabled, so keep output on error.
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil && len(out) == 0 {
+		return SecurityControl{State: ControlUnknown}
+	}
+	return parse(string(out))
+}
+
+func newSecurityControl(state, detail string) SecurityControl {
+	return SecurityControl{
+		State:   state,
+		Enabled: state == ControlEnabled,
+		Detail:  strings.TrimSpace(detail),
+	}
+}
+
+var (
+	fileVaultRe  = regexp.MustCompile(`FileVault is (On|Off)`)
+	fvProgressRe = regexp.MustCompile(`(Encryption|Decryption) in progress`)
+	csrutilRe    = regexp.MustCompile(`System Integrity Protection status:\s*(\w+)`)
+	firewallRe   = regexp.MustCompile(`\(State = (\d)\)`)
+)
+
+// parseFileVault parses `fdesetup status`, e.g. "FileVault is On." or
+// "FileVault is Off.\nEncryption in progress: Percent completed = 45".
+// Encryption in progress counts as enabled; decryption in progress does not.
+func parseFileVault(s string) SecurityControl {
+	m := fileVaultRe.FindStringSubmatch(s)
+	if len(m) < 2 {
+		return newSecurityControl(ControlUnknown, s)
+	}
+	state := ControlDisabled
+	if m[1] == "On" {
+		state = ControlEnabled
+	}
+	if p := fvProgressRe.FindStringSubmatch(s); len(p) >= 2 {
+		if p[1] == "Encryption" {
+			state = ControlEnabled
+		} else {
+			state = ControlDisabled
+		}
+	}
+	return newSecurityControl(state, s)
+}
+
+// parseCSRUtil parses `csrutil status`, e.g.
+// "System Integrity Protection status: enabled." A custom configuration
+// ("unknown (Custom Configuration)") has some protections off and counts as disabled.
+func parseCSRUtil(s string) SecurityControl {
+	m := csrutilRe.FindStringSubmatch(s)
+	if len(m) < 2 {
+		return newSecurityControl(ControlUnknown, s)
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	switch m[1] {
+	case "enabled":
+		return newSecurityControl(ControlEnabled, line)
+	default:
+		return newSecurityControl(ControlDisabled, line)
+	}
+}
+
+// parseSpctl parses `spctl --status`: "assessments enabled" or "assessments disabled".
+func parseSpctl(s string) SecurityControl {
+	switch {
+	case strings.Contains(s, "assessments enabled"):
+		return newSecurityControl(ControlEnabled, s)
+	case strings.Contains(s, "assessments disabled"):
+		return newSecurityControl(ControlDisabled, s)
+	default:
+		return newSecurityControl(ControlUnknown, s)
+	}
+}
+
+// parseFirewall parses `socketfilterfw --getglobalstate`, e.g.
+// "Firewall is enabled. (State = 1)". State 2 (block all incoming) is enabled.
+func parseFirewall(s string) SecurityControl {
+	m := firewallRe.FindStringSubmatch(s)
+	if len(m) < 2 {
+		return newSecurityControl(ControlUnknown, s)
+	}
+	if m[1] == "0" {
+		return newSecurityControl(ControlDisabled, s)
+	}
+	return newSecurityControl(ControlEnabled, s)
+}
+
+// DiagnoseSecurity returns one diagnosis per disabled control, each with
+// the exact steps to re-enable it.
+func DiagnoseSecurity(s Security) []Diagnosis {
+	if s.Status == StatusGreen {
+		return nil
+	}
+
+	var diags []Diagnosis
+	add := func(c SecurityControl, sev Status, name, summary, action string) {
+		if c.State != ControlDisabled {
+			return
+		}
+		detail := fmt.Sprintf("%s is disabled.", name)
+		if c.Detail != "" {
+			detail += " Reported: " + c.Detail
+		}
+		diags = append(diags, Diagnosis{
+			Subsystem: "security",
+			Severity:  sev,
+			Summary:   summary,
+			Detail:    detail,
+			Action:    action,
+		})
+	}
+
+	add(s.FileVault, StatusRed, "FileVault disk encryption",
+		"FileVault is off — disk contents are not encrypted at rest",
+		"Enable FileVault in System Settings > Privacy & Security > FileVault, or run 'sudo fdesetup enable' and store the recovery key safely")
+	add(s.SIP, StatusRed, "System Integrity Protection",
+		"System Integrity Protection is disabled",
+		"Start up in macOS Recovery, open Terminal, run 'csrutil enable', then restart")
+	add(s.Gatekeeper, StatusYellow, "Gatekeeper",
+		"Gatekeeper app assessments are disabled",
+		"Run 'sudo spctl --global-enable' ('sudo spctl --master-enable' on macOS 14 and earlier)")
+	add(s.Firewall, StatusYellow, "The application firewall",
+		"Application firewall is off",
+		"Run 'sudo /usr/libexec/ApplicationFirewall/socketfilterfw --setglobalstate on', or enable it in System Settings > Network > Firewall")
+
+	return diags
+}
@@ -0,0 +1,72 @@
+package health
+
+import "testing"
+
+func TestParseSecurityControls(t *testing.T) {
+	tests := []struct {
+		name  string
+		parse func(string) SecurityControl
+		input string
+		want  string
+	}{
+		{"filevault on", parseFileVault, "FileVault is On.\n", ControlEnabled},
+		{"filevault off", parseFileVault, "FileVault is Off.\n", ControlDisabled},
+		{"filevault encrypting", parseFileVault, "FileVault is Off.\nEncryption in progress: Percent completed = 45\n", ControlEnabled},
+		{"filevault decrypting", parseFileVault, "FileVault is On.\nDecryption in progress: Percent completed = 10\n", ControlDisabled},
+		{"filevault garbage", parseFileVault, "fdesetup: command not found", ControlUnknown},
+		{"sip enabled", parseCSRUtil, "System Integrity Protection status: enabled.\n", ControlEnabled},
+		{"sip disabled", parseCSRUtil, "System Integrity Protection status: disabled.\n", ControlDisabled},
+		{"sip custom", parseCSRUtil, "System Integrity Protection status: unknown (Custom Configuration).\n\nConfiguration:\n\tKext Signing: disabled\n", ControlDisabled},
+		{"sip empty", parseCSRUtil, "", ControlUnknown},
+		{"gatekeeper enabled", parseSpctl, "assessments enabled\n", ControlEnabled},
+		{"gatekeeper disabled", parseSpctl, "assessments disabled\n", ControlDisabled},
+		{"gatekeeper empty", parseSpctl, "", ControlUnknown},
+		{"firewall on", parseFirewall, "Firewall is enabled. (State = 1)\n", ControlEnabled},
+		{"firewall block all", parseFirewall, "Firewall is blocking all non-essential incoming connections (State = 2)\n", ControlEnabled},
+		{"firewall off", parseFirewall, "Firewall is disabled. (State = 0)\n", ControlDisabled},
+		{"firewall empty", parseFirewall, "", ControlUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.parse(tt.input)
+			if c.State != tt.want {
+				t.Errorf("state = %s, want %s", c.State, tt.want)
+			}
+			if c.Enabled != (tt.want == ControlEnabled) {
+				t.Errorf("Enabled = %v for state %s", c.Enabled, c.State)
+			}
+		})
+	}
+}
+
+func TestDiagnoseSecurity(t *testing.T) {
+	enabled := SecurityControl{State: ControlEnabled, Enabled: true}
+	disabled := SecurityControl{State: ControlDisabled}
+
+	if d := DiagnoseSecurity(Security{Status: StatusGreen, FileVault: enabled, SIP: enabled, Gatekeeper: enabled, Firewall: enabled}); d != nil {
+		t.Errorf("expected no diagnoses for green status, got %v", d)
+	}
+
+	diags := DiagnoseSecurity(Security{
+		Status:     StatusRed,
+		FileVault:  disabled,
+		SIP:        enabled,
+		Gatekeeper: SecurityControl{State: ControlUnknown},
+		Firewall:   disabled,
+	})
+	if len(diags) != 2 {
+		t.Fatalf("len(diags) = %d, want 2", len(diags))
+	}
+	if diags[0].Severity != StatusRed || diags[0].Subsystem != "security" {
+		t.Errorf("FileVault diagnosis = %+v", diags[0])
+	}
+	if diags[1].Severity != StatusYellow {
+		t.Errorf("firewall severity = %s, want yellow", diags[1].Severity)
+	}
+	for _, d := range diags {
+		if d.Action == "" {
+			t.Errorf("diagnosis %q has no remediation", d.Summary)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package health
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Service states.
+const (
+	ServiceRunning      = "running"
+	ServiceIdle         = "idle" // not running, but last exited cleanly or never ran
+	ServiceStopped      = "stopped"
+	ServiceCrashLooping = "crash-looping"
+	ServiceNotFound     = "not-found"
+)
+
+// CheckServices reports the state of the configured launchd labels (macOS)
+// or systemd units (Linux). Each service gets its own status: running or
+// idle (an on-demand job, oneshot or timer that last exited cleanly) is
+// green, not found is yellow, stopped after a failure or crash-looping is
+// red. The subsystem takes the worst.
+func CheckServices(cfg ServicesConfig) Services {
+	s := Services{Status: StatusGreen}
+
+	if runtime.GOOS == "linux" {
+		for _, unit := range cfg.Systemd {
+			s.Services = append(s.Services, checkSystemdUnit(unit, cfg.CrashLoopRestarts))
+		}
+	} else {
+		uid := os.Getuid()
+		for _, label := range cfg.Launchd {
+			s.Services = append(s.Services, checkLaunchdLabel(label, uid, cfg.CrashLoopRestarts))
+		}
+	}
+
+	for _, svc := range s.Services {
+		if svc.Status == StatusRed || (svc.Status == StatusYellow && s.Status == StatusGreen) {
+			s.Status = svc.Status
+		}
+	}
+	return s
+}
+
+// checkLaunchdLabel looks the label up in the user's GUI domain, then the
+// system domain, falling back to `launchctl list` on systems where
+// `launchctl print` is unavailable.
+func checkLaunchdLabel(label string, uid, crashLoopRuns int) ServiceState {
+	for _, domain := range []string{fmt.Sprintf("gui/%d", uid), "system"} {
+		out, err := exec.Command("/bin/launchctl", "print", domain+"/"+label).Output()
+		if err == nil {
+			svc := parseLaunchctlPrint(string(out), crashLoopRuns)
+			svc.Name, svc.Manager, svc.Domain = label, "launchd", domain
+			return svc
+		}
+	}
+	if out, err := exec.Command("/bin/launchctl", "list", label).Output(); err == nil {
+		svc := parseLaunchctlList(string(out))
+		svc.Name, svc.Manager = label, "launchd"
+		return svc
+	}
+	return ServiceState{Name: label, Manager: "launchd", State: ServiceNotFound, Status: StatusYellow}
+}
+
+var launchctlExitRe = regexp.MustCompile(`^(-?\d+)`)
+
+// parseLaunchctlPrint parses the top-level "key = value" lines of
+// `launchctl print <domain>/<label>`:
+//
+//	state = running
+//	runs = 3
+//	pid = 1234
+//	last exit code = 78: EX_CONFIG
+//
+// A service that is not running, has run more than once and last exited
+// non-zero is crash-looping; so is a running one that has been started
+// crashLoopRuns times with a non-zero last exit. One that is not running
+// but never exited or last exited 0 is an idle on-demand job.
+func parseLaunchctlPrint(s string, crashLoopRuns int) ServiceState {
+	var svc ServiceState
+	state := ""
+	for _, line := range strings.Split(s, "\n") {
+		// Only the service's own properties: one tab deep, not nested blocks.
+		if !strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "\t\t") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimSpace(line), " = ")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "state":
+			state = value
+		case "pid":
+			svc.PID, _ = strconv.Atoi(value)
+		case "runs":
+			svc.Runs, _ = strconv.Atoi(value)
+		case "last exit code":
+			if m := launchctlExitRe.FindStringSubmatch(value); len(m) >= 2 {
+				code, _ := strconv.Atoi(m[1])
+				svc.LastExitCode = &code
+			}
+			if value != "(never exited)" {
+				svc.Detail = "last exit " + value
+			}
+		case "last terminating signal":
+			svc.Detail = "last signal " + value
+		}
+	}
+
+	failed := svc.LastExitCode != nil && *svc.LastExitCode != 0 || strings.HasPrefix(svc.Detail, "last signal")
+	running := state == "running" || svc.PID > 0
+	switch {
+	case !running && failed && svc.Runs > 1:
+		svc.State = ServiceCrashLooping
+	case running && failed && crashLoopRuns > 0 && svc.Runs >= crashLoopRuns:
+		svc.State = ServiceCrashLooping
+	case running:
+		svc.State = ServiceRunning
+	case !failed:
+		svc.State = ServiceIdle
+	default:
+		svc.State = ServiceStopped
+	}
+	svc.Status = serviceStatus(svc.State)
+	return svc
+}
+
+var (
+	launchctlListPIDRe    = regexp.MustCompile(`"PID"\s*=\s*(\d+);`)
+	launchctlListStatusRe = regexp.MustCompile(`"LastExitStatus"\s*=\s*(-?\d+);`)
+)
+
+// parseLaunchctlList parses the plist-style output of `launchctl list <label>`.
+// LastExitStatus is a wait(2) status, so the exit code is in the high byte.
+func parseLaunchctlList(s string) ServiceState {
+	var svc ServiceState
+	if m := launchctlListPIDRe.FindStringSubmatch(s); len(m) >= 2 {
+		svc.PID, _ = strconv.Atoi(m[1])
+	}
+	if m := launchctlListStatusRe.FindStringSubmatch(s); len(m) >= 2 {
+		status, _ := strconv.Atoi(m[1])
+		code := status >> 8
+		if status&0x7f != 0 {
+			svc.Detail = fmt.Sprintf("last signal %d", status&0x7f)
+			code = 128 + status&0x7f
+		}
+		svc.LastExitCode = &code
+	}
+	switch {
+	case svc.PID > 0:
+		svc.State = ServiceRunning
+	case svc.LastExitCode == nil || *svc.LastExitCode == 0:
+		svc.State = ServiceIdle
+	default:
+		svc.State = ServiceStopped
+	}
+	svc.Status = serviceStatus(svc.State)
+	return svc
+}
+
+// checkSystemdUnit queries a system unit, or a user unit when prefixed with "user:".
+func checkSystemdUnit(unit string, crashLoopRestarts int) ServiceState {
+	args := []string{"show", "-p", "LoadState", "-p", "ActiveState", "-p", "SubState",
+		"-p", "MainPID", "-p", "NRestarts", "-p", "ExecMainStatus", "-p", "Result"}
+	name, domain := unit, "system"
+	if u, ok := strings.CutPrefix(unit, "user:"); ok {
+		name, domain = u, "user"
+		args = append([]string{"--user"}, args...)
+	}
+	svc := ServiceState{Name: unit, Manager: "systemd", Domain: domain}
+	out, err := exec.Command("/usr/bin/systemctl", append(args, name)...).Output()
+	if err != nil {
+		svc.State, svc.Status = ServiceNotFound, StatusYellow
+		svc.Detail = err.Error()
+		return svc
+	}
+	parsed := parseSystemctlShow(string(out), crashLoopRestarts)
+	parsed.Name, parsed.Manager, parsed.Domain = svc.Name, svc.Manager, svc.Domain
+	return parsed
+}
+
+// parseSystemctlShow parses `systemctl show -p ...` key=value output. A unit
+// auto-restarting, or running after crashLoopRestarts restarts, is
+// crash-looping; an inactive one whose last run succeeded (a oneshot or a
+// timer's service between runs) is idle.
+func parseSystemctlShow(s string, crashLoopRestarts int) ServiceState {
+	props := map[string]string{}
+	for _, line := range strings.Split(s, "\n") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			props[k] = v
+		}
+	}
+
+	var svc ServiceState
+	svc.PID, _ = strconv.Atoi(props["MainPID"])
+	svc.Runs, _ = strconv.Atoi(props["NRestarts"])
+	if v, ok := props["ExecMainStatus"]; ok {
+		code, _ := strconv.Atoi(v)
+		svc.LastExitCode = &code
+	}
+	svc.Detail = strings.TrimSpace(props["ActiveState"] + "/" + props["SubState"])
+	if r := props["Result"]; r != "" && r != "success" {
+		svc.Detail += ", result " + r
+	}
+
+	switch {
+	case props["LoadState"] == "not-found":
+		svc.State = ServiceNotFound
+	case props["SubState"] == "auto-restart":
+		svc.State = ServiceCrashLooping
+	case props["ActiveState"] == "active" && crashLoopRestarts > 0 && svc.Runs >= crashLoopRestarts:
+		svc.State = ServiceCrashLooping
+	case props["ActiveState"] == "active", props["ActiveState"] == "reloading":
+		svc.State = ServiceRunning
+	case props["ActiveState"] == "inactive" && props["Result"] == "success":
+		svc.State = ServiceIdle
+	default:
+		svc.State = ServiceStopped
+	}
+	svc.Status = serviceStatus(svc.State)
+	return svc
+}
+
+func serviceStatus(state string) Status {
+	switch state {
+	case ServiceRunning, ServiceIdle:
+		return StatusGreen
+	case ServiceNotFound:
+		return StatusYellow
+	default:
+		return StatusRed
+	}
+}
+
+// DiagnoseServices returns one diagnosis per service that is not running.
+func DiagnoseServices(s Services) []Diagnosis {
+	if s.Status == StatusGreen {
+		return nil
+	}
+
+	var diags []Diagnosis
+	for _, svc := range s.Services {
+		if svc.Status == StatusGreen {
+			continue
+		}
+		d := Diagnosis{
+			Subsystem: "services",
+			Severity:  svc.Status,
+		}
+		detail := fmt.Sprintf("%s service %s is %s", svc.Manager, svc.Name, svc.State)
+		if svc.Runs > 0 {
+			detail += fmt.Sprintf(" after %d start(s)", svc.Runs)
+		}
+		if svc.Detail != "" {
+			detail += " (" + svc.Detail + ")"
+		}
+		d.Detail = detail + "."
+
+		// launchctl takes <domain>/<label>; systemctl and journalctl need
+		// --user for user units.
+		target, systemctl, journalctl := svc.Name, "systemctl", "journalctl"
+		if svc.Manager == "launchd" && svc.Domain != "" {
+			target = svc.Domain + "/" + svc.Name
+		}
+		if unit, ok := strings.CutPrefix(svc.Name, "user:"); ok {
+			target, systemctl, journalctl = unit, "systemctl --user", "journalctl --user"
+		}
+		switch svc.State {
+		case ServiceNotFound:
+			d.Summary = fmt.Sprintf("Service %s is not installed", svc.Name)
+			if svc.Manager == "launchd" {
+				d.Action = "Check the label in the config, or load it with 'launchctl bootstrap gui/$(id -u) <plist>'"
+			} else {
+				d.Action = "Check the unit name in the config ('systemctl list-unit-files')"
+			}
+		case ServiceCrashLooping:
+			d.Summary = fmt.Sprintf("Service %s is crash-looping", svc.Name)
+			if svc.Manager == "launchd" {
+				d.Action = fmt.Sprintf("Inspect its logs ('launchctl print %s' shows the stdout/stderr paths) and fix the cause before restarting with 'launchctl kickstart -k %s'", target, target)
+			} else {
+				d.Action = fmt.Sprintf("Inspect '%s status %s' and '%s -u %s -n 50', and fix the cause before '%s restart %s'", systemctl, target, journalctl, target, systemctl, target)
+			}
+		default:
+			d.Summary = fmt.Sprintf("Service %s is not running", svc.Name)
+			if svc.Manager == "launchd" {
+				d.Action = fmt.Sprintf("Start it with 'launchctl kickstart %s'", target)
+			} else {
+				d.Action = fmt.Sprintf("Start it with '%s start %s'", systemctl, target)
+			}
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
@@ -0,0 +1,199 @@
+package health
+
+import (
+	"strings"
+	"testing"
+)
+
+const launchctlPrintRunning = `gui/501/com.example.agent = {
+	active count = 1
+	path = /Users/dev/Library/LaunchAgents/com.example.agent.plist
+	type = LaunchAgent
+	state = running
+
+	program = /usr/local/bin/agent
+	arguments = {
+		/usr/local/bin/agent
+		--serve
+	}
+
+	runs = 1
+	pid = 4242
+	immediate reason = speculative
+	forks = 0
+	execs = 1
+	last exit code = (never exited)
+}
+`
+
+const launchctlPrintCrashing = `gui/501/com.example.agent = {
+	active count = 0
+	path = /Users/dev/Library/LaunchAgents/com.example.agent.plist
+	type = LaunchAgent
+	state = spawn scheduled
+
+	program = /usr/local/bin/agent
+	environment = {
+		state = running
+	}
+
+	runs = 37
+	last exit code = 78: EX_CONFIG
+	spawn type = daemon (3)
+}
+`
+
+func TestParseLaunchctlPrint(t *testing.T) {
+	svc := parseLaunchctlPrint(launchctlPrintRunning, 5)
+	if svc.State != ServiceRunning || svc.Status != StatusGreen || svc.PID != 4242 || svc.Runs != 1 {
+		t.Errorf("running = %+v", svc)
+	}
+	if svc.LastExitCode != nil || svc.Detail != "" {
+		t.Errorf("never exited: code %v, detail %q", svc.LastExitCode, svc.Detail)
+	}
+
+	svc = parseLaunchctlPrint(launchctlPrintCrashing, 5)
+	if svc.State != ServiceCrashLooping || svc.Status != StatusRed || svc.Runs != 37 {
+		t.Errorf("crashing = %+v", svc)
+	}
+	if svc.LastExitCode == nil || *svc.LastExitCode != 78 || svc.Detail != "last exit 78: EX_CONFIG" {
+		t.Errorf("crashing exit = %v, %q", svc.LastExitCode, svc.Detail)
+	}
+}
+
+func TestParseLaunchctlPrint_StoppedAndRestarting(t *testing.T) {
+	stopped := "svc = {\n\tstate = not running\n\truns = 1\n\tlast exit code = 1\n}\n"
+	if svc := parseLaunchctlPrint(stopped, 5); svc.State != ServiceStopped || svc.Status != StatusRed {
+		t.Errorf("stopped = %+v", svc)
+	}
+
+	// On-demand jobs such as com.openssh.ssh-agent sit idle between uses.
+	for _, idle := range []string{
+		"svc = {\n\tstate = not running\n\truns = 4\n\tlast exit code = 0\n}\n",
+		"svc = {\n\tstate = not running\n\truns = 0\n\tlast exit code = (never exited)\n}\n",
+	} {
+		if svc := parseLaunchctlPrint(idle, 5); svc.State != ServiceIdle || svc.Status != StatusGreen {
+			t.Errorf("idle = %+v", svc)
+		}
+	}
+
+	// Running again, but it has been restarted many times after failures.
+	restarting := "svc = {\n\tstate = running\n\tpid = 99\n\truns = 6\n\tlast exit code = 1\n}\n"
+	if svc := parseLaunchctlPrint(restarting, 5); svc.State != ServiceCrashLooping {
+		t.Errorf("restarting = %+v", svc)
+	}
+	if svc := parseLaunchctlPrint(restarting, 0); svc.State != ServiceRunning {
+		t.Errorf("restarting with threshold disabled = %+v", svc)
+	}
+
+	signalled := "svc = {\n\tstate = not running\n\truns = 3\n\tlast terminating signal = Killed: 9\n}\n"
+	if svc := parseLaunchctlPrint(signalled, 5); svc.State != ServiceCrashLooping || svc.Detail != "last signal Killed: 9" {
+		t.Errorf("signalled = %+v", svc)
+	}
+}
+
+func TestParseLaunchctlList(t *testing.T) {
+	running := `{
+	"LimitLoadToSessionType" = "Aqua";
+	"Label" = "com.example.agent";
+	"OnDemand" = false;
+	"LastExitStatus" = 0;
+	"PID" = 4242;
+	"Program" = "/usr/local/bin/agent";
+};`
+	svc := parseLaunchctlList(running)
+	if svc.State != ServiceRunning || svc.PID != 4242 || svc.LastExitCode == nil || *svc.LastExitCode != 0 {
+		t.Errorf("running = %+v", svc)
+	}
+
+	// 19968 = exit status 78 << 8.
+	stopped := `{
+	"Label" = "com.example.agent";
+	"LastExitStatus" = 19968;
+};`
+	svc = parseLaunchctlList(stopped)
+	if svc.State != ServiceStopped || svc.Status != StatusRed || *svc.LastExitCode != 78 {
+		t.Errorf("stopped = %+v, code %d", svc, *svc.LastExitCode)
+	}
+
+	idle := parseLaunchctlList(`{ "Label" = "com.openssh.ssh-agent"; "LastExitStatus" = 0; };`)
+	if idle.State != ServiceIdle || idle.Status != StatusGreen {
+		t.Errorf("idle = %+v", idle)
+	}
+
+	killed := parseLaunchctlList(`{ "LastExitStatus" = 9; };`)
+	if *killed.LastExitCode != 137 || killed.Detail != "last signal 9" {
+		t.Errorf("killed = %+v", killed)
+	}
+}
+
+func TestParseSystemctlShow(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		state  string
+		status Status
+	}{
+		{"running", "LoadState=loaded\nActiveState=active\nSubState=running\nMainPID=812\nNRestarts=0\nExecMainStatus=0\nResult=success\n", ServiceRunning, StatusGreen},
+		{"auto-restart", "LoadState=loaded\nActiveState=activating\nSubState=auto-restart\nMainPID=0\nNRestarts=12\nExecMainStatus=1\nResult=exit-code\n", ServiceCrashLooping, StatusRed},
+		{"many restarts", "LoadState=loaded\nActiveState=active\nSubState=running\nMainPID=900\nNRestarts=8\nExecMainStatus=1\nResult=success\n", ServiceCrashLooping, StatusRed},
+		{"failed", "LoadState=loaded\nActiveState=failed\nSubState=failed\nMainPID=0\nNRestarts=0\nExecMainStatus=203\nResult=exit-code\n", ServiceStopped, StatusRed},
+		{"oneshot done", "LoadState=loaded\nActiveState=inactive\nSubState=dead\nMainPID=0\nNRestarts=0\nExecMainStatus=0\nResult=success\n", ServiceIdle, StatusGreen},
+		{"inactive after failure", "LoadState=loaded\nActiveState=inactive\nSubState=dead\nMainPID=0\nNRestarts=0\nExecMainStatus=1\nResult=exit-code\n", ServiceStopped, StatusRed},
+		{"not found", "LoadState=not-found\nActiveState=inactive\nSubState=dead\nMainPID=0\n", ServiceNotFound, StatusYellow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := parseSystemctlShow(tt.input, 5)
+			if svc.State != tt.state || svc.Status != tt.status {
+				t.Errorf("got %s/%s, want %s/%s (%+v)", svc.State, svc.Status, tt.state, tt.status, svc)
+			}
+		})
+	}
+
+	svc := parseSystemctlShow(tests[3].input, 5)
+	if svc.LastExitCode == nil || *svc.LastExitCode != 203 || svc.Detail != "failed/failed, result exit-code" {
+		t.Errorf("failed detail = %v, %q", svc.LastExitCode, svc.Detail)
+	}
+}
+
+func TestCheckServices_NoneConfigured(t *testing.T) {
+	s := CheckServices(ServicesConfig{})
+	if s.Status != StatusGreen || len(s.Services) != 0 {
+		t.Errorf("CheckServices() = %+v", s)
+	}
+}
+
+func TestDiagnoseServices(t *testing.T) {
+	if d := DiagnoseServices(Services{Status: StatusGreen}); d != nil {
+		t.Errorf("expected nil for green, got %+v", d)
+	}
+
+	s := Services{
+		Status: StatusRed,
+		Services: []ServiceState{
+			{Name: "com.example.ok", Manager: "launchd", Domain: "gui/501", State: ServiceRunning, Status: StatusGreen},
+			{Name: "com.example.agent", Manager: "launchd", Domain: "gui/501", State: ServiceCrashLooping, Status: StatusRed, Runs: 37, Detail: "last exit 78: EX_CONFIG"},
+			{Name: "user:ssh-agent.service", Manager: "systemd", Domain: "user", State: ServiceStopped, Status: StatusRed},
+			{Name: "missing.service", Manager: "systemd", Domain: "system", State: ServiceNotFound, Status: StatusYellow},
+			{Name: "user:syncthing.service", Manager: "systemd", Domain: "user", State: ServiceCrashLooping, Status: StatusRed, Runs: 12},
+		},
+	}
+	diags := DiagnoseServices(s)
+	if len(diags) != 4 {
+		t.Fatalf("got %d diagnoses, want 4", len(diags))
+	}
+	if d := diags[0]; d.Severity != StatusRed || !strings.Contains(d.Summary, "crash-looping") ||
+		!strings.Contains(d.Detail, "37 start(s)") || !strings.Contains(d.Action, "kickstart -k gui/501/com.example.agent") {
+		t.Errorf("crash-loop diagnosis = %+v", d)
+	}
+	if d := diags[1]; !strings.Contains(d.Action, "systemctl --user start ssh-agent.service") {
+		t.Errorf("stopped diagnosis = %+v", d)
+	}
+	if d := diags[2]; d.Severity != StatusYellow || !strings.Contains(d.Summary, "not installed") {
+		t.Errorf("not-found diagnosis = %+v", d)
+	}
+	if d := diags[3]; !strings.Contains(d.Action, "'journalctl --user -u syncthing.service -n 50'") {
+		t.Errorf("user crash-loop diagnosis = %+v", d)
+	}
+}
@@ -0,0 +1,205 @@
+package health
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// panicReportDirs are where macOS writes kernel panic reports.
+var panicReportDirs = []string{"/Library/Logs/DiagnosticReports"}
+
+// CheckStability collects boot time, uptime, the last shutdown cause and
+// recent kernel panics. A panic inside the configured window is red; an
+// uptime beyond the configured maximum is yellow.
+func CheckStability(cfg StabilityConfig) Stability {
+	st := Stability{Status: StatusGreen}
+
+	boot, ok := bootTime()
+	if !ok {
+		st.Error = "failed to determine boot time"
+		return st
+	}
+	st.BootTime = boot
+	st.UptimeSec = int64(time.Since(boot).Seconds())
+
+	if runtime.GOOS == "darwin" {
+		// The shutdown cause only changes on reboot, so cache it per boot and
+		// look it up in the background: `log show` takes seconds.
+		dir := cacheDir()
+		name := fmt.Sprintf("shutdown-cause-%d.txt", boot.Unix())
+		data, fetched, _ := readCache(dir, name, time.Duration(math.MaxInt64))
+		if fetched.IsZero() {
+			start := boot.Local()
+			refreshCacheAsync(dir, name, "/usr/bin/log", "show", "--style", "compact",
+				"--predicate", `eventMessage CONTAINS "Previous shutdown cause"`,
+				"--start", start.Format("2006-01-02 15:04:05"),
+				"--end", start.Add(15*time.Minute).Format("2006-01-02 15:04:05"))
+		} else if code, ok := parseShutdownCause(string(data)); ok {
+			st.LastShutdownCause = &code
+			st.LastShutdownReason = shutdownCauseReason(code)
+		}
+
+		window := time.Duration(cfg.PanicWindow)
+		st.RecentPanics = scanPanicReports(panicReportDirs, time.Now().Add(-window))
+		st.PanicCount = len(st.RecentPanics)
+	}
+
+	if maxUptime := time.Duration(cfg.MaxUptime); maxUptime > 0 && time.Duration(st.UptimeSec)*time.Second > maxUptime {
+		st.Status = StatusYellow
+	}
+	if st.PanicCount > 0 {
+		st.Status = StatusRed
+	}
+
+	return st
+}
+
+// bootTime returns when the system booted: kern.boottime on macOS, the btime
+// line of /proc/stat on Linux.
+func bootTime() (time.Time, bool) {
+	if runtime.GOOS == "linux" {
+		data, err := os.ReadFile("/proc/stat")
+		if err != nil {
+			return time.Time{}, false
+		}
+		return parseProcStatBootTime(string(data))
+	}
+	out, err := exec.Command("/usr/sbin/sysctl", "-n", "kern.boottime").Output()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return parseBootTime(string(out))
+}
+
+var (
+	bootTimeRe      = regexp.MustCompile(`sec\s*=\s*(\d+)`)
+	procBtimeRe     = regexp.MustCompile(`(?m)^btime\s+(\d+)`)
+	shutdownCauseRe = regexp.MustCompile(`Previous shutdown cause:\s*(-?\d+)`)
+)
+
+// parseBootTime parses `sysctl -n kern.boottime`:
+// "{ sec = 1739870000, usec = 123456 } Tue Feb 18 09:13:20 2026".
+func parseBootTime(s string) (time.Time, bool) {
+	m := bootTimeRe.FindStringSubmatch(s)
+	if len(m) < 2 {
+		return time.Time{}, false
+	}
+	sec, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil || sec <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, 0).UTC(), true
+}
+
+// parseProcStatBootTime parses the "btime 1739870000" line of /proc/stat.
+func parseProcStatBootTime(s string) (time.Time, bool) {
+	m := procBtimeRe.FindStringSubmatch(s)
+	if len(m) < 2 {
+		return time.Time{}, false
+	}
+	sec, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil || sec <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, 0).UTC(), true
+}
+
+// parseShutdownCause finds the last "Previous shutdown cause: N" in `log show` output.
+func parseShutdownCause(s string) (int, bool) {
+	all := shutdownCauseRe.FindAllStringSubmatch(s, -1)
+	if len(all) == 0 {
+		return 0, false
+	}
+	code, err := strconv.Atoi(all[len(all)-1][1])
+	return code, err == nil
+}
+
+// shutdownCauses maps well-known AppleSMC shutdown cause codes.
+var shutdownCauses = map[int]string{
+	5:    "normal shutdown or restart",
+	3:    "forced shutdown (power button held)",
+	0:    "power lost",
+	-2:   "power supply failure",
+	-3:   "multiple temperature sensors exceeded limit",
+	-60:  "bad master directory block (disk)",
+	-62:  "watchdog timer (system hang)",
+	-64:  "kernel panic",
+	-74:  "battery temperature exceeded limit",
+	-86:  "proximity temperature exceeded limit",
+	-95:  "CPU temperature exceeded limit",
+	-100: "power supply temperature exceeded limit",
+	-103: "battery cell under voltage",
+	-104: "battery failure",
+	-128: "unknown (possibly memory)",
+}
+
+func shutdownCauseReason(code int) string {
+	if r, ok := shutdownCauses[code]; ok {
+		return r
+	}
+	return fmt.Sprintf("unknown cause %d", code)
+}
+
+// scanPanicReports returns kernel panic reports (*.panic) in dirs modified
+// after since, newest first.
+func scanPanicReports(dirs []string, since time.Time) []PanicReport {
+	var reports []PanicReport
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".panic") {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil || info.ModTime().Before(since) {
+				continue
+			}
+			reports = append(reports, PanicReport{
+				File: filepath.Join(dir, e.Name()),
+				Time: info.ModTime().UTC(),
+			})
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Time.After(reports[j].Time) })
+	return reports
+}
+
+// DiagnoseStability returns diagnosis for stability issues.
+func DiagnoseStability(st Stability) *Diagnosis {
+	if st.Status == StatusGreen {
+		return nil
+	}
+	d := &Diagnosis{
+		Subsystem: "stability",
+		Severity:  st.Status,
+	}
+	uptime := FormatDuration(time.Duration(st.UptimeSec) * time.Second)
+
+	if st.PanicCount > 0 {
+		d.Summary = "System has kernel panicked recently"
+		d.Detail = fmt.Sprintf("%d kernel panic(s) recorded, most recent at %s (%s).",
+			st.PanicCount, st.RecentPanics[0].Time.Format(time.RFC3339), st.RecentPanics[0].File)
+		if st.LastShutdownReason != "" {
+			d.Detail += " Last shutdown cause: " + st.LastShutdownReason + "."
+		}
+		d.Action = "Avoid long unattended jobs until the cause is found. Inspect the panic report for the faulting kext or hardware, and check for OS updates"
+	} else {
+		d.Summary = "System has been up for a long time"
+		d.Detail = fmt.Sprintf("Uptime is %s (booted %s). Leaky daemons and stale caches accumulate over long uptimes.",
+			uptime, st.BootTime.Format(time.RFC3339))
+		d.Action = "Schedule a restart between jobs"
+	}
+	return d
+}
@@ -0,0 +1,101 @@
+package health
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBootTime(t *testing.T) {
+	boot, ok := parseBootTime("{ sec = 1739870000, usec = 123456 } Tue Feb 18 09:13:20 2025\n")
+	if !ok {
+		t.Fatal("parseBootTime() failed")
+	}
+	if !boot.Equal(time.Unix(1739870000, 0)) {
+		t.Errorf("boot = %v", boot)
+	}
+	if _, ok := parseBootTime(""); ok {
+		t.Error("expected failure for empty input")
+	}
+}
+
+func TestParseProcStatBootTime(t *testing.T) {
+	input := "cpu  10132153 290696 3084719 46828483 16683 0 25195 0 0 0\nintr 1462898\nctxt 2519233\nbtime 1739870000\nprocesses 26442\n"
+	boot, ok := parseProcStatBootTime(input)
+	if !ok || !boot.Equal(time.Unix(1739870000, 0)) {
+		t.Errorf("parseProcStatBootTime() = %v, %v", boot, ok)
+	}
+	if _, ok := parseProcStatBootTime("cpu 1 2 3\n"); ok {
+		t.Error("expected failure without btime line")
+	}
+}
+
+func TestParseShutdownCause(t *testing.T) {
+	input := `Timestamp               Ty Process[PID:TID]
+2026-02-18 09:13:25.120 Df kernel[0:1a] (AppleSMC) Previous shutdown cause: 5
+2026-02-18 09:13:25.480 Df kernel[0:1a] (AppleSMC) Previous shutdown cause: -128
+`
+	code, ok := parseShutdownCause(input)
+	if !ok || code != -128 {
+		t.Errorf("parseShutdownCause() = %d, %v, want -128 (last match)", code, ok)
+	}
+	if _, ok := parseShutdownCause("Timestamp Ty Process\n"); ok {
+		t.Error("expected no cause in empty log")
+	}
+	if got := shutdownCauseReason(5); got != "normal shutdown or restart" {
+		t.Errorf("shutdownCauseReason(5) = %q", got)
+	}
+	if got := shutdownCauseReason(42); got != "unknown cause 42" {
+		t.Errorf("shutdownCauseReason(42) = %q", got)
+	}
+}
+
+func TestScanPanicReports(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	files := map[string]time.Time{
+		"panic-full-2026-02-17-101010.0002.panic": now.Add(-24 * time.Hour),
+		"panic-full-2026-02-18-080000.0002.panic": now.Add(-1 * time.Hour),
+		"panic-full-2026-01-01-000000.0002.panic": now.Add(-30 * 24 * time.Hour),
+		"Safari-2026-02-18-080000.ips":            now.Add(-1 * time.Hour),
+	}
+	for name, mtime := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reports := scanPanicReports([]string{dir, filepath.Join(dir, "missing")}, now.Add(-7*24*time.Hour))
+	if len(reports) != 2 {
+		t.Fatalf("len(reports) = %d, want 2", len(reports))
+	}
+	if !strings.HasSuffix(reports[0].File, "2026-02-18-080000.0002.panic") {
+		t.Errorf("newest report = %s, want the 2026-02-18 panic first", reports[0].File)
+	}
+}
+
+func TestDiagnoseStability(t *testing.T) {
+	if d := DiagnoseStability(Stability{Status: StatusGreen}); d != nil {
+		t.Error("expected nil diagnosis for green status")
+	}
+
+	d := DiagnoseStability(Stability{
+		Status:       StatusRed,
+		PanicCount:   1,
+		RecentPanics: []PanicReport{{File: "/Library/Logs/DiagnosticReports/x.panic", Time: time.Now()}},
+	})
+	if d == nil || d.Severity != StatusRed || !strings.Contains(d.Summary, "panicked") {
+		t.Errorf("panic diagnosis = %+v", d)
+	}
+
+	d = DiagnoseStability(Stability{Status: StatusYellow, UptimeSec: 60 * 24 * 3600})
+	if d == nil || d.Severity != StatusYellow || !strings.Contains(d.Detail, "1440h00m") {
+		t.Errorf("uptime diagnosis = %+v", d)
+	}
+}
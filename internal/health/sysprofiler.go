@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Parser versions record which code path produced a subsystem's data. Bump the
+// suffix when a parser's output semantics change.
+const (
+	ParserJSON = "json-v1"
+	ParserText = "text-v1"
+)
+
+var errEmptyOutput = errors.New("empty output")
+
+// systemProfilerCacheTTL is how long cached system_profiler output is
+// reused before a background refresh is started.
+const systemProfilerCacheTTL = 5 * time.Minute
+
+// systemProfilerTimeout bounds a system_profiler run made inline because
+// nothing is cached yet.
+const systemProfilerTimeout = 3 * time.Second
+
+var errNoSystemProfiler = errors.New("system_profiler is only available on macOS")
+
+// cachedSystemProfiler returns the output of `system_profiler <dataType>`,
+// with -json when asJSON is set. Some data types (notably SPAirPortDataType)
+// scan hardware and take seconds, so checks read the last cached output and
+// a detached process refreshes it once it is older than
+// systemProfilerCacheTTL. With nothing cached yet (first run, or the cache
+// was cleared) it runs inline, bounded by systemProfilerTimeout, and caches
+// the result. Empty output is treated as a failure so callers can fall back.
+func cachedSystemProfiler(dataType string, asJSON bool) ([]byte, error) {
+	if runtime.GOOS != "darwin" {
+		return nil, errNoSystemProfiler
+	}
+	name, argv := "system_profiler-"+dataType, []string{"/usr/sbin/system_profiler", dataType}
+	if asJSON {
+		name, argv = name+".json", append(argv, "-json")
+	}
+	dir := cacheDir()
+	data, fetched, stale := readCache(dir, name, systemProfilerCacheTTL)
+	switch {
+	case fetched.IsZero():
+		ctx, cancel := context.WithTimeout(context.Background(), systemProfilerTimeout)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, argv[0], argv[1:]...).Output()
+		if err != nil {
+			return nil, err
+		}
+		writeCache(dir, name, out)
+		data = out
+	case stale:
+		refreshCacheAsync(dir, name, argv...)
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, errEmptyOutput
+	}
+	return data, nil
+}
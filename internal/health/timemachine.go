@@ -5,11 +5,12 @@ import (
 	"os/exec"
 	"regexp"
 	"strconv"
+	"time"
 )
 
 // CheckTimeMachine collects Time Machine backup state.
 func CheckTimeMachine() TimeMachine {
-	tm := TimeMachine{Status: StatusGreen, Percent: -1}
+	tm := TimeMachine{Status: StatusGreen, Percent: -1, TimeRemainingSec: -1}
 
 	out, err := exec.Command("/usr/bin/tmutil", "status").Output()
 	if err != nil {
@@ -17,6 +18,9 @@ func CheckTimeMachine() TimeMachine {
 	}
 
 	tm.Running, tm.Phase, tm.Percent = parseTmutil(string(out))
+	if tm.Running {
+		parseTmutilProgress(string(out), time.Now(), &tm)
+	}
 
 	if tm.Running {
 		tm.Status = StatusYellow
@@ -27,8 +31,17 @@ func CheckTimeMachine() TimeMachine {
 
 var (
 	tmRunningRe = regexp.MustCompile(`Running\s*=\s*(\d)`)
-	tmPhaseRe   = regexp.MustCompile(`BackupPhase\s*=\s*"([^"]+)"`)
+	tmPhaseRe   = regexp.MustCompile(`BackupPhase\s*=\s*"?([^";]+)"?;`)
 	tmPercentRe = regexp.MustCompile(`Percent\s*=\s*"([\d.e-]+)"`)
+
+	// Progress dictionary keys. Anchored to line start so that "bytes" does not
+	// match "totalBytes" or the quoted "_raw_totalBytes" key.
+	tmBytesRe         = regexp.MustCompile(`(?m)^\s*bytes\s*=\s*(\d+);`)
+	tmTotalBytesRe    = regexp.MustCompile(`(?m)^\s*totalBytes\s*=\s*(\d+);`)
+	tmFilesRe         = regexp.MustCompile(`(?m)^\s*files\s*=\s*(\d+);`)
+	tmTotalFilesRe    = regexp.MustCompile(`(?m)^\s*totalFiles\s*=\s*(\d+);`)
+	tmTimeRemainingRe = regexp.MustCompile(`(?m)^\s*TimeRemaining\s*=\s*"?([\d.]+)"?;`)
+	tmStateChangeRe   = regexp.MustCompile(`DateOfStateChange\s*=\s*"([^"]+)"`)
 )
 
 func parseTmutil(s string) (running bool, phase string, percent float64) {
@@ -57,6 +70,53 @@ func parseTmutil(s string) (running bool, phase string, percent float64) {
 	return
 }
 
+// parseTmutilProgress fills the byte/file counters and time remaining from the
+// Progress dictionary of `tmutil status`, then derives throughput and ETA
+// relative to now.
+func parseTmutilProgress(s string, now time.Time, tm *TimeMachine) {
+	if m := tmBytesRe.FindStringSubmatch(s); len(m) >= 2 {
+		tm.BytesCopied, _ = strconv.ParseInt(m[1], 10, 64)
+	}
+	if m := tmTotalBytesRe.FindStringSubmatch(s); len(m) >= 2 {
+		tm.TotalBytes, _ = strconv.ParseInt(m[1], 10, 64)
+	}
+	if m := tmFilesRe.FindStringSubmatch(s); len(m) >= 2 {
+		tm.FilesCopied, _ = strconv.Atoi(m[1])
+	}
+	if m := tmTotalFilesRe.FindStringSubmatch(s); len(m) >= 2 {
+		tm.TotalFiles, _ = strconv.Atoi(m[1])
+	}
+	if m := tmTimeRemainingRe.FindStringSubmatch(s); len(m) >= 2 {
+		if v, err := strconv.ParseFloat(m[1], 64); err == nil {
+			tm.TimeRemainingSec = int(v)
+		}
+	}
+
+	remaining := tm.TotalBytes - tm.BytesCopied
+
+	// Prefer measured throughput: bytes copied since the current phase began.
+	if m := tmStateChangeRe.FindStringSubmatch(s); len(m) >= 2 && tm.BytesCopied > 0 {
+		if since, err := time.Parse("2006-01-02 15:04:05 -0700", m[1]); err == nil {
+			if elapsed := now.Sub(since).Seconds(); elapsed > 0 {
+				tm.ThroughputMBps = float64(tm.BytesCopied) / elapsed / (1024 * 1024)
+			}
+		}
+	}
+	// Otherwise fall back to the rate implied by tmutil's own estimate.
+	if tm.ThroughputMBps == 0 && tm.TimeRemainingSec > 0 && remaining > 0 {
+		tm.ThroughputMBps = float64(remaining) / float64(tm.TimeRemainingSec) / (1024 * 1024)
+	}
+
+	switch {
+	case tm.TimeRemainingSec >= 0:
+		tm.ETA = now.Add(time.Duration(tm.TimeRemainingSec) * time.Second).UTC()
+	case tm.ThroughputMBps > 0 && remaining > 0:
+		sec := float64(remaining) / (tm.ThroughputMBps * 1024 * 1024)
+		tm.TimeRemainingSec = int(sec)
+		tm.ETA = now.Add(time.Duration(sec) * time.Second).UTC()
+	}
+}
+
 // DiagnoseTimeMachine returns diagnosis for Time Machine issues.
 func DiagnoseTimeMachine(tm TimeMachine) *Diagnosis {
 	if tm.Status == StatusGreen {
@@ -75,9 +135,40 @@ func DiagnoseTimeMachine(tm TimeMachine) *Diagnosis {
 	if tm.Percent >= 0 {
 		detail += fmt.Sprintf(", %.0f%% complete", tm.Percent)
 	}
+	if tm.TotalBytes > 0 {
+		detail += fmt.Sprintf(", %.1f of %.1f GB copied", bytesToGB(tm.BytesCopied), bytesToGB(tm.TotalBytes))
+	}
+	if tm.ThroughputMBps > 0 {
+		detail += fmt.Sprintf(" at %.1f MB/s", tm.ThroughputMBps)
+	}
 	detail += ". This may cause elevated disk I/O."
+	if tm.TimeRemainingSec >= 0 {
+		detail += fmt.Sprintf(" Estimated %s remaining.", FormatDuration(time.Duration(tm.TimeRemainingSec)*time.Second))
+	}
 	d.Detail = detail
 	d.Action = "Wait for backup to complete or defer heavy disk I/O tasks"
+	if tm.TimeRemainingSec >= 0 && !tm.ETA.IsZero() {
+		d.Action += fmt.Sprintf(" (expected to finish around %s)", tm.ETA.Local().Format("15:04"))
+	}
 
 	return d
 }
+
+func bytesToGB(b int64) float64 {
+	return float64(b) / (1024 * 1024 * 1024)
+}
+
+// FormatDuration renders d compactly for human output, e.g. "1h05m" or "4m".
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	h := int(d.Hours())
+	m := int(d.Minutes()) % 60
+	switch {
+	case h > 0:
+		return fmt.Sprintf("%dh%02dm", h, m)
+	case m > 0:
+		return fmt.Sprintf("%dm", m)
+	default:
+		return "<1m"
+	}
+}
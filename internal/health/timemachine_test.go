@@ -1,6 +1,10 @@
 package health
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestParseTmutil(t *testing.T) {
 	tests := []struct {
@@ -63,3 +67,145 @@ func TestParseTmutil(t *testing.T) {
 		})
 	}
 }
+
+const tmSampleCopying = `Backup session status:
+{
+    BackupPhase = Copying;
+    ClientID = "com.apple.backupd";
+    DateOfStateChange = "2026-02-18 10:00:00 +0000";
+    DestinationID = "0C7A5F2E-1B7D-4E3C-9C51-5B7E6D2A1F00";
+    Percent = "0.5";
+    Progress =     {
+        Percent = "0.5";
+        TimeRemaining = 600;
+        "_raw_Percent" = "0.5";
+        "_raw_totalBytes" = 2147483648;
+        bytes = 1073741824;
+        files = 1200;
+        totalBytes = 2147483648;
+        totalFiles = 2400;
+    };
+    Running = 1;
+    Stopping = 0;
+}`
+
+func TestParseTmutil_UnquotedPhase(t *testing.T) {
+	running, phase, pct := parseTmutil(tmSampleCopying)
+	if !running {
+		t.Error("running should be true")
+	}
+	if phase != "Copying" {
+		t.Errorf("phase = %q, want Copying", phase)
+	}
+	if abs(pct-50) > 0.1 {
+		t.Errorf("percent = %.2f, want 50", pct)
+	}
+}
+
+func TestParseTmutilProgress(t *testing.T) {
+	now := time.Date(2026, 2, 18, 10, 17, 4, 0, time.UTC) // 1024s after state change
+
+	tests := []struct {
+		name           string
+		input          string
+		wantBytes      int64
+		wantTotal      int64
+		wantFiles      int
+		wantRemaining  int
+		wantThroughput float64
+	}{
+		{
+			name:           "measured from state change",
+			input:          tmSampleCopying,
+			wantBytes:      1 << 30,
+			wantTotal:      2 << 30,
+			wantFiles:      1200,
+			wantRemaining:  600,
+			wantThroughput: 1.0, // 1024 MB in 1024s
+		},
+		{
+			name: "derived from time remaining",
+			input: `    Progress =     {
+        TimeRemaining = 512;
+        bytes = 0;
+        totalBytes = 536870912;
+    };`,
+			wantTotal:      512 << 20,
+			wantRemaining:  512,
+			wantThroughput: 1.0,
+		},
+		{
+			name: "ETA derived from throughput",
+			input: `    DateOfStateChange = "2026-02-18 10:00:00 +0000";
+    Progress =     {
+        bytes = 1073741824;
+        totalBytes = 2147483648;
+    };`,
+			wantBytes:      1 << 30,
+			wantTotal:      2 << 30,
+			wantRemaining:  1024,
+			wantThroughput: 1.0,
+		},
+		{
+			name:          "no progress dictionary",
+			input:         "Backup session status:\n{\n    Running = 1;\n}",
+			wantRemaining: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := TimeMachine{Running: true, TimeRemainingSec: -1}
+			parseTmutilProgress(tt.input, now, &tm)
+			if tm.BytesCopied != tt.wantBytes {
+				t.Errorf("BytesCopied = %d, want %d", tm.BytesCopied, tt.wantBytes)
+			}
+			if tm.TotalBytes != tt.wantTotal {
+				t.Errorf("TotalBytes = %d, want %d", tm.TotalBytes, tt.wantTotal)
+			}
+			if tm.FilesCopied != tt.wantFiles {
+				t.Errorf("FilesCopied = %d, want %d", tm.FilesCopied, tt.wantFiles)
+			}
+			if tm.TimeRemainingSec != tt.wantRemaining {
+				t.Errorf("TimeRemainingSec = %d, want %d", tm.TimeRemainingSec, tt.wantRemaining)
+			}
+			if abs(tm.ThroughputMBps-tt.wantThroughput) > 0.01 {
+				t.Errorf("ThroughputMBps = %.2f, want %.2f", tm.ThroughputMBps, tt.wantThroughput)
+			}
+			if tt.wantRemaining >= 0 {
+				want := now.Add(time.Duration(tt.wantRemaining) * time.Second)
+				if !tm.ETA.Equal(want) {
+					t.Errorf("ETA = %v, want %v", tm.ETA, want)
+				}
+			} else if !tm.ETA.IsZero() {
+				t.Errorf("ETA = %v, want zero", tm.ETA)
+			}
+		})
+	}
+}
+
+func TestDiagnoseTimeMachine_Progress(t *testing.T) {
+	tm := TimeMachine{
+		Status:           StatusYellow,
+		Running:          true,
+		Phase:            "Copying",
+		Percent:          42,
+		BytesCopied:      1 << 30,
+		TotalBytes:       4 << 30,
+		ThroughputMBps:   12.5,
+		TimeRemainingSec: 900,
+		ETA:              time.Now().Add(15 * time.Minute),
+	}
+	d := DiagnoseTimeMachine(tm)
+	if d == nil {
+		t.Fatal("expected non-nil diagnosis")
+	}
+	for _, want := range []string{"42% complete", "1.0 of 4.0 GB", "12.5 MB/s", "15m remaining"} {
+		if !strings.Contains(d.Detail, want) {
+			t.Errorf("Detail %q missing %q", d.Detail, want)
+		}
+	}
+	if DiagnoseTimeMachine(TimeMachine{Status: StatusGreen}) != nil {
+		t.Error("expected nil diagnosis for green status")
+	}
+}
@@ -0,0 +1,270 @@
+package health
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ntpCacheName is the cache entry for the last measured clock offset.
+const ntpCacheName = "ntp-offset.json"
+
+// CheckTime reports whether network time is enabled, the timezone and the
+// clock offset against the configured NTP server. The offset is measured with
+// a built-in SNTP client bounded by the configured timeout and reused for
+// CacheTTL, failures included, so repeated checks do not hit the network. A
+// drift beyond the yellow/red thresholds, or network time being off,
+// degrades status.
+func CheckTime(cfg TimeConfig) TimeSync {
+	ts := TimeSync{Status: StatusGreen, NetworkTime: ControlUnknown, Server: cfg.Server}
+	ts.Timezone, ts.ZoneAbbrev = localTimezone()
+
+	if runtime.GOOS == "linux" {
+		if out, err := exec.Command("/usr/bin/timedatectl", "show", "-p", "NTP", "-p", "NTPSynchronized").Output(); err == nil {
+			ts.NetworkTime = parseTimedatectl(string(out))
+		}
+	} else {
+		// timed's preferences are world-readable; systemsetup needs admin
+		// rights on recent macOS and is only a fallback.
+		out, _ := exec.Command("/usr/bin/defaults", "read", "/Library/Preferences/com.apple.timed", "TMAutomaticTimeOnlyEnabled").Output()
+		ts.NetworkTime = parseDefaultsBool(string(out))
+		if ts.NetworkTime == ControlUnknown {
+			out, _ = exec.Command("/usr/sbin/systemsetup", "-getusingnetworktime").CombinedOutput()
+			ts.NetworkTime = parseNetworkTime(string(out))
+		}
+	}
+
+	if cfg.Server != "" {
+		if m, ok := measureOffset(cacheDir(), cfg); ok {
+			ts.Measured = true
+			ts.OffsetMs = m.OffsetMs
+			ts.RTTMs = m.RTTMs
+			ts.MeasuredAt = m.At
+		} else {
+			ts.Error = fmt.Sprintf("failed to query NTP server %s: %s", cfg.Server, m.Error)
+		}
+	}
+
+	ts.Status = timeStatus(ts, cfg)
+	return ts
+}
+
+// parseDefaultsBool parses a boolean printed by `defaults read`: "1" or "0".
+func parseDefaultsBool(s string) string {
+	switch strings.TrimSpace(s) {
+	case "1":
+		return ControlEnabled
+	case "0":
+		return ControlDisabled
+	default:
+		return ControlUnknown
+	}
+}
+
+// parseNetworkTime parses `systemsetup -getusingnetworktime`: "Network Time: On".
+func parseNetworkTime(s string) string {
+	_, v, ok := strings.Cut(s, "Network Time:")
+	if !ok {
+		return ControlUnknown
+	}
+	switch strings.TrimSpace(v) {
+	case "On":
+		return ControlEnabled
+	case "Off":
+		return ControlDisabled
+	default:
+		return ControlUnknown
+	}
+}
+
+// parseTimedatectl parses `timedatectl show -p NTP -p NTPSynchronized`.
+func parseTimedatectl(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), "="); ok && k == "NTP" {
+			if v == "yes" {
+				return ControlEnabled
+			}
+			return ControlDisabled
+		}
+	}
+	return ControlUnknown
+}
+
+// localTimezone returns the IANA zone from the /etc/localtime symlink (or
+// $TZ) and the current zone abbreviation.
+func localTimezone() (name, abbrev string) {
+	abbrev, _ = time.Now().Zone()
+	if tz := os.Getenv("TZ"); tz != "" {
+		return strings.TrimPrefix(tz, ":"), abbrev
+	}
+	if target, err := os.Readlink("/etc/localtime"); err == nil {
+		return zoneFromPath(target), abbrev
+	}
+	return "", abbrev
+}
+
+// zoneFromPath extracts "Europe/Berlin" from
+// "/var/db/timezone/zoneinfo/Europe/Berlin" or "../usr/share/zoneinfo/Europe/Berlin".
+func zoneFromPath(p string) string {
+	if _, zone, ok := strings.Cut(p, "zoneinfo/"); ok {
+		return zone
+	}
+	return filepath.Base(p)
+}
+
+// ntpMeasurement is a cached offset measurement, or the error of a failed
+// query.
+type ntpMeasurement struct {
+	Server   string    `json:"server"`
+	OffsetMs float64   `json:"offset_ms"`
+	RTTMs    float64   `json:"rtt_ms"`
+	At       time.Time `json:"at"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// measureOffset returns a cached measurement for cfg.Server if fresh, and
+// otherwise queries the server and caches the result. Failures are cached
+// too, so an unreachable server costs one timeout per CacheTTL; ok is false
+// and m.Error says why.
+func measureOffset(dir string, cfg TimeConfig) (m ntpMeasurement, ok bool) {
+	if data, _, stale := readCache(dir, ntpCacheName, time.Duration(cfg.CacheTTL)); !stale {
+		if json.Unmarshal(data, &m) == nil && m.Server == cfg.Server {
+			return m, m.Error == ""
+		}
+	}
+
+	m = ntpMeasurement{Server: cfg.Server, At: time.Now().UTC()}
+	offset, rtt, err := querySNTP(cfg.Server, time.Duration(cfg.Timeout))
+	if err != nil {
+		m.Error = err.Error()
+	} else {
+		m.OffsetMs = float64(offset) / float64(time.Millisecond)
+		m.RTTMs = float64(rtt) / float64(time.Millisecond)
+	}
+	if data, err := json.Marshal(m); err == nil && dir != "" && os.MkdirAll(dir, 0o755) == nil {
+		os.WriteFile(filepath.Join(dir, ntpCacheName), data, 0o644)
+	}
+	return m, m.Error == ""
+}
+
+// ntpEpochOffset is the number of seconds between 1900-01-01 and 1970-01-01.
+const ntpEpochOffset = 2208988800
+
+var errBadNTPResponse = errors.New("invalid NTP response")
+
+// querySNTP sends a single SNTPv4 client request (RFC 4330) to server
+// ("host" or "host:port") and returns the local clock offset (positive when
+// the local clock is behind) and the round-trip delay.
+func querySNTP(server string, timeout time.Duration) (offset, rtt time.Duration, err error) {
+	addr := server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		addr = net.JoinHostPort(server, "123")
+	}
+	deadline := time.Now().Add(timeout)
+	conn, err := net.DialTimeout("udp", addr, timeout)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return 0, 0, err
+	}
+
+	req := make([]byte, 48)
+	req[0] = 0<<6 | 4<<3 | 3 // LI 0, version 4, mode 3 (client)
+	t1 := time.Now()
+	binary.BigEndian.PutUint64(req[40:], toNTPTime(t1))
+	if _, err := conn.Write(req); err != nil {
+		return 0, 0, err
+	}
+
+	resp := make([]byte, 48)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return 0, 0, err
+	}
+	t4 := time.Now()
+	if n < 48 || resp[0]&0x7 != 4 || resp[1] == 0 {
+		// Wrong mode, or stratum 0 (kiss-o'-death).
+		return 0, 0, errBadNTPResponse
+	}
+	if binary.BigEndian.Uint64(resp[24:]) != binary.BigEndian.Uint64(req[40:]) {
+		return 0, 0, errBadNTPResponse
+	}
+
+	t2 := fromNTPTime(binary.BigEndian.Uint64(resp[32:]))
+	t3 := fromNTPTime(binary.BigEndian.Uint64(resp[40:]))
+	offset = (t2.Sub(t1) + t3.Sub(t4)) / 2
+	rtt = t4.Sub(t1) - t3.Sub(t2)
+	return offset, rtt, nil
+}
+
+func toNTPTime(t time.Time) uint64 {
+	sec := uint64(t.Unix() + ntpEpochOffset)
+	frac := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return sec<<32 | frac
+}
+
+func fromNTPTime(v uint64) time.Time {
+	sec := int64(v>>32) - ntpEpochOffset
+	nsec := int64((v & 0xffffffff) * uint64(time.Second) >> 32)
+	return time.Unix(sec, nsec)
+}
+
+// timeStatus rates the measured drift and the network time setting.
+func timeStatus(ts TimeSync, cfg TimeConfig) Status {
+	drift := time.Duration(math.Abs(ts.OffsetMs) * float64(time.Millisecond))
+	switch {
+	case ts.Measured && cfg.RedDrift > 0 && drift >= time.Duration(cfg.RedDrift):
+		return StatusRed
+	case ts.Measured && cfg.YellowDrift > 0 && drift >= time.Duration(cfg.YellowDrift):
+		return StatusYellow
+	case ts.NetworkTime == ControlDisabled:
+		return StatusYellow
+	default:
+		return StatusGreen
+	}
+}
+
+// DiagnoseTime returns diagnosis for clock drift or disabled network time.
+func DiagnoseTime(ts TimeSync) *Diagnosis {
+	if ts.Status == StatusGreen {
+		return nil
+	}
+	d := &Diagnosis{
+		Subsystem: "time",
+		Severity:  ts.Status,
+	}
+
+	if ts.NetworkTime == ControlDisabled && ts.Status == StatusYellow {
+		d.Summary = "Automatic network time is disabled"
+		d.Detail = "The clock is not synchronised and will drift, especially after long sleeps."
+		if ts.Measured {
+			d.Detail += fmt.Sprintf(" Current offset against %s: %.3fs.", ts.Server, ts.OffsetMs/1000)
+		}
+		d.Action = "Enable 'Set time and date automatically' in System Settings > General > Date & Time, or run 'sudo systemsetup -setusingnetworktime on' (Linux: 'sudo timedatectl set-ntp true')"
+		return d
+	}
+
+	dir := "ahead of"
+	if ts.OffsetMs > 0 {
+		dir = "behind"
+	}
+	d.Summary = "System clock has drifted"
+	d.Detail = fmt.Sprintf("Clock is %s %s by %.3fs (round trip %.0f ms, measured %s). Signed requests, TLS and build caches fail on skewed clocks.",
+		dir, ts.Server, math.Abs(ts.OffsetMs)/1000, ts.RTTMs, ts.MeasuredAt.Format(time.RFC3339))
+	if ts.NetworkTime == ControlDisabled {
+		d.Detail += " Network time is off."
+	}
+	d.Action = "Run 'sudo sntp -sS " + ts.Server + "' to step the clock now, and keep 'Set time and date automatically' enabled in System Settings > General > Date & Time"
+	return d
+}
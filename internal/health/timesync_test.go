@@ -0,0 +1,186 @@
+package health
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startSNTPStub serves SNTP responses whose clock is skewed by skew from the
+// local clock.
+func startSNTPStub(t *testing.T, skew time.Duration, stratum byte) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on UDP: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 48)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n < 48 {
+				continue
+			}
+			resp := make([]byte, 48)
+			resp[0] = 0<<6 | 4<<3 | 4 // server mode
+			resp[1] = stratum
+			copy(resp[24:32], buf[40:48]) // originate = client transmit
+			now := time.Now().Add(skew)
+			binary.BigEndian.PutUint64(resp[32:], toNTPTime(now))
+			binary.BigEndian.PutUint64(resp[40:], toNTPTime(now))
+			conn.WriteTo(resp, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestQuerySNTP(t *testing.T) {
+	addr := startSNTPStub(t, 2*time.Second, 2)
+	offset, rtt, err := querySNTP(addr, time.Second)
+	if err != nil {
+		t.Fatalf("querySNTP() error = %v", err)
+	}
+	if offset < 1900*time.Millisecond || offset > 2100*time.Millisecond {
+		t.Errorf("offset = %v, want ~2s", offset)
+	}
+	if rtt < 0 || rtt > 100*time.Millisecond {
+		t.Errorf("rtt = %v", rtt)
+	}
+}
+
+func TestQuerySNTP_KissOfDeath(t *testing.T) {
+	addr := startSNTPStub(t, 0, 0)
+	if _, _, err := querySNTP(addr, time.Second); err != errBadNTPResponse {
+		t.Errorf("querySNTP() error = %v, want errBadNTPResponse", err)
+	}
+}
+
+func TestQuerySNTP_Timeout(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on UDP: %v", err)
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	if _, _, err := querySNTP(conn.LocalAddr().String(), 100*time.Millisecond); err == nil {
+		t.Error("expected timeout from silent server")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("querySNTP() took %v, want bounded by timeout", elapsed)
+	}
+}
+
+func TestNTPTimeRoundTrip(t *testing.T) {
+	now := time.Date(2026, 2, 18, 9, 0, 0, 123456789, time.UTC)
+	got := fromNTPTime(toNTPTime(now))
+	if d := got.Sub(now); d < -time.Microsecond || d > time.Microsecond {
+		t.Errorf("round trip = %v, want %v", got, now)
+	}
+}
+
+func TestMeasureOffsetCache(t *testing.T) {
+	dir := t.TempDir()
+	addr := startSNTPStub(t, -3*time.Second, 2)
+	cfg := TimeConfig{Server: addr, Timeout: Duration(time.Second), CacheTTL: Duration(time.Hour)}
+
+	m, ok := measureOffset(dir, cfg)
+	if !ok || m.OffsetMs > -2900 || m.OffsetMs < -3100 {
+		t.Fatalf("measureOffset() = %+v, %v", m, ok)
+	}
+	// A second call is served from the cache even if the server is gone.
+	cfg2 := cfg
+	cfg2.Timeout = Duration(time.Millisecond)
+	cached, ok := measureOffset(dir, cfg2)
+	if !ok || !cached.At.Equal(m.At) {
+		t.Errorf("expected cached measurement, got %+v, %v", cached, ok)
+	}
+	// A different server ignores the cache.
+	bad := TimeConfig{Server: "127.0.0.1:1", Timeout: Duration(50 * time.Millisecond), CacheTTL: Duration(time.Hour)}
+	failed, ok := measureOffset(dir, bad)
+	if ok || failed.Error == "" {
+		t.Fatalf("expected failure for a different server, got %+v, %v", failed, ok)
+	}
+	// The failure is cached as well.
+	if again, ok := measureOffset(dir, bad); ok || !again.At.Equal(failed.At) || again.Error != failed.Error {
+		t.Errorf("expected cached failure, got %+v, %v", again, ok)
+	}
+}
+
+func TestParseNetworkTime(t *testing.T) {
+	tests := map[string]string{
+		"Network Time: On\n":  ControlEnabled,
+		"Network Time: Off\n": ControlDisabled,
+		"You need administrator access to run this tool... exiting!\n": ControlUnknown,
+	}
+	for in, want := range tests {
+		if got := parseNetworkTime(in); got != want {
+			t.Errorf("parseNetworkTime(%q) = %q, want %q", in, got, want)
+		}
+	}
+	for in, want := range map[string]string{"1\n": ControlEnabled, "0\n": ControlDisabled, "": ControlUnknown} {
+		if got := parseDefaultsBool(in); got != want {
+			t.Errorf("parseDefaultsBool(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := parseTimedatectl("NTP=yes\nNTPSynchronized=yes\n"); got != ControlEnabled {
+		t.Errorf("parseTimedatectl() = %q", got)
+	}
+	if got := parseTimedatectl("NTP=no\n"); got != ControlDisabled {
+		t.Errorf("parseTimedatectl() = %q", got)
+	}
+}
+
+func TestZoneFromPath(t *testing.T) {
+	tests := map[string]string{
+		"/var/db/timezone/zoneinfo/America/Los_Angeles": "America/Los_Angeles",
+		"../usr/share/zoneinfo/Europe/Berlin":           "Europe/Berlin",
+		"/etc/UTC":                                      "UTC",
+	}
+	for in, want := range tests {
+		if got := zoneFromPath(in); got != want {
+			t.Errorf("zoneFromPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTimeStatusAndDiagnosis(t *testing.T) {
+	cfg := TimeConfig{YellowDrift: Duration(time.Second), RedDrift: Duration(time.Minute)}
+	tests := []struct {
+		name string
+		ts   TimeSync
+		want Status
+	}{
+		{"in sync", TimeSync{Measured: true, OffsetMs: 12, NetworkTime: ControlEnabled}, StatusGreen},
+		{"drift yellow", TimeSync{Measured: true, OffsetMs: -1500, NetworkTime: ControlEnabled}, StatusYellow},
+		{"drift red", TimeSync{Measured: true, OffsetMs: 90000, NetworkTime: ControlUnknown}, StatusRed},
+		{"network time off", TimeSync{NetworkTime: ControlDisabled}, StatusYellow},
+		{"unmeasured", TimeSync{NetworkTime: ControlUnknown}, StatusGreen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := timeStatus(tt.ts, cfg); got != tt.want {
+				t.Errorf("timeStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	d := DiagnoseTime(TimeSync{Status: StatusRed, Measured: true, OffsetMs: 90000, Server: "time.apple.com"})
+	if d == nil || d.Summary != "System clock has drifted" || !strings.Contains(d.Detail, "behind time.apple.com by 90.000s") {
+		t.Errorf("drift diagnosis = %+v", d)
+	}
+	d = DiagnoseTime(TimeSync{Status: StatusYellow, NetworkTime: ControlDisabled})
+	if d == nil || d.Summary != "Automatic network time is disabled" {
+		t.Errorf("network time diagnosis = %+v", d)
+	}
+	if DiagnoseTime(TimeSync{Status: StatusGreen}) != nil {
+		t.Error("expected nil for green")
+	}
+}
@@ -0,0 +1,289 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Xcode license states.
+const (
+	LicenseAccepted      = "accepted"
+	LicenseNotAccepted   = "not-accepted"
+	LicenseNotApplicable = "not-applicable" // Command Line Tools only
+	LicenseUnknown       = "unknown"
+)
+
+// Cache entries for slow toolchain commands.
+const (
+	simRuntimesCacheName = "simctl-runtimes.json"
+	derivedDataCacheName = "derived-data-du.txt"
+)
+
+// xcodeSearchGlob is where Xcode installs are looked for.
+var xcodeSearchGlob = "/Applications/Xcode*.app"
+
+// CheckToolchain reports the active developer directory, installed Xcode
+// versions, the Command Line Tools version, whether the Xcode license has been
+// accepted, simulator runtimes and DerivedData size. Simulator runtimes and
+// DerivedData size come from caches refreshed in the background, since
+// `simctl` and `du` can take seconds. A missing developer directory or an
+// unaccepted license is red; no developer tools or oversized DerivedData is yellow.
+func CheckToolchain(cfg ToolchainConfig) Toolchain {
+	tc := Toolchain{Status: StatusGreen, License: LicenseUnknown, DerivedDataBytes: -1}
+
+	out, err := exec.Command("/usr/bin/xcode-select", "-p").Output()
+	if err != nil {
+		if _, statErr := os.Stat("/usr/bin/xcode-select"); statErr != nil {
+			tc.Error = "xcode-select not available"
+			return tc
+		}
+		// xcode-select exits 2 with no output when nothing is installed.
+	}
+	tc.DeveloperDir = strings.TrimSpace(string(out))
+	if tc.DeveloperDir != "" {
+		_, err := os.Stat(tc.DeveloperDir)
+		tc.DeveloperDirExists = err == nil
+	}
+
+	if out, err := exec.Command("/usr/sbin/pkgutil", "--pkg-info=com.apple.pkg.CLTools_Executables").Output(); err == nil {
+		tc.CLTVersion = parsePkgutilVersion(string(out))
+	}
+
+	tc.Xcodes = findXcodes(xcodeSearchGlob, tc.DeveloperDir)
+
+	active := activeXcode(tc.Xcodes)
+	switch {
+	case active == nil:
+		tc.License = LicenseNotApplicable
+	default:
+		out, _ := exec.Command("/usr/bin/defaults", "read", "/Library/Preferences/com.apple.dt.Xcode", "IDEXcodeVersionForAgreedToGMLicense").Output()
+		tc.License = licenseState(strings.TrimSpace(string(out)), active.Version)
+	}
+
+	dir := cacheDir()
+	ttl := time.Duration(cfg.CacheTTL)
+	if tc.DeveloperDirExists {
+		data, fetched, stale := readCache(dir, simRuntimesCacheName, ttl)
+		if stale {
+			refreshCacheAsync(dir, simRuntimesCacheName, "/usr/bin/xcrun", "simctl", "list", "runtimes", "-j")
+		}
+		if !fetched.IsZero() {
+			tc.SimulatorRuntimes = parseSimctlRuntimes(data)
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		derived := filepath.Join(home, "Library", "Developer", "Xcode", "DerivedData")
+		if _, err := os.Stat(derived); err == nil {
+			data, fetched, stale := readCache(dir, derivedDataCacheName, ttl)
+			if stale {
+				refreshCacheAsync(dir, derivedDataCacheName, "/usr/bin/du", "-sk", derived)
+			}
+			if kb := parseDuKB(string(data)); !fetched.IsZero() && kb >= 0 {
+				tc.DerivedDataBytes = kb * 1024
+			}
+		}
+	}
+
+	tc.Status = toolchainStatus(tc, cfg)
+	return tc
+}
+
+// parsePkgutilVersion parses the "version: 16.2.0.0.1.1733547573" line of `pkgutil --pkg-info`.
+func parsePkgutilVersion(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(line), "version:"); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+var plistStringRe = regexp.MustCompile(`<key>(\w+)</key>\s*<string>([^<]*)</string>`)
+
+// parseVersionPlist reads CFBundleShortVersionString and ProductBuildVersion
+// from Xcode's Contents/version.plist (XML).
+func parseVersionPlist(s string) (version, build string) {
+	for _, m := range plistStringRe.FindAllStringSubmatch(s, -1) {
+		switch m[1] {
+		case "CFBundleShortVersionString":
+			version = m[2]
+		case "ProductBuildVersion":
+			build = m[2]
+		}
+	}
+	return
+}
+
+// findXcodes lists Xcode bundles matching glob plus the one developerDir
+// points into, marking the latter active.
+func findXcodes(glob, developerDir string) []XcodeInstall {
+	paths, _ := filepath.Glob(glob)
+	activeApp := ""
+	if app, _, ok := strings.Cut(developerDir, ".app/"); ok {
+		activeApp = app + ".app"
+		if !slices.Contains(paths, activeApp) {
+			paths = append(paths, activeApp)
+		}
+	}
+	sort.Strings(paths)
+
+	var xcodes []XcodeInstall
+	for _, p := range paths {
+		data, err := os.ReadFile(filepath.Join(p, "Contents", "version.plist"))
+		if err != nil {
+			continue
+		}
+		version, build := parseVersionPlist(string(data))
+		xcodes = append(xcodes, XcodeInstall{Path: p, Version: version, Build: build, Active: p == activeApp})
+	}
+	return xcodes
+}
+
+func activeXcode(xcodes []XcodeInstall) *XcodeInstall {
+	for i := range xcodes {
+		if xcodes[i].Active {
+			return &xcodes[i]
+		}
+	}
+	return nil
+}
+
+// licenseState compares the last license version agreed to with the active
+// Xcode version. A new major or minor release requires accepting again.
+func licenseState(agreed, active string) string {
+	switch {
+	case active == "":
+		return LicenseUnknown
+	case agreed == "":
+		return LicenseNotAccepted
+	case compareVersions(agreed, active) >= 0:
+		return LicenseAccepted
+	default:
+		return LicenseNotAccepted
+	}
+}
+
+// compareVersions compares dotted numeric versions ("16.2" vs "16.1.1").
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < max(len(as), len(bs)); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// spSimctlRuntimes is the shape of `xcrun simctl list runtimes -j`.
+type spSimctlRuntimes struct {
+	Runtimes []struct {
+		Name         string `json:"name"`
+		Version      string `json:"version"`
+		BuildVersion string `json:"buildversion"`
+		Identifier   string `json:"identifier"`
+		IsAvailable  bool   `json:"isAvailable"`
+	} `json:"runtimes"`
+}
+
+func parseSimctlRuntimes(data []byte) []SimulatorRuntime {
+	var raw spSimctlRuntimes
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil
+	}
+	runtimes := make([]SimulatorRuntime, 0, len(raw.Runtimes))
+	for _, r := range raw.Runtimes {
+		runtimes = append(runtimes, SimulatorRuntime{
+			Name:       r.Name,
+			Version:    r.Version,
+			Build:      r.BuildVersion,
+			Identifier: r.Identifier,
+			Available:  r.IsAvailable,
+		})
+	}
+	return runtimes
+}
+
+// parseDuKB parses `du -sk` output: "12345678\t/path".
+func parseDuKB(s string) int64 {
+	f := strings.Fields(s)
+	if len(f) == 0 {
+		return -1
+	}
+	n, err := strconv.ParseInt(f[0], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func toolchainStatus(tc Toolchain, cfg ToolchainConfig) Status {
+	switch {
+	case tc.DeveloperDir != "" && !tc.DeveloperDirExists, tc.License == LicenseNotAccepted:
+		return StatusRed
+	case tc.DeveloperDir == "":
+		return StatusYellow
+	case cfg.MaxDerivedDataGB > 0 && tc.DerivedDataBytes > int64(cfg.MaxDerivedDataGB)<<30:
+		return StatusYellow
+	default:
+		return StatusGreen
+	}
+}
+
+// DiagnoseToolchain returns diagnosis for broken toolchain states.
+func DiagnoseToolchain(tc Toolchain) *Diagnosis {
+	if tc.Status == StatusGreen {
+		return nil
+	}
+	d := &Diagnosis{
+		Subsystem: "toolchain",
+		Severity:  tc.Status,
+	}
+
+	switch {
+	case tc.DeveloperDir != "" && !tc.DeveloperDirExists:
+		d.Summary = "xcode-select points to a missing developer directory"
+		d.Detail = fmt.Sprintf("Active developer directory %s does not exist; xcodebuild, clang and git will fail.", tc.DeveloperDir)
+		if len(tc.Xcodes) > 0 {
+			d.Action = fmt.Sprintf("Run 'sudo xcode-select -s %s/Contents/Developer'", tc.Xcodes[len(tc.Xcodes)-1].Path)
+		} else if tc.CLTVersion != "" {
+			d.Action = "Run 'sudo xcode-select --reset' to use the installed Command Line Tools"
+		} else {
+			d.Action = "Run 'xcode-select --install' to install the Command Line Tools"
+		}
+	case tc.License == LicenseNotAccepted:
+		d.Summary = "Xcode license has not been accepted"
+		d.Detail = "The active Xcode's license agreement has not been accepted; xcodebuild and other developer tools refuse to run."
+		if active := activeXcode(tc.Xcodes); active != nil {
+			d.Detail = fmt.Sprintf("Xcode %s (%s) is active but its license agreement has not been accepted; xcodebuild and other developer tools refuse to run.", active.Version, active.Path)
+		}
+		d.Action = "Run 'sudo xcodebuild -license accept'"
+	case tc.DeveloperDir == "":
+		d.Summary = "No developer tools are installed"
+		d.Detail = "Neither Xcode nor the Command Line Tools are selected; git, clang and make are unavailable."
+		d.Action = "Run 'xcode-select --install' to install the Command Line Tools, or install Xcode from the App Store"
+	default:
+		d.Summary = "DerivedData is using a lot of disk"
+		d.Detail = fmt.Sprintf("~/Library/Developer/Xcode/DerivedData is %.1f GB.", bytesToGB(tc.DerivedDataBytes))
+		d.Action = "Quit Xcode and run 'rm -rf ~/Library/Developer/Xcode/DerivedData'; it is rebuilt on the next build"
+	}
+	return d
+}
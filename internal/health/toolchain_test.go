@@ -0,0 +1,182 @@
+package health
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleVersionPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>BuildAliasOf</key>
+	<string>IDEFrameworks</string>
+	<key>BuildVersion</key>
+	<string>9</string>
+	<key>CFBundleShortVersionString</key>
+	<string>16.2</string>
+	<key>CFBundleVersion</key>
+	<string>23507</string>
+	<key>ProductBuildVersion</key>
+	<string>16C5032a</string>
+</dict>
+</plist>
+`
+
+func writeFakeXcode(t *testing.T, app, plist string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(app, "Contents", "Developer"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(app, "Contents", "version.plist"), []byte(plist), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseVersionPlist(t *testing.T) {
+	version, build := parseVersionPlist(sampleVersionPlist)
+	if version != "16.2" || build != "16C5032a" {
+		t.Errorf("parseVersionPlist() = %q, %q", version, build)
+	}
+}
+
+func TestParsePkgutilVersion(t *testing.T) {
+	input := `package-id: com.apple.pkg.CLTools_Executables
+version: 16.2.0.0.1.1733547573
+volume: /
+location: /
+install-time: 1739870000
+`
+	if got := parsePkgutilVersion(input); got != "16.2.0.0.1.1733547573" {
+		t.Errorf("parsePkgutilVersion() = %q", got)
+	}
+}
+
+func TestFindXcodes(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeXcode(t, filepath.Join(dir, "Xcode.app"), sampleVersionPlist)
+	writeFakeXcode(t, filepath.Join(dir, "Xcode-beta.app"), strings.ReplaceAll(sampleVersionPlist, "16.2", "16.3"))
+	other := filepath.Join(t.TempDir(), "Xcode_15.4.app")
+	writeFakeXcode(t, other, strings.ReplaceAll(sampleVersionPlist, "16.2", "15.4"))
+
+	xcodes := findXcodes(filepath.Join(dir, "Xcode*.app"), other+"/Contents/Developer")
+	if len(xcodes) != 3 {
+		t.Fatalf("got %d Xcodes, want 3: %+v", len(xcodes), xcodes)
+	}
+	active := activeXcode(xcodes)
+	if active == nil || active.Path != other || active.Version != "15.4" {
+		t.Errorf("active = %+v", active)
+	}
+
+	if xcodes := findXcodes(filepath.Join(dir, "Xcode*.app"), "/Library/Developer/CommandLineTools"); activeXcode(xcodes) != nil {
+		t.Error("Command Line Tools should not mark an Xcode active")
+	}
+}
+
+func TestLicenseState(t *testing.T) {
+	tests := []struct {
+		agreed, active, want string
+	}{
+		{"16.2", "16.2", LicenseAccepted},
+		{"16.3", "16.2", LicenseAccepted},
+		{"16.1", "16.2", LicenseNotAccepted},
+		{"", "16.2", LicenseNotAccepted},
+		{"16.2", "", LicenseUnknown},
+	}
+	for _, tt := range tests {
+		if got := licenseState(tt.agreed, tt.active); got != tt.want {
+			t.Errorf("licenseState(%q, %q) = %q, want %q", tt.agreed, tt.active, got, tt.want)
+		}
+	}
+	if compareVersions("16.1.1", "16.1") != 1 || compareVersions("9.4", "16.0") != -1 || compareVersions("16.0", "16") != 0 {
+		t.Error("compareVersions ordering is wrong")
+	}
+}
+
+func TestParseSimctlRuntimes(t *testing.T) {
+	input := `{
+  "runtimes" : [
+    {
+      "bundlePath" : "/Library/Developer/CoreSimulator/Volumes/iOS_22C150/Library/Developer/CoreSimulator/Profiles/Runtimes/iOS 18.2.simruntime",
+      "buildversion" : "22C150",
+      "platform" : "iOS",
+      "identifier" : "com.apple.CoreSimulator.SimRuntime.iOS-18-2",
+      "version" : "18.2",
+      "isInternal" : false,
+      "isAvailable" : true,
+      "name" : "iOS 18.2"
+    },
+    {
+      "buildversion" : "21R355",
+      "identifier" : "com.apple.CoreSimulator.SimRuntime.watchOS-10-2",
+      "version" : "10.2",
+      "isAvailable" : false,
+      "name" : "watchOS 10.2"
+    }
+  ]
+}`
+	runtimes := parseSimctlRuntimes([]byte(input))
+	if len(runtimes) != 2 {
+		t.Fatalf("got %d runtimes, want 2", len(runtimes))
+	}
+	if r := runtimes[0]; r.Name != "iOS 18.2" || r.Build != "22C150" || !r.Available {
+		t.Errorf("runtimes[0] = %+v", r)
+	}
+	if runtimes[1].Available {
+		t.Error("runtimes[1] should be unavailable")
+	}
+	if parseSimctlRuntimes([]byte("garbage")) != nil {
+		t.Error("expected nil on invalid JSON")
+	}
+}
+
+func TestParseDuKB(t *testing.T) {
+	if got := parseDuKB("52428800\t/Users/dev/Library/Developer/Xcode/DerivedData\n"); got != 52428800 {
+		t.Errorf("parseDuKB() = %d", got)
+	}
+	if got := parseDuKB(""); got != -1 {
+		t.Errorf("parseDuKB(\"\") = %d, want -1", got)
+	}
+}
+
+func TestToolchainStatusAndDiagnosis(t *testing.T) {
+	cfg := ToolchainConfig{MaxDerivedDataGB: 50}
+	xcode := []XcodeInstall{{Path: "/Applications/Xcode.app", Version: "16.2", Active: true}}
+	tests := []struct {
+		name    string
+		tc      Toolchain
+		want    Status
+		summary string
+	}{
+		{"healthy", Toolchain{DeveloperDir: "/Applications/Xcode.app/Contents/Developer", DeveloperDirExists: true, Xcodes: xcode, License: LicenseAccepted}, StatusGreen, ""},
+		{"missing developer dir", Toolchain{DeveloperDir: "/Applications/Xcode-beta.app/Contents/Developer", Xcodes: xcode}, StatusRed, "missing developer directory"},
+		{"license", Toolchain{DeveloperDir: "/Applications/Xcode.app/Contents/Developer", DeveloperDirExists: true, Xcodes: xcode, License: LicenseNotAccepted}, StatusRed, "license"},
+		{"nothing installed", Toolchain{License: LicenseNotApplicable}, StatusYellow, "No developer tools"},
+		{"derived data", Toolchain{DeveloperDir: "/Library/Developer/CommandLineTools", DeveloperDirExists: true, DerivedDataBytes: 60 << 30}, StatusYellow, "DerivedData"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.tc.Status = toolchainStatus(tt.tc, cfg)
+			if tt.tc.Status != tt.want {
+				t.Fatalf("toolchainStatus() = %v, want %v", tt.tc.Status, tt.want)
+			}
+			d := DiagnoseToolchain(tt.tc)
+			if tt.summary == "" {
+				if d != nil {
+					t.Errorf("unexpected diagnosis %+v", d)
+				}
+				return
+			}
+			if d == nil || !strings.Contains(d.Summary, tt.summary) || d.Action == "" {
+				t.Errorf("diagnosis = %+v, want summary containing %q", d, tt.summary)
+			}
+		})
+	}
+
+	d := DiagnoseToolchain(Toolchain{Status: StatusRed, DeveloperDir: "/Applications/Xcode-beta.app/Contents/Developer", Xcodes: xcode})
+	if !strings.Contains(d.Action, "xcode-select -s /Applications/Xcode.app/Contents/Developer") {
+		t.Errorf("Action = %q", d.Action)
+	}
+}
@@ -13,17 +13,32 @@ const (
 
 // Report is the top-level health check output.
 type Report struct {
-	Timestamp   time.Time   `json:"timestamp"`
-	Score       Score       `json:"score"`
-	CPU         CPU         `json:"cpu"`
-	Memory      Memory      `json:"memory"`
-	Disk        Disk        `json:"disk"`
-	Thermal     Thermal     `json:"thermal"`
-	ICloud      ICloud      `json:"icloud"`
-	Battery     Battery     `json:"battery"`
-	TimeMachine TimeMachine `json:"timemachine"`
-	Network     Network     `json:"network"`
-	Bluetooth   Bluetooth   `json:"bluetooth"`
+	Timestamp    time.Time      `json:"timestamp"`
+	Score        Score          `json:"score"`
+	CPU          CPU            `json:"cpu"`
+	Memory       Memory         `json:"memory"`
+	Disk         Disk           `json:"disk"`
+	Thermal      Thermal        `json:"thermal"`
+	ICloud       ICloud         `json:"icloud"`
+	CloudSync    CloudSync      `json:"cloudsync"`
+	Battery      Battery        `json:"battery"`
+	TimeMachine  TimeMachine    `json:"timemachine"`
+	Network      Network        `json:"network"`
+	Bluetooth    Bluetooth      `json:"bluetooth"`
+	Security     Security       `json:"security"`
+	OS           OS             `json:"os"`
+	Stability    Stability      `json:"stability"`
+	Crashes      Crashes        `json:"crashes"`
+	Power        Power          `json:"power"`
+	Limits       Limits         `json:"limits"`
+	Processes    Processes      `json:"processes"`
+	Time         TimeSync       `json:"time"`
+	Toolchain    Toolchain      `json:"toolchain"`
+	Containers   Containers     `json:"containers"`
+	Packages     Packages       `json:"packages"`
+	Services     Services       `json:"services"`
+	Certificates Certificates   `json:"certificates"`
+	Custom       []CustomResult `json:"custom,omitempty"`
 }
 
 // Score is the composite health score.
@@ -72,11 +87,56 @@ type Thermal struct {
 
 // ICloud contains iCloud sync state.
 type ICloud struct {
-	Status   Status `json:"status"`
-	Error    string `json:"error,omitempty"`
-	Syncing  bool   `json:"syncing"`
-	CaughtUp bool   `json:"caught_up"`
-	LastSync string `json:"last_sync"`
+	Status           Status            `json:"status"`
+	Error            string            `json:"error,omitempty"`
+	Syncing          bool              `json:"syncing"`
+	CaughtUp         bool              `json:"caught_up"`
+	LastSync         string            `json:"last_sync"`
+	PendingUploads   int               `json:"pending_uploads"`
+	PendingDownloads int               `json:"pending_downloads"`
+	ErrorCount       int               `json:"error_count"`
+	Containers       []ICloudContainer `json:"containers,omitempty"`
+	Paths            []ICloudPath      `json:"paths,omitempty"`
+}
+
+// ICloudContainer is the sync state of a single iCloud container from `brctl status`.
+type ICloudContainer struct {
+	ID               string   `json:"id"`
+	State            string   `json:"state,omitempty"` // client state, e.g. "idle", "needs-sync"
+	CaughtUp         bool     `json:"caught_up"`
+	LastSync         string   `json:"last_sync,omitempty"`
+	PendingUploads   int      `json:"pending_uploads"`
+	PendingDownloads int      `json:"pending_downloads"`
+	Errors           []string `json:"errors,omitempty"`
+}
+
+// ICloudPath reports whether a configured folder is fully materialised locally.
+type ICloudPath struct {
+	Path         string `json:"path"`
+	Error        string `json:"error,omitempty"`
+	Files        int    `json:"files"`
+	Evicted      int    `json:"evicted"`
+	Materialized bool   `json:"materialized"`        // false for a partial scan, even with nothing evicted
+	Truncated    bool   `json:"truncated,omitempty"` // scan stopped at the file limit
+	Skipped      int    `json:"skipped,omitempty"`   // unreadable entries left out of the scan
+}
+
+// CloudSync contains third-party cloud sync state (Dropbox, Google Drive, OneDrive).
+type CloudSync struct {
+	Status    Status              `json:"status"`
+	Error     string              `json:"error,omitempty"`
+	Providers []CloudSyncProvider `json:"providers,omitempty"`
+}
+
+// CloudSyncProvider is the sync state of one configured provider.
+type CloudSyncProvider struct {
+	Name     string   `json:"name"`
+	Root     string   `json:"root,omitempty"`
+	Domains  []string `json:"domains,omitempty"`
+	State    string   `json:"state"` // caught-up, syncing, error, unknown
+	Syncing  bool     `json:"syncing"`
+	CaughtUp bool     `json:"caught_up"`
+	Errors   []string `json:"errors,omitempty"`
 }
 
 // Battery contains battery state.
@@ -90,25 +150,89 @@ type Battery struct {
 	TimeRemainingMin int     `json:"time_remaining_min"`
 	HealthPercent    float64 `json:"health_percent"`
 	CycleCount       int     `json:"cycle_count"`
+	Condition        string  `json:"condition,omitempty"` // e.g. "Normal", "Service Recommended"
 	Installed        bool    `json:"installed"`
+	Parser           string  `json:"parser,omitempty"` // json-v1 (system_profiler) or text-v1 (ioreg)
 }
 
 // TimeMachine contains Time Machine backup state.
 type TimeMachine struct {
-	Status  Status  `json:"status"`
-	Error   string  `json:"error,omitempty"`
-	Running bool    `json:"running"`
-	Phase   string  `json:"phase,omitempty"`
-	Percent float64 `json:"percent"`
+	Status           Status    `json:"status"`
+	Error            string    `json:"error,omitempty"`
+	Running          bool      `json:"running"`
+	Phase            string    `json:"phase,omitempty"`
+	Percent          float64   `json:"percent"`
+	BytesCopied      int64     `json:"bytes_copied,omitempty"`
+	TotalBytes       int64     `json:"total_bytes,omitempty"`
+	FilesCopied      int       `json:"files_copied,omitempty"`
+	TotalFiles       int       `json:"total_files,omitempty"`
+	TimeRemainingSec int       `json:"time_remaining_sec"` // -1 means not reported
+	ThroughputMBps   float64   `json:"throughput_mbps,omitempty"`
+	ETA              time.Time `json:"eta,omitzero"`
 }
 
 // Network contains network connectivity state.
 type Network struct {
-	Status    Status `json:"status"`
-	Error     string `json:"error,omitempty"`
-	Reachable bool   `json:"reachable"`
-	Interface string `json:"interface,omitempty"`
-	IP        string `json:"ip,omitempty"`
+	Status         Status        `json:"status"`
+	Error          string        `json:"error,omitempty"`
+	Reachable      bool          `json:"reachable"`
+	Interface      string        `json:"interface,omitempty"`
+	IP             string        `json:"ip,omitempty"`
+	WiFi           WiFi          `json:"wifi,omitzero"`
+	Proxy          ProxySettings `json:"proxy,omitzero"`
+	DNS            DNSConfig     `json:"dns,omitzero"`
+	HostsOverrides []HostsEntry  `json:"hosts_overrides,omitempty"`
+}
+
+// ProxySettings are the system proxy settings from `scutil --proxy`.
+// Proxies are "host:port" and empty when disabled.
+type ProxySettings struct {
+	HTTP          string   `json:"http,omitempty"`
+	HTTPS         string   `json:"https,omitempty"`
+	SOCKS         string   `json:"socks,omitempty"`
+	PACURL        string   `json:"pac_url,omitempty"`
+	PACError      string   `json:"pac_error,omitempty"` // set when the PAC file could not be fetched
+	AutoDiscovery bool     `json:"auto_discovery"`
+	Exceptions    []string `json:"exceptions,omitempty"`
+}
+
+// DNSConfig is the resolver configuration from `scutil --dns`.
+type DNSConfig struct {
+	Nameservers   []string      `json:"nameservers,omitempty"` // default resolver
+	SearchDomains []string      `json:"search_domains,omitempty"`
+	Resolvers     []DNSResolver `json:"resolvers,omitempty"`
+	Unresponsive  []string      `json:"unresponsive,omitempty"` // nameservers that did not answer a probe
+}
+
+// DNSResolver is one resolver entry. Domain is empty for default resolvers
+// and set for supplemental ones (e.g. added by a VPN).
+type DNSResolver struct {
+	Domain        string   `json:"domain,omitempty"`
+	Nameservers   []string `json:"nameservers"`
+	SearchDomains []string `json:"search_domains,omitempty"`
+	Interface     string   `json:"interface,omitempty"`
+	Scoped        bool     `json:"scoped"`
+	Reachable     bool     `json:"reachable"`
+	Order         int      `json:"order,omitempty"`
+}
+
+// HostsEntry is a non-default /etc/hosts line.
+type HostsEntry struct {
+	IP        string   `json:"ip"`
+	Hostnames []string `json:"hostnames"`
+}
+
+// WiFi contains the state of the Wi-Fi interface from system_profiler.
+type WiFi struct {
+	Interface        string `json:"interface,omitempty"`
+	Connected        bool   `json:"connected"`
+	SSID             string `json:"ssid,omitempty"` // may be redacted without Location permission
+	PHYMode          string `json:"phy_mode,omitempty"`
+	Channel          string `json:"channel,omitempty"`
+	SignalDBm        int    `json:"signal_dbm,omitempty"`
+	NoiseDBm         int    `json:"noise_dbm,omitempty"`
+	TransmitRateMbps int    `json:"transmit_rate_mbps,omitempty"`
+	Parser           string `json:"parser,omitempty"` // json-v1 or text-v1
 }
 
 // Bluetooth contains Bluetooth controller and connected-device state.
@@ -119,13 +243,325 @@ type Bluetooth struct {
 	Enabled              bool              `json:"enabled"`
 	ConnectedDeviceCount int               `json:"connected_device_count"`
 	Devices              []BluetoothDevice `json:"devices,omitempty"`
+	Parser               string            `json:"parser,omitempty"` // json-v1 or text-v1
 }
 
 // BluetoothDevice represents a single paired/connected Bluetooth device.
+// Battery fields use -1 to mean "not reported".
 type BluetoothDevice struct {
 	Name           string `json:"name"`
 	Connected      bool   `json:"connected"`
-	BatteryPercent int    `json:"battery_percent,omitempty"` // -1 means not reported
+	BatteryPercent int    `json:"battery_percent,omitempty"` // highest of the component levels
+	BatteryMain    int    `json:"battery_main"`
+	BatteryLeft    int    `json:"battery_left"`
+	BatteryRight   int    `json:"battery_right"`
+	BatteryCase    int    `json:"battery_case"`
+	MinorType      string `json:"minor_type,omitempty"` // e.g. "headphones", "keyboard", "mouse", "trackpad"
+	Address        string `json:"address,omitempty"`
+	VendorID       string `json:"vendor_id,omitempty"`
+	ProductID      string `json:"product_id,omitempty"`
+	Firmware       string `json:"firmware,omitempty"`
+}
+
+// Security contains the state of macOS security controls.
+type Security struct {
+	Status     Status          `json:"status"`
+	Error      string          `json:"error,omitempty"`
+	FileVault  SecurityControl `json:"filevault"`
+	SIP        SecurityControl `json:"sip"`
+	Gatekeeper SecurityControl `json:"gatekeeper"`
+	Firewall   SecurityControl `json:"firewall"`
+}
+
+// SecurityControl is the state of a single security control.
+type SecurityControl struct {
+	State   string `json:"state"` // enabled, disabled, unknown
+	Enabled bool   `json:"enabled"`
+	Detail  string `json:"detail,omitempty"` // raw status output
+}
+
+// OS contains operating system version, uptime and pending update state.
+type OS struct {
+	Status           Status           `json:"status"`
+	Error            string           `json:"error,omitempty"`
+	ProductName      string           `json:"product_name"`
+	ProductVersion   string           `json:"product_version"`
+	BuildVersion     string           `json:"build_version,omitempty"`
+	Arch             string           `json:"arch"`
+	UptimeSec        int64            `json:"uptime_sec"`
+	UpdatesCheckedAt time.Time        `json:"updates_checked_at,omitzero"` // zero until the first background scan finishes
+	PendingUpdates   []SoftwareUpdate `json:"pending_updates,omitempty"`
+	RestartRequired  bool             `json:"restart_required"`
+	SecurityUpdates  int              `json:"security_updates"`
+}
+
+// SoftwareUpdate is a single entry from `softwareupdate --list`.
+type SoftwareUpdate struct {
+	Label           string `json:"label"`
+	Title           string `json:"title"`
+	Version         string `json:"version,omitempty"`
+	SizeKB          int64  `json:"size_kb,omitempty"`
+	Recommended     bool   `json:"recommended"`
+	RestartRequired bool   `json:"restart_required"`
+	Security        bool   `json:"security"`
+}
+
+// Stability contains boot, shutdown and kernel panic history.
+type Stability struct {
+	Status             Status        `json:"status"`
+	Error              string        `json:"error,omitempty"`
+	BootTime           time.Time     `json:"boot_time,omitzero"`
+	UptimeSec          int64         `json:"uptime_sec"`
+	LastShutdownCause  *int          `json:"last_shutdown_cause,omitempty"` // AppleSMC code; nil until known
+	LastShutdownReason string        `json:"last_shutdown_reason,omitempty"`
+	PanicCount         int           `json:"panic_count"`
+	RecentPanics       []PanicReport `json:"recent_panics,omitempty"`
+}
+
+// PanicReport is a kernel panic report file.
+type PanicReport struct {
+	File string    `json:"file"`
+	Time time.Time `json:"time"`
+}
+
+// Crashes summarizes application crash and hang reports in the configured window.
+type Crashes struct {
+	Status     Status       `json:"status"`
+	Error      string       `json:"error,omitempty"`
+	Total      int          `json:"total"`
+	TopProcess string       `json:"top_process,omitempty"`
+	TopCount   int          `json:"top_count"`
+	Groups     []CrashGroup `json:"groups,omitempty"` // most frequent first
+}
+
+// CrashGroup counts reports sharing a process, kind and exception type.
+type CrashGroup struct {
+	Process       string    `json:"process"`
+	Kind          string    `json:"kind"` // crash, hang
+	ExceptionType string    `json:"exception_type,omitempty"`
+	Count         int       `json:"count"`
+	MostRecent    time.Time `json:"most_recent"`
+	LastReport    string    `json:"last_report"`
+}
+
+// Power contains sleep settings, power assertions and clamshell state.
+type Power struct {
+	Status                Status           `json:"status"`
+	Error                 string           `json:"error,omitempty"`
+	SleepMinutes          int              `json:"sleep_minutes"` // 0 = never
+	DisplaySleepMinutes   int              `json:"display_sleep_minutes"`
+	SystemSleepPrevented  bool             `json:"system_sleep_prevented"`
+	DisplaySleepPrevented bool             `json:"display_sleep_prevented"`
+	LowPowerMode          bool             `json:"low_power_mode"`
+	LidClosed             bool             `json:"lid_closed"`
+	ClamshellCausesSleep  bool             `json:"clamshell_causes_sleep"`
+	SleepRisk             bool             `json:"sleep_risk"` // a long-running task may be interrupted by sleep
+	SleepRiskReason       string           `json:"sleep_risk_reason,omitempty"`
+	Assertions            []PowerAssertion `json:"assertions,omitempty"`
+}
+
+// PowerAssertion is a power assertion held by a process.
+type PowerAssertion struct {
+	PID           int    `json:"pid"`
+	Process       string `json:"process"`
+	Type          string `json:"type"` // e.g. PreventUserIdleSystemSleep
+	Name          string `json:"name"`
+	AgeSec        int64  `json:"age_sec"`
+	PreventsSleep bool   `json:"prevents_sleep"`
+}
+
+// Limits compares resource usage against kernel and per-user limits.
+type Limits struct {
+	Status         Status         `json:"status"`
+	Error          string         `json:"error,omitempty"`
+	Files          LimitUsage     `json:"files"`           // open files vs kern.maxfiles (fs.file-max)
+	Processes      LimitUsage     `json:"processes"`       // processes vs kern.maxproc (pid_max)
+	UserProcesses  LimitUsage     `json:"user_processes"`  // this user's processes vs maxprocperuid / ulimit -u
+	EphemeralPorts LimitUsage     `json:"ephemeral_ports"` // TCP sockets holding a port in the ephemeral range vs its size
+	TimeWait       int            `json:"time_wait"`
+	SocketStates   map[string]int `json:"socket_states,omitempty"`
+	UlimitProcs    int64          `json:"ulimit_procs"` // -1 = unlimited or unknown
+}
+
+// LimitUsage is current usage against a limit.
+type LimitUsage struct {
+	Used    int64   `json:"used"`
+	Limit   int64   `json:"limit"` // 0 = unknown
+	Percent float64 `json:"percent"`
+}
+
+// Processes lists zombie, uninterruptible, stuck and orphaned processes.
+type Processes struct {
+	Status          Status        `json:"status"`
+	Error           string        `json:"error,omitempty"`
+	Total           int           `json:"total"`
+	Zombies         []ProcessInfo `json:"zombies,omitempty"`
+	Uninterruptible []ProcessInfo `json:"uninterruptible,omitempty"` // state U (macOS) or D (Linux)
+	Stuck           []ProcessInfo `json:"stuck,omitempty"`           // no CPU progress for at least the stuck age
+	Orphans         []ProcessInfo `json:"orphans,omitempty"`         // watched tools reparented to PID 1
+}
+
+// ProcessInfo describes a single process.
+type ProcessInfo struct {
+	PID         int     `json:"pid"`
+	PPID        int     `json:"ppid"`
+	Command     string  `json:"command"`
+	State       string  `json:"state"`
+	AgeSec      int64   `json:"age_sec"`
+	CPUSec      float64 `json:"cpu_sec"`
+	StalledSec  int64   `json:"stalled_sec,omitempty"`
+	Descendants int     `json:"descendants,omitempty"`
+}
+
+// TimeSync contains clock synchronisation state and measured drift.
+type TimeSync struct {
+	Status      Status    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	NetworkTime string    `json:"network_time"` // enabled, disabled, unknown
+	Timezone    string    `json:"timezone,omitempty"`
+	ZoneAbbrev  string    `json:"zone_abbrev,omitempty"`
+	Server      string    `json:"server,omitempty"`
+	Measured    bool      `json:"measured"`
+	OffsetMs    float64   `json:"offset_ms"` // positive when the local clock is behind
+	RTTMs       float64   `json:"rtt_ms"`
+	MeasuredAt  time.Time `json:"measured_at,omitzero"`
+}
+
+// Toolchain contains the Xcode and Command Line Tools state.
+type Toolchain struct {
+	Status             Status             `json:"status"`
+	Error              string             `json:"error,omitempty"`
+	DeveloperDir       string             `json:"developer_dir"` // xcode-select -p; "" when nothing is installed
+	DeveloperDirExists bool               `json:"developer_dir_exists"`
+	CLTVersion         string             `json:"clt_version,omitempty"`
+	Xcodes             []XcodeInstall     `json:"xcodes,omitempty"`
+	License            string             `json:"license"` // accepted, not-accepted, not-applicable, unknown
+	SimulatorRuntimes  []SimulatorRuntime `json:"simulator_runtimes,omitempty"`
+	DerivedDataBytes   int64              `json:"derived_data_bytes"` // -1 = unknown
+}
+
+// XcodeInstall is an installed Xcode bundle.
+type XcodeInstall struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Build   string `json:"build"`
+	Active  bool   `json:"active"`
+}
+
+// SimulatorRuntime is an installed simulator runtime.
+type SimulatorRuntime struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	Build      string `json:"build"`
+	Identifier string `json:"identifier"`
+	Available  bool   `json:"available"`
+}
+
+// Containers contains container runtime and VM state.
+type Containers struct {
+	Status            Status  `json:"status"`
+	Error             string  `json:"error,omitempty"`
+	Installed         bool    `json:"installed"`
+	Runtime           string  `json:"runtime,omitempty"` // docker-desktop, colima, orbstack, podman, docker
+	Socket            string  `json:"socket,omitempty"`
+	Reachable         bool    `json:"reachable"`
+	PermissionDenied  bool    `json:"permission_denied,omitempty"` // socket exists but this user may not open it
+	ServerVersion     string  `json:"server_version,omitempty"`
+	OperatingSystem   string  `json:"operating_system,omitempty"`
+	CPUs              int     `json:"cpus"`
+	MemoryBytes       int64   `json:"memory_bytes"`
+	ContainersRunning int     `json:"containers_running"`
+	ContainersTotal   int     `json:"containers_total"`
+	Images            int     `json:"images"`
+	DiskUsageKnown    bool    `json:"disk_usage_known"`
+	ImagesBytes       int64   `json:"images_bytes"`
+	VolumesBytes      int64   `json:"volumes_bytes"`
+	BuildCacheBytes   int64   `json:"build_cache_bytes"`
+	VMDiskBytes       int64   `json:"vm_disk_bytes"` // 0 = unknown
+	VMDiskUsedBytes   int64   `json:"vm_disk_used_bytes"`
+	VMDiskPercent     float64 `json:"vm_disk_percent"`
+}
+
+// Packages contains package manager health.
+type Packages struct {
+	Status          Status            `json:"status"`
+	Error           string            `json:"error,omitempty"`
+	Manager         string            `json:"manager,omitempty"` // homebrew, apt
+	Version         string            `json:"version,omitempty"`
+	Prefix          string            `json:"prefix,omitempty"`
+	LastUpdate      string            `json:"last_update,omitempty"` // as reported by brew config
+	CheckedAt       time.Time         `json:"checked_at,omitzero"`
+	Outdated        []OutdatedPackage `json:"outdated,omitempty"` // formulae or apt packages
+	OutdatedCasks   []OutdatedPackage `json:"outdated_casks,omitempty"`
+	OutdatedCount   int               `json:"outdated_count"`
+	SecurityUpdates int               `json:"security_updates"`
+	DoctorWarnings  []string          `json:"doctor_warnings,omitempty"`
+}
+
+// OutdatedPackage is a package with a newer version available.
+type OutdatedPackage struct {
+	Name      string `json:"name"`
+	Installed string `json:"installed"`
+	Latest    string `json:"latest"`
+	Pinned    bool   `json:"pinned,omitempty"`
+	Security  bool   `json:"security,omitempty"`
+}
+
+// Services contains the state of the configured launchd or systemd services.
+type Services struct {
+	Status   Status         `json:"status"`
+	Error    string         `json:"error,omitempty"`
+	Services []ServiceState `json:"services,omitempty"`
+}
+
+// ServiceState is one launchd job or systemd unit.
+type ServiceState struct {
+	Name         string `json:"name"`
+	Manager      string `json:"manager"`          // launchd, systemd
+	Domain       string `json:"domain,omitempty"` // gui/<uid>, system, user
+	State        string `json:"state"`            // running, idle, stopped, crash-looping, not-found
+	Status       Status `json:"status"`
+	PID          int    `json:"pid,omitempty"`
+	Runs         int    `json:"runs"` // launchd runs, systemd NRestarts
+	LastExitCode *int   `json:"last_exit_code,omitempty"`
+	Detail       string `json:"detail,omitempty"`
+}
+
+// Certificates contains expiry information for signing identities and
+// configured certificates.
+type Certificates struct {
+	Status       Status            `json:"status"`
+	Error        string            `json:"error,omitempty"`
+	Certificates []CertificateInfo `json:"certificates,omitempty"`
+}
+
+// CertificateInfo is one certificate and how long it remains valid.
+type CertificateInfo struct {
+	Source   string    `json:"source"`   // keychain, file, endpoint
+	Name     string    `json:"name"`     // identity name or subject CN
+	Location string    `json:"location"` // SHA-1 hash, path or host:port
+	Issuer   string    `json:"issuer,omitempty"`
+	NotAfter time.Time `json:"not_after,omitzero"`
+	DaysLeft int       `json:"days_left"` // negative once expired
+	// Superseded counts older keychain identities with the same name; only
+	// the one that expires last is reported and rated.
+	Superseded int    `json:"superseded,omitempty"`
+	Status     Status `json:"status"`
+	Error      string `json:"error,omitempty"`
+}
+
+// CustomResult is the outcome of a user-defined check script.
+type CustomResult struct {
+	Name       string             `json:"name"`
+	Status     Status             `json:"status"`
+	Error      string             `json:"error,omitempty"`
+	Summary    string             `json:"summary,omitempty"`
+	Detail     string             `json:"detail,omitempty"`
+	Action     string             `json:"action,omitempty"`
+	Metrics    map[string]float64 `json:"metrics,omitempty"`
+	ExitCode   int                `json:"exit_code"` // -1 if the script did not exit
+	DurationMs int64              `json:"duration_ms"`
+	Weight     int                `json:"weight"`
 }
 
 // Diagnosis is a detailed explanation of a health issue.
@@ -0,0 +1,316 @@
+// Package mcp serves machealth as a Model Context Protocol server: JSON-RPC
+// 2.0 messages, one per line, over stdin and stdout.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/lu-zhengda/machealth/internal/health"
+)
+
+// protocolVersions are the MCP revisions this server speaks, newest first.
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// reportURI names the latest report resource.
+const reportURI = "machealth://report/latest"
+
+// maxMessage bounds one incoming line.
+const maxMessage = 4 << 20
+
+// JSON-RPC error codes.
+const (
+	codeParseError       = -32700
+	codeInvalidRequest   = -32600
+	codeMethodNotFound   = -32601
+	codeInvalidParams    = -32602
+	codeResourceNotFound = -32002
+)
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+// Server answers MCP requests with health checks.
+type Server struct {
+	cfg     health.Config
+	version string
+
+	// check runs a full health check; checkSubsystems runs some of them.
+	check           func(health.Config) health.Report
+	checkSubsystems func(health.Config, ...string) (health.Report, error)
+
+	outMu sync.Mutex
+	out   *json.Encoder
+
+	mu       sync.Mutex
+	latest   *health.Report
+	inflight map[string]context.CancelFunc // by request ID
+}
+
+// New returns a Server that runs full checks with check (health.Check, or a
+// function that asks the daemon) and reports version as its own.
+func New(cfg health.Config, version string, check func(health.Config) health.Report) *Server {
+	return &Server{
+		cfg:             cfg,
+		version:         version,
+		check:           check,
+		checkSubsystems: health.CheckSubsystems,
+		inflight:        map[string]context.CancelFunc{},
+	}
+}
+
+// Serve reads requests from r and writes responses to w until r reaches EOF
+// or ctx is done, then cancels the calls still running. Requests are handled
+// concurrently, so a long wait_until_healthy call does not hold up others.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.out = json.NewEncoder(w)
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	// Cancel in-flight calls before waiting for them, so a long
+	// wait_until_healthy does not outlive the client.
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	lines := make(chan []byte)
+	errc := make(chan error, 1)
+	go func() {
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 64<<10), maxMessage)
+		for sc.Scan() {
+			line := slices.Clone(sc.Bytes())
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		errc <- sc.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errc:
+			return err
+		case line := <-lines:
+			if len(line) == 0 {
+				continue
+			}
+			var req request
+			if err := json.Unmarshal(line, &req); err != nil {
+				s.write(response{ID: json.RawMessage("null"), Error: &rpcError{codeParseError, "parse error: " + err.Error()}})
+				continue
+			}
+			if req.ID == nil {
+				s.notify(req)
+				continue
+			}
+			wg.Go(func() { s.handle(ctx, req) })
+		}
+	}
+}
+
+func (s *Server) write(resp response) {
+	resp.JSONRPC = "2.0"
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	s.out.Encode(resp)
+}
+
+// notify handles a notification, which gets no response.
+func (s *Server) notify(req request) {
+	if req.Method != "notifications/cancelled" {
+		return
+	}
+	var p struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if json.Unmarshal(req.Params, &p) == nil {
+		s.mu.Lock()
+		if cancel, ok := s.inflight[string(p.RequestID)]; ok {
+			cancel()
+		}
+		s.mu.Unlock()
+	}
+}
+
+// handle answers one request, unless the client cancels it first.
+func (s *Server) handle(ctx context.Context, req request) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	key := string(req.ID)
+	s.mu.Lock()
+	s.inflight[key] = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.inflight, key)
+		s.mu.Unlock()
+	}()
+
+	result, err := s.dispatch(ctx, req)
+	if ctx.Err() != nil {
+		return
+	}
+	resp := response{ID: req.ID, Result: result}
+	if err != nil {
+		var rerr *rpcError
+		if !errors.As(err, &rerr) {
+			rerr = &rpcError{codeInvalidParams, err.Error()}
+		}
+		resp.Result, resp.Error = nil, rerr
+	}
+	s.write(resp)
+}
+
+func (s *Server) dispatch(ctx context.Context, req request) (any, error) {
+	if req.JSONRPC != "2.0" {
+		return nil, &rpcError{codeInvalidRequest, `jsonrpc must be "2.0"`}
+	}
+	switch req.Method {
+	case "initialize":
+		return s.initialize(req.Params)
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": toolList()}, nil
+	case "tools/call":
+		return s.callTool(ctx, req.Params)
+	case "resources/list":
+		return map[string]any{"resources": []map[string]any{{
+			"uri":         reportURI,
+			"name":        "latest-report",
+			"title":       "Latest health report",
+			"description": "The most recent full report from check, diagnose or wait_until_healthy; checks now if there is none yet.",
+			"mimeType":    "application/json",
+		}}}, nil
+	case "resources/read":
+		return s.readResource(req.Params)
+	}
+	return nil, &rpcError{codeMethodNotFound, "method not found: " + req.Method}
+}
+
+func (s *Server) initialize(params json.RawMessage) (any, error) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{codeInvalidParams, "invalid initialize params: " + err.Error()}
+	}
+	version := protocolVersions[0]
+	if slices.Contains(protocolVersions, p.ProtocolVersion) {
+		version = p.ProtocolVersion
+	}
+	return map[string]any{
+		"protocolVersion": version,
+		"capabilities": map[string]any{
+			"tools":     map[string]any{"listChanged": false},
+			"resources": map[string]any{"subscribe": false, "listChanged": false},
+		},
+		"serverInfo": map[string]any{"name": "machealth", "version": s.version},
+		"instructions": "machealth reports macOS system health. Call check for a scored report, " +
+			"diagnose for actionable explanations of non-green subsystems, check_subsystem to look at " +
+			"one subsystem cheaply, and wait_until_healthy before heavy work on a busy machine.",
+	}, nil
+}
+
+func (s *Server) readResource(params json.RawMessage) (any, error) {
+	var p struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{codeInvalidParams, "invalid params: " + err.Error()}
+	}
+	if p.URI != reportURI {
+		return nil, &rpcError{codeResourceNotFound, "resource not found: " + p.URI}
+	}
+	s.mu.Lock()
+	latest := s.latest
+	s.mu.Unlock()
+	var r health.Report
+	if latest != nil {
+		r = *latest
+	} else {
+		r = s.fullCheck()
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"contents": []map[string]any{{
+		"uri":      reportURI,
+		"mimeType": "application/json",
+		"text":     string(data),
+	}}}, nil
+}
+
+// fullCheck runs a full check and keeps it as the latest report.
+func (s *Server) fullCheck() health.Report {
+	r := s.check(s.cfg)
+	s.mu.Lock()
+	s.latest = &r
+	s.mu.Unlock()
+	return r
+}
+
+// callTool runs a tool. Failures of the tool itself are reported in the
+// result with isError, as MCP asks, so the model can see them.
+func (s *Server) callTool(ctx context.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{codeInvalidParams, "invalid params: " + err.Error()}
+	}
+	i := slices.IndexFunc(tools, func(t tool) bool { return t.name == p.Name })
+	if i < 0 {
+		return nil, &rpcError{codeInvalidParams, "unknown tool: " + p.Name}
+	}
+	if len(p.Arguments) == 0 || string(p.Arguments) == "null" {
+		p.Arguments = json.RawMessage("{}")
+	}
+
+	out, err := tools[i].call(ctx, s, p.Arguments)
+	if err != nil {
+		return map[string]any{
+			"content": []map[string]any{{"type": "text", "text": err.Error()}},
+			"isError": true,
+		}, nil
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("encoding result: %w", err)
+	}
+	return map[string]any{
+		"content":           []map[string]any{{"type": "text", "text": string(data)}},
+		"structuredContent": json.RawMessage(data),
+	}, nil
+}
@@ -0,0 +1,239 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lu-zhengda/machealth/internal/health"
+)
+
+// session runs a Server over pipes with check returning status.
+type session struct {
+	t      *testing.T
+	in     *io.PipeWriter
+	out    *bufio.Scanner
+	checks atomic.Int32
+	done   chan error
+}
+
+func newSession(t *testing.T, status func(n int32) health.Status) *session {
+	t.Helper()
+	ss := &session{t: t, done: make(chan error, 1)}
+	s := New(health.DefaultConfig(), "1.2.3", func(health.Config) health.Report {
+		n := ss.checks.Add(1)
+		st := status(n)
+		return health.Report{
+			Timestamp: time.Unix(1700000000, 0).UTC(),
+			Score:     health.Score{Status: st, Value: 90, Reasons: []string{}},
+			Memory:    health.Memory{Status: st, PressurePercent: 90},
+		}
+	})
+	s.checkSubsystems = func(cfg health.Config, names ...string) (health.Report, error) {
+		if names[0] == "gpu" {
+			return health.Report{}, fmt.Errorf("unknown subsystem %q", names[0])
+		}
+		st := status(ss.checks.Add(1))
+		return health.Report{Score: health.Score{Status: st}, Memory: health.Memory{Status: st, PressurePercent: 90}}, nil
+	}
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	ss.in = inW
+	ss.out = bufio.NewScanner(outR)
+	go func() {
+		ss.done <- s.Serve(context.Background(), inR, outW)
+		outW.Close()
+	}()
+	t.Cleanup(func() { inW.Close() })
+	return ss
+}
+
+func (ss *session) send(msg string) {
+	ss.t.Helper()
+	if _, err := io.WriteString(ss.in, msg+"\n"); err != nil {
+		ss.t.Fatal(err)
+	}
+}
+
+// read returns the next response.
+func (ss *session) read() map[string]any {
+	ss.t.Helper()
+	if !ss.out.Scan() {
+		ss.t.Fatalf("no response: %v", ss.out.Err())
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(ss.out.Bytes(), &resp); err != nil {
+		ss.t.Fatalf("bad response %s: %v", ss.out.Bytes(), err)
+	}
+	return resp
+}
+
+// call sends a request and returns its response.
+func (ss *session) call(id int, method, params string) map[string]any {
+	ss.t.Helper()
+	ss.send(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":%q,"params":%s}`, id, method, params))
+	resp := ss.read()
+	if resp["id"] != float64(id) {
+		ss.t.Fatalf("response id = %v, want %d", resp["id"], id)
+	}
+	return resp
+}
+
+func green(int32) health.Status { return health.StatusGreen }
+
+func TestServe_Initialize(t *testing.T) {
+	ss := newSession(t, green)
+
+	res := ss.call(1, "initialize", `{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"0"}}`)["result"].(map[string]any)
+	if res["protocolVersion"] != "2025-03-26" || res["serverInfo"].(map[string]any)["version"] != "1.2.3" {
+		t.Errorf("initialize = %v", res)
+	}
+	res = ss.call(2, "initialize", `{"protocolVersion":"1999-01-01"}`)["result"].(map[string]any)
+	if res["protocolVersion"] != protocolVersions[0] {
+		t.Errorf("unsupported version answered with %v", res["protocolVersion"])
+	}
+
+	ss.send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	if res := ss.call(3, "ping", `{}`); res["result"] == nil {
+		t.Errorf("ping = %v", res)
+	}
+	if err := ss.call(4, "resources/subscribe", `{}`)["error"].(map[string]any); err["code"] != float64(codeMethodNotFound) {
+		t.Errorf("unknown method = %v", err)
+	}
+	ss.send(`{not json`)
+	if err := ss.read()["error"].(map[string]any); err["code"] != float64(codeParseError) {
+		t.Errorf("parse error = %v", err)
+	}
+
+	ss.in.Close()
+	if err := <-ss.done; err != nil {
+		t.Errorf("Serve() = %v", err)
+	}
+}
+
+func TestServe_Tools(t *testing.T) {
+	ss := newSession(t, green)
+
+	list := ss.call(1, "tools/list", `{}`)["result"].(map[string]any)["tools"].([]any)
+	var names []string
+	for _, tl := range list {
+		tl := tl.(map[string]any)
+		names = append(names, tl["name"].(string))
+		if tl["inputSchema"].(map[string]any)["type"] != "object" || tl["outputSchema"] == nil {
+			t.Errorf("%s schemas = %v", tl["name"], tl)
+		}
+	}
+	if strings.Join(names, ",") != "check,diagnose,check_subsystem,wait_until_healthy" {
+		t.Errorf("tools = %v", names)
+	}
+
+	res := ss.call(2, "tools/call", `{"name":"check","arguments":{}}`)["result"].(map[string]any)
+	report := res["structuredContent"].(map[string]any)
+	if report["memory"].(map[string]any)["pressure_percent"] != float64(90) || res["isError"] != nil {
+		t.Errorf("check = %v", res)
+	}
+	if text := res["content"].([]any)[0].(map[string]any)["text"].(string); !strings.Contains(text, `"pressure_percent":90`) {
+		t.Errorf("check text = %s", text)
+	}
+
+	res = ss.call(3, "tools/call", `{"name":"diagnose"}`)["result"].(map[string]any)
+	if _, ok := res["structuredContent"].(map[string]any)["diagnoses"]; !ok {
+		t.Errorf("diagnose = %v", res)
+	}
+
+	res = ss.call(4, "tools/call", `{"name":"check_subsystem","arguments":{"name":"memory"}}`)["result"].(map[string]any)
+	sub := res["structuredContent"].(map[string]any)
+	if sub["subsystem"] != "memory" || sub["status"] != "green" || sub["result"].(map[string]any)["pressure_percent"] != float64(90) {
+		t.Errorf("check_subsystem = %v", sub)
+	}
+	if diags := sub["diagnoses"].([]any); len(diags) != 0 {
+		t.Errorf("diagnoses of other subsystems leaked: %v", diags)
+	}
+
+	for id, args := range map[int]string{5: `{"name":"gpu"}`, 6: `{}`, 7: `{"name":"cpu","extra":1}`} {
+		res := ss.call(id, "tools/call", `{"name":"check_subsystem","arguments":`+args+`}`)["result"].(map[string]any)
+		if res["isError"] != true {
+			t.Errorf("check_subsystem %s = %v", args, res)
+		}
+	}
+	if err := ss.call(8, "tools/call", `{"name":"reboot"}`)["error"].(map[string]any); err["code"] != float64(codeInvalidParams) {
+		t.Errorf("unknown tool = %v", err)
+	}
+}
+
+func TestServe_Resource(t *testing.T) {
+	ss := newSession(t, green)
+
+	if list := ss.call(1, "resources/list", `{}`)["result"].(map[string]any)["resources"].([]any); list[0].(map[string]any)["uri"] != reportURI {
+		t.Errorf("resources = %v", list)
+	}
+	ss.call(2, "tools/call", `{"name":"check"}`)
+	contents := ss.call(3, "resources/read", `{"uri":"`+reportURI+`"}`)["result"].(map[string]any)["contents"].([]any)
+	if c := contents[0].(map[string]any); c["mimeType"] != "application/json" || !strings.Contains(c["text"].(string), `"pressure_percent": 90`) {
+		t.Errorf("contents = %v", c)
+	}
+	if n := ss.checks.Load(); n != 1 {
+		t.Errorf("reading the resource ran another check (%d)", n)
+	}
+	if err := ss.call(4, "resources/read", `{"uri":"machealth://nope"}`)["error"].(map[string]any); err["code"] != float64(codeResourceNotFound) {
+		t.Errorf("unknown resource = %v", err)
+	}
+}
+
+func TestServe_WaitUntilHealthy(t *testing.T) {
+	// Red for the first check, green afterwards.
+	ss := newSession(t, func(n int32) health.Status {
+		if n == 1 {
+			return health.StatusRed
+		}
+		return health.StatusGreen
+	})
+	res := ss.call(1, "tools/call", `{"name":"wait_until_healthy","arguments":{"subsystems":["memory"],"interval_seconds":1,"timeout_seconds":10}}`)["result"].(map[string]any)
+	out := res["structuredContent"].(map[string]any)
+	if out["healthy"] != true || out["checks"] != float64(2) {
+		t.Errorf("wait = %v", out)
+	}
+}
+
+func TestServe_EOFCancelsWait(t *testing.T) {
+	ss := newSession(t, func(int32) health.Status { return health.StatusRed })
+	ss.send(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"wait_until_healthy","arguments":{"timeout_seconds":3600}}}`)
+	time.Sleep(50 * time.Millisecond)
+	ss.in.Close()
+	select {
+	case err := <-ss.done:
+		if err != nil {
+			t.Errorf("Serve() = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after stdin closed during a wait")
+	}
+}
+
+func TestServe_WaitTimeoutAndCancel(t *testing.T) {
+	ss := newSession(t, func(int32) health.Status { return health.StatusRed })
+
+	res := ss.call(1, "tools/call", `{"name":"wait_until_healthy","arguments":{"timeout_seconds":1,"interval_seconds":1}}`)["result"].(map[string]any)
+	if out := res["structuredContent"].(map[string]any); out["healthy"] != false || out["status"] != "red" {
+		t.Errorf("timed out wait = %v", out)
+	}
+
+	// A cancelled call gets no response; the next request is still answered.
+	ss.send(`{"jsonrpc":"2.0","id":"w","method":"tools/call","params":{"name":"wait_until_healthy","arguments":{"timeout_seconds":60}}}`)
+	time.Sleep(50 * time.Millisecond)
+	ss.send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"w"}}`)
+	if resp := ss.call(2, "ping", `{}`); resp["result"] == nil {
+		t.Errorf("ping after cancel = %v", resp)
+	}
+	ss.in.Close()
+	if ss.out.Scan() {
+		t.Errorf("unexpected response to cancelled request: %s", ss.out.Bytes())
+	}
+}
@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lu-zhengda/machealth/internal/health"
+)
+
+var (
+	timeType   = reflect.TypeFor[time.Time]()
+	statusType = reflect.TypeFor[health.Status]()
+)
+
+// statusSchema describes a health.Status.
+func statusSchema() map[string]any {
+	return map[string]any{"type": "string", "enum": []string{"green", "yellow", "red"}}
+}
+
+// schemaFor returns a JSON Schema for the encoding/json output of type t.
+// Fields without omitempty or omitzero are required; nil slices, maps and
+// pointers encode as null and are allowed to.
+func schemaFor(t reflect.Type) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case statusType:
+		return statusSchema()
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(schemaFor(t.Elem()))
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return nullable(map[string]any{"type": "array", "items": schemaFor(t.Elem())})
+	case reflect.Map:
+		return nullable(map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem())})
+	case reflect.Struct:
+		props := map[string]any{}
+		var required []string
+		addFields(t, props, &required)
+		s := map[string]any{"type": "object", "properties": props}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	}
+	return map[string]any{}
+}
+
+// addFields adds the JSON fields of struct t, including those promoted
+// from embedded structs, to props.
+func addFields(t reflect.Type, props map[string]any, required *[]string) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			addFields(f.Type, props, required)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = schemaFor(f.Type)
+		flags := strings.Split(opts, ",")
+		if !slices.Contains(flags, "omitempty") && !slices.Contains(flags, "omitzero") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// nullable lets s also match null.
+func nullable(s map[string]any) map[string]any {
+	if t, ok := s["type"].(string); ok {
+		s["type"] = []string{t, "null"}
+	}
+	return s
+}
@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lu-zhengda/machealth/internal/health"
+)
+
+func TestSchemaFor(t *testing.T) {
+	s := schemaFor(reflect.TypeFor[health.DiagnoseReport]())
+	props := s["properties"].(map[string]any)
+	required := s["required"].([]string)
+
+	// Fields of the embedded Report are promoted.
+	for _, name := range []string{"timestamp", "score", "cpu", "diagnoses"} {
+		if props[name] == nil || !slices.Contains(required, name) {
+			t.Errorf("%s missing or not required", name)
+		}
+	}
+	if props["custom"] == nil || slices.Contains(required, "custom") {
+		t.Error("omitempty field custom should be optional")
+	}
+	if ts := props["timestamp"].(map[string]any); ts["format"] != "date-time" {
+		t.Errorf("timestamp = %v", ts)
+	}
+
+	cpu := props["cpu"].(map[string]any)["properties"].(map[string]any)
+	if got := cpu["status"].(map[string]any)["enum"]; !reflect.DeepEqual(got, []string{"green", "yellow", "red"}) {
+		t.Errorf("status enum = %v", got)
+	}
+	if got := cpu["logical_cores"].(map[string]any)["type"]; got != "integer" {
+		t.Errorf("logical_cores type = %v", got)
+	}
+
+	code := schemaFor(reflect.TypeFor[health.ServiceState]())["properties"].(map[string]any)["last_exit_code"].(map[string]any)
+	if !reflect.DeepEqual(code["type"], []string{"integer", "null"}) {
+		t.Errorf("pointer type = %v", code["type"])
+	}
+	if diags := props["diagnoses"].(map[string]any); !reflect.DeepEqual(diags["type"], []string{"array", "null"}) {
+		t.Errorf("slice type = %v", diags["type"])
+	}
+}
+
+func TestSchemaFor_MatchesReportJSON(t *testing.T) {
+	r := health.Report{Timestamp: time.Now(), Custom: []health.CustomResult{{Name: "x"}}}
+	data, _ := json.Marshal(r)
+	var fields map[string]any
+	json.Unmarshal(data, &fields)
+
+	s := schemaFor(reflect.TypeFor[health.Report]())
+	props := s["properties"].(map[string]any)
+	for name := range fields {
+		if props[name] == nil {
+			t.Errorf("report field %q not in schema", name)
+		}
+	}
+	for _, name := range s["required"].([]string) {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("required field %q missing from report JSON", name)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lu-zhengda/machealth/internal/health"
+)
+
+// tool is one MCP tool. call decodes its arguments and returns a value that
+// encodes to a JSON object matching outputSchema.
+type tool struct {
+	name, title, description  string
+	inputSchema, outputSchema map[string]any
+	call                      func(ctx context.Context, s *Server, args json.RawMessage) (any, error)
+}
+
+// subsystemResult is the output of check_subsystem.
+type subsystemResult struct {
+	Subsystem string             `json:"subsystem"`
+	Status    health.Status      `json:"status"`
+	Result    json.RawMessage    `json:"result"`
+	Diagnoses []health.Diagnosis `json:"diagnoses"`
+}
+
+// waitResult is the output of wait_until_healthy.
+type waitResult struct {
+	Healthy       bool          `json:"healthy"`
+	Status        health.Status `json:"status"`
+	Reasons       []string      `json:"reasons"`
+	Checks        int           `json:"checks"`
+	WaitedSeconds float64       `json:"waited_seconds"`
+}
+
+// Limits on wait_until_healthy arguments, in seconds.
+const (
+	defaultWaitTimeout  = 300
+	maxWaitTimeout      = 3600
+	defaultWaitInterval = 5
+	maxWaitInterval     = 300
+)
+
+func objectSchema(props map[string]any, required ...string) map[string]any {
+	s := map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func subsystemSchema() map[string]any {
+	return map[string]any{"type": "string", "enum": health.SubsystemNames()}
+}
+
+var tools = []tool{
+	{
+		name:  "check",
+		title: "Check system health",
+		description: "Run every health check and return the full report: a composite score " +
+			"(0-100, green/yellow/red) and per-subsystem data for CPU, memory, disk, thermal, " +
+			"network, battery and more.",
+		inputSchema:  objectSchema(map[string]any{}),
+		outputSchema: schemaFor(reflect.TypeFor[health.Report]()),
+		call: func(ctx context.Context, s *Server, args json.RawMessage) (any, error) {
+			return s.fullCheck(), nil
+		},
+	},
+	{
+		name:  "diagnose",
+		title: "Diagnose health issues",
+		description: "Run every health check and explain each non-green subsystem with a " +
+			"summary, detail and a suggested action.",
+		inputSchema:  objectSchema(map[string]any{}),
+		outputSchema: schemaFor(reflect.TypeFor[health.DiagnoseReport]()),
+		call: func(ctx context.Context, s *Server, args json.RawMessage) (any, error) {
+			return health.DiagnoseFrom(s.fullCheck()), nil
+		},
+	},
+	{
+		name:  "check_subsystem",
+		title: "Check one subsystem",
+		description: "Run a single subsystem's check, which is much cheaper than a full check, " +
+			"and return its data, status and diagnoses.",
+		inputSchema: objectSchema(map[string]any{
+			"name": subsystemSchema(),
+		}, "name"),
+		outputSchema: objectSchema(map[string]any{
+			"subsystem": map[string]any{"type": "string"},
+			"status":    statusSchema(),
+			"result":    map[string]any{"description": "The subsystem's section of the check report."},
+			"diagnoses": schemaFor(reflect.TypeFor[[]health.Diagnosis]()),
+		}, "subsystem", "status", "result", "diagnoses"),
+		call: callCheckSubsystem,
+	},
+	{
+		name:  "wait_until_healthy",
+		title: "Wait until healthy",
+		description: "Re-check every interval_seconds until the selected subsystems (default all) " +
+			"are no worse than status (default green), or timeout_seconds passes. Returns " +
+			"healthy=false on timeout. Use before heavy work such as builds on a loaded machine.",
+		inputSchema: objectSchema(map[string]any{
+			"subsystems": map[string]any{
+				"type": "array", "items": subsystemSchema(), "uniqueItems": true,
+				"description": "Subsystems to wait for; all when omitted.",
+			},
+			"status": map[string]any{
+				"type": "string", "enum": []string{"green", "yellow"}, "default": "green",
+				"description": "Worst acceptable status.",
+			},
+			"timeout_seconds": map[string]any{
+				"type": "integer", "minimum": 1, "maximum": maxWaitTimeout, "default": defaultWaitTimeout,
+			},
+			"interval_seconds": map[string]any{
+				"type": "integer", "minimum": 1, "maximum": maxWaitInterval, "default": defaultWaitInterval,
+			},
+		}),
+		outputSchema: schemaFor(reflect.TypeFor[waitResult]()),
+		call:         callWaitUntilHealthy,
+	},
+}
+
+// toolList returns the tools as tools/list describes them.
+func toolList() []map[string]any {
+	list := make([]map[string]any, len(tools))
+	for i, t := range tools {
+		list[i] = map[string]any{
+			"name":         t.name,
+			"title":        t.title,
+			"description":  t.description,
+			"inputSchema":  t.inputSchema,
+			"outputSchema": t.outputSchema,
+		}
+	}
+	return list
+}
+
+// decodeArgs decodes tool arguments, rejecting unknown ones.
+func decodeArgs(args json.RawMessage, v any) error {
+	dec := json.NewDecoder(strings.NewReader(string(args)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+func callCheckSubsystem(ctx context.Context, s *Server, args json.RawMessage) (any, error) {
+	var a struct {
+		Name string `json:"name"`
+	}
+	if err := decodeArgs(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Name == "" {
+		return nil, fmt.Errorf("name is required (one of %s)", strings.Join(health.SubsystemNames(), ", "))
+	}
+	r, err := s.checkSubsystems(s.cfg, a.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	result := fields[a.Name]
+	if result == nil {
+		result = json.RawMessage("null")
+	}
+
+	// Diagnosers see the other, uncollected subsystems as not green, so
+	// keep only this subsystem's diagnoses.
+	diagnoses := []health.Diagnosis{}
+	for _, d := range health.DiagnoseFrom(r).Diagnoses {
+		if d.Subsystem == a.Name || a.Name == "custom" && strings.HasPrefix(d.Subsystem, "custom:") {
+			diagnoses = append(diagnoses, d)
+		}
+	}
+	return subsystemResult{Subsystem: a.Name, Status: r.Score.Status, Result: result, Diagnoses: diagnoses}, nil
+}
+
+func callWaitUntilHealthy(ctx context.Context, s *Server, args json.RawMessage) (any, error) {
+	a := struct {
+		Subsystems      []string      `json:"subsystems"`
+		Status          health.Status `json:"status"`
+		TimeoutSeconds  int           `json:"timeout_seconds"`
+		IntervalSeconds int           `json:"interval_seconds"`
+	}{Status: health.StatusGreen, TimeoutSeconds: defaultWaitTimeout, IntervalSeconds: defaultWaitInterval}
+	if err := decodeArgs(args, &a); err != nil {
+		return nil, err
+	}
+	if a.Status != health.StatusGreen && a.Status != health.StatusYellow {
+		return nil, fmt.Errorf("status must be green or yellow, not %q", a.Status)
+	}
+	if a.TimeoutSeconds < 1 || a.TimeoutSeconds > maxWaitTimeout {
+		return nil, fmt.Errorf("timeout_seconds must be between 1 and %d", maxWaitTimeout)
+	}
+	if a.IntervalSeconds < 1 || a.IntervalSeconds > maxWaitInterval {
+		return nil, fmt.Errorf("interval_seconds must be between 1 and %d", maxWaitInterval)
+	}
+	names := health.SubsystemNames()
+	for _, name := range a.Subsystems {
+		if !slices.Contains(names, name) {
+			return nil, fmt.Errorf("unknown subsystem %q", name)
+		}
+	}
+
+	check := func() (health.Report, error) {
+		if len(a.Subsystems) == 0 {
+			return s.fullCheck(), nil
+		}
+		return s.checkSubsystems(s.cfg, a.Subsystems...)
+	}
+	acceptable := func(st health.Status) bool {
+		return st == health.StatusGreen || a.Status == health.StatusYellow && st == health.StatusYellow
+	}
+
+	start := time.Now()
+	deadline := time.NewTimer(time.Duration(a.TimeoutSeconds) * time.Second)
+	defer deadline.Stop()
+	ticker := time.NewTicker(time.Duration(a.IntervalSeconds) * time.Second)
+	defer ticker.Stop()
+	for checks := 1; ; checks++ {
+		r, err := check()
+		if err != nil {
+			return nil, err
+		}
+		res := waitResult{
+			Healthy:       acceptable(r.Score.Status),
+			Status:        r.Score.Status,
+			Reasons:       r.Score.Reasons,
+			Checks:        checks,
+			WaitedSeconds: time.Since(start).Round(time.Millisecond).Seconds(),
+		}
+		if res.Healthy {
+			return res, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline.C:
+			return res, nil
+		case <-ticker.C:
+		}
+	}
+}
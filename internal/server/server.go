@@ -0,0 +1,212 @@
+// Package server serves machealth reports over HTTP.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lu-zhengda/machealth/internal/health"
+)
+
+// Options configures a Server.
+type Options struct {
+	// Interval is how often checks run in the background. If zero, no
+	// background checks run and every request runs Check, which is then
+	// expected to do its own caching (see health.Collector).
+	Interval time.Duration
+	// Token, if set, must be sent as "Authorization: Bearer <token>".
+	Token string
+	// Check runs a health check; nil uses health.Check.
+	Check func(health.Config) health.Report
+	// ForceCheck runs a check that bypasses any caching Check does, for
+	// ?refresh=1; nil uses Check.
+	ForceCheck func(health.Config) health.Report
+}
+
+// Server runs health checks on a schedule and serves the latest report.
+type Server struct {
+	cfg  health.Config
+	opts Options
+
+	check      func(health.Config) health.Report
+	forceCheck func(health.Config) health.Report
+
+	checkMu sync.Mutex // serialises checks
+	mu      sync.RWMutex
+	report  health.Report
+	ready   bool
+}
+
+// New returns a Server that checks with cfg.
+func New(cfg health.Config, opts Options) *Server {
+	check := opts.Check
+	if check == nil {
+		check = health.Check
+	}
+	forceCheck := opts.ForceCheck
+	if forceCheck == nil {
+		forceCheck = check
+	}
+	return &Server{cfg: cfg, opts: opts, check: check, forceCheck: forceCheck}
+}
+
+// Run checks immediately and then every Interval until ctx is done.
+func (s *Server) Run(ctx context.Context) {
+	s.Refresh()
+	if s.opts.Interval <= 0 {
+		<-ctx.Done()
+		return
+	}
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Refresh()
+		}
+	}
+}
+
+// Refresh runs a check now, stores it as the latest report and returns it.
+// Concurrent callers wait for one another rather than running checks in
+// parallel.
+func (s *Server) Refresh() health.Report {
+	s.checkMu.Lock()
+	defer s.checkMu.Unlock()
+	return s.refreshLocked(s.check)
+}
+
+// forceRefresh is Refresh with ForceCheck.
+func (s *Server) forceRefresh() health.Report {
+	s.checkMu.Lock()
+	defer s.checkMu.Unlock()
+	return s.refreshLocked(s.forceCheck)
+}
+
+// refreshLocked runs check and stores the report; s.checkMu must be held.
+func (s *Server) refreshLocked(check func(health.Config) health.Report) health.Report {
+	r := check(s.cfg)
+	s.mu.Lock()
+	s.report, s.ready = r, true
+	s.mu.Unlock()
+	return r
+}
+
+// Latest returns the most recent report, running a check if there is none yet.
+func (s *Server) Latest() health.Report {
+	s.mu.RLock()
+	r, ready := s.report, s.ready
+	s.mu.RUnlock()
+	if !ready {
+		return s.Refresh()
+	}
+	return r
+}
+
+// Handler returns the HTTP handler serving /metrics, /report, /diagnose,
+// /healthz (also available as /health), and the /events (Server-Sent Events)
+// and /ws (WebSocket) report streams.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	mux.HandleFunc("GET /report", s.handleReport)
+	mux.HandleFunc("GET /diagnose", s.handleDiagnose)
+	mux.HandleFunc("GET /healthz", s.handleHealthz)
+	mux.HandleFunc("GET /health", s.handleHealthz)
+	mux.HandleFunc("GET /events", s.handleEvents)
+	mux.HandleFunc("GET /ws", s.handleWebSocket)
+	return s.authorize(mux)
+}
+
+// authorize rejects requests without the bearer token when one is configured.
+func (s *Server) authorize(next http.Handler) http.Handler {
+	if s.opts.Token == "" {
+		return next
+	}
+	want := []byte("Bearer " + s.opts.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="machealth"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// reportFor returns a fresh report for ?refresh=1, bypassing Check's own
+// caching, runs Check for on-demand servers, and returns the latest report
+// otherwise.
+func (s *Server) reportFor(r *http.Request) health.Report {
+	switch strings.ToLower(r.URL.Query().Get("refresh")) {
+	case "1", "true", "yes":
+		return s.forceRefresh()
+	}
+	if s.opts.Interval <= 0 {
+		return s.Refresh()
+	}
+	return s.Latest()
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	report := s.reportFor(r)
+	w.Header().Set("Content-Type", health.PrometheusContentType)
+	health.WritePrometheus(w, report)
+}
+
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.reportFor(r))
+}
+
+func (s *Server) handleDiagnose(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, health.DiagnoseFrom(s.reportFor(r)))
+}
+
+// healthzResponse is the body of /healthz.
+type healthzResponse struct {
+	Status    health.Status `json:"status"`
+	Score     int           `json:"score"`
+	ExitCode  int           `json:"exit_code"`
+	Reasons   []string      `json:"reasons"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// HealthzStatusCode maps a report's exit code to an HTTP status: healthy is
+// 200, degraded 429 and critical 503, which load balancers and Consul-style
+// checks read as passing, warning and critical.
+func HealthzStatusCode(r health.Report) int {
+	switch health.ExitCode(r) {
+	case 0:
+		return http.StatusOK
+	case 1:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusServiceUnavailable
+	}
+}
+
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	report := s.reportFor(r)
+	writeJSON(w, HealthzStatusCode(report), healthzResponse{
+		Status:    report.Score.Status,
+		Score:     report.Score.Value,
+		ExitCode:  health.ExitCode(report),
+		Reasons:   report.Score.Reasons,
+		Timestamp: report.Timestamp,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lu-zhengda/machealth/internal/health"
+)
+
+// newTestServer returns a Server whose checks report status and count calls.
+func newTestServer(status health.Status, opts Options) (*Server, *atomic.Int32) {
+	var calls atomic.Int32
+	s := New(health.DefaultConfig(), opts)
+	s.check = func(health.Config) health.Report {
+		n := calls.Add(1)
+		return health.Report{
+			Timestamp: time.Unix(1700000000+int64(n), 0).UTC(),
+			Score:     health.Score{Status: status, Value: 100 - int(n), Reasons: []string{}},
+			Memory:    health.Memory{Status: status, PressurePercent: 42},
+		}
+	}
+	s.forceCheck = s.check
+	return s, &calls
+}
+
+func get(t *testing.T, h http.Handler, target, token string) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Result()
+}
+
+func TestServer_Endpoints(t *testing.T) {
+	s, calls := newTestServer(health.StatusGreen, Options{Interval: time.Hour})
+	h := s.Handler()
+
+	resp := get(t, h, "/report", "")
+	var r health.Report
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || r.Memory.PressurePercent != 42 || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("/report = %d %+v", resp.StatusCode, r.Memory)
+	}
+
+	resp = get(t, h, "/metrics", "")
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4") ||
+		!strings.Contains(string(body), "machealth_memory_pressure_percent 42\n") {
+		t.Errorf("/metrics = %s\n%s", resp.Header.Get("Content-Type"), body)
+	}
+
+	resp = get(t, h, "/diagnose", "")
+	var dr health.DiagnoseReport
+	if err := json.NewDecoder(resp.Body).Decode(&dr); err != nil || dr.Diagnoses == nil {
+		t.Errorf("/diagnose = %v %+v", err, dr)
+	}
+
+	resp = get(t, h, "/healthz", "")
+	var hz healthzResponse
+	json.NewDecoder(resp.Body).Decode(&hz)
+	if resp.StatusCode != http.StatusOK || hz.Status != health.StatusGreen || hz.ExitCode != 0 {
+		t.Errorf("/healthz = %d %+v", resp.StatusCode, hz)
+	}
+
+	// All requests were served from the one cached report.
+	if n := calls.Load(); n != 1 {
+		t.Errorf("checks run = %d, want 1", n)
+	}
+
+	if resp := get(t, h, "/nope", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("/nope = %d", resp.StatusCode)
+	}
+}
+
+func TestServer_Refresh(t *testing.T) {
+	s, calls := newTestServer(health.StatusGreen, Options{Interval: time.Hour})
+	h := s.Handler()
+
+	get(t, h, "/report", "")
+	resp := get(t, h, "/report?refresh=1", "")
+	var r health.Report
+	json.NewDecoder(resp.Body).Decode(&r)
+	if n := calls.Load(); n != 2 || r.Score.Value != 98 {
+		t.Errorf("after refresh: checks = %d, score = %d", n, r.Score.Value)
+	}
+	get(t, h, "/report", "")
+	if n := calls.Load(); n != 2 {
+		t.Errorf("cached request ran a check: %d", n)
+	}
+}
+
+func TestServer_Run(t *testing.T) {
+	s, calls := newTestServer(health.StatusGreen, Options{Interval: 10 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	time.Sleep(55 * time.Millisecond)
+	cancel()
+	<-done
+	if n := calls.Load(); n < 3 {
+		t.Errorf("checks run = %d, want at least 3", n)
+	}
+}
+
+func TestServer_Auth(t *testing.T) {
+	s, _ := newTestServer(health.StatusGreen, Options{Interval: time.Hour, Token: "s3cret"})
+	h := s.Handler()
+
+	for _, token := range []string{"", "wrong"} {
+		resp := get(t, h, "/report", token)
+		if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+			t.Errorf("token %q: status = %d", token, resp.StatusCode)
+		}
+	}
+	if resp := get(t, h, "/metrics", "s3cret"); resp.StatusCode != http.StatusOK {
+		t.Errorf("valid token: status = %d", resp.StatusCode)
+	}
+}
+
+func TestHealthzStatusCode(t *testing.T) {
+	tests := []struct {
+		status health.Status
+		want   int
+	}{
+		{health.StatusGreen, http.StatusOK},
+		{health.StatusYellow, http.StatusTooManyRequests},
+		{health.StatusRed, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		s, _ := newTestServer(tt.status, Options{Interval: time.Hour})
+		if resp := get(t, s.Handler(), "/healthz", ""); resp.StatusCode != tt.want {
+			t.Errorf("%s: /healthz = %d, want %d", tt.status, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestServer_OnDemand(t *testing.T) {
+	var calls atomic.Int32
+	s := New(health.DefaultConfig(), Options{Check: func(health.Config) health.Report {
+		calls.Add(1)
+		return health.Report{Score: health.Score{Status: health.StatusGreen}}
+	}})
+	h := s.Handler()
+	get(t, h, "/report", "")
+	get(t, h, "/healthz", "")
+	if n := calls.Load(); n != 2 {
+		t.Errorf("checks run = %d, want one per request", n)
+	}
+}
+
+func TestServer_OnDemandForceRefresh(t *testing.T) {
+	var checks, forced atomic.Int32
+	s := New(health.DefaultConfig(), Options{
+		Check: func(health.Config) health.Report {
+			checks.Add(1)
+			return health.Report{Score: health.Score{Status: health.StatusGreen}}
+		},
+		ForceCheck: func(health.Config) health.Report {
+			forced.Add(1)
+			return health.Report{Score: health.Score{Status: health.StatusGreen}}
+		},
+	})
+	h := s.Handler()
+	get(t, h, "/report?refresh=1", "")
+	get(t, h, "/report", "")
+	if checks.Load() != 1 || forced.Load() != 1 {
+		t.Errorf("checks = %d, forced = %d, want 1 each", checks.Load(), forced.Load())
+	}
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lu-zhengda/machealth/internal/health"
+)
+
+// minStreamInterval is the shortest update interval a client may request.
+const minStreamInterval = time.Second
+
+// streamParams are the query parameters shared by /events and /ws.
+type streamParams struct {
+	subsystems []string // nil means all
+	interval   time.Duration
+	changes    bool   // send only subsystems that changed since the last event
+	lastID     string // resume token: the ID of the last event the client saw
+}
+
+// parseStreamParams reads ?subsystems=cpu,memory, ?interval=10s,
+// ?changes=1 and ?last_event_id=.
+func (s *Server) parseStreamParams(r *http.Request) (streamParams, error) {
+	q := r.URL.Query()
+	p := streamParams{interval: s.opts.Interval, lastID: q.Get("last_event_id")}
+	if p.interval <= 0 {
+		p.interval = 5 * time.Second
+	}
+	if v := q.Get("interval"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return p, fmt.Errorf("invalid interval: %w", err)
+		}
+		if d < minStreamInterval {
+			return p, fmt.Errorf("interval must be at least %s", minStreamInterval)
+		}
+		p.interval = d
+	}
+	if v := q.Get("subsystems"); v != "" {
+		names := health.SubsystemNames()
+		for name := range strings.SplitSeq(v, ",") {
+			name = strings.TrimSpace(name)
+			if !slices.Contains(names, name) {
+				return p, fmt.Errorf("unknown subsystem %q (use %s)", name, strings.Join(names, ", "))
+			}
+			p.subsystems = append(p.subsystems, name)
+		}
+	}
+	switch strings.ToLower(q.Get("changes")) {
+	case "1", "true", "yes":
+		p.changes = true
+	}
+	return p, nil
+}
+
+// eventID identifies a report; clients send it back to resume a stream.
+func eventID(r health.Report) string {
+	return strconv.FormatInt(r.Timestamp.UnixNano(), 10)
+}
+
+// fresh returns the report a stream should send next. With background
+// checks it is always the latest one, so a client asking for a shorter
+// interval than the server's polls the cache rather than forcing checks.
+// On-demand servers run a check only if the stored report is older than
+// maxAge; streams waiting on the same check share its result.
+func (s *Server) fresh(maxAge time.Duration) health.Report {
+	if s.opts.Interval > 0 {
+		return s.Latest()
+	}
+	s.checkMu.Lock()
+	defer s.checkMu.Unlock()
+	s.mu.RLock()
+	r, ready := s.report, s.ready
+	s.mu.RUnlock()
+	if ready && time.Since(r.Timestamp) < maxAge {
+		return r
+	}
+	return s.refreshLocked(s.check)
+}
+
+// stream calls send with an event for the latest report and then for every
+// new report each interval, until ctx is done or send fails. A report whose
+// ID matches p.lastID is not sent again, so a resuming client only gets the
+// latest report immediately if it missed it.
+func (s *Server) stream(ctx context.Context, p streamParams, send func(id string, data []byte) error) error {
+	var prev map[string]json.RawMessage
+	lastID := p.lastID
+	emit := func(r health.Report) error {
+		id := eventID(r)
+		if id == lastID {
+			return nil
+		}
+		lastID = id
+		data, fields, err := streamPayload(r, p, prev)
+		prev = fields
+		if err != nil || data == nil {
+			return err
+		}
+		return send(id, data)
+	}
+
+	if err := emit(s.Latest()); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := emit(s.fresh(p.interval)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// streamPayload encodes the timestamp, score and selected subsystems of r,
+// in report order. With p.changes, subsystems equal to those in prev are
+// left out and a nil payload is returned when none changed. It also returns
+// every encoded subsystem of r, to pass as prev next time.
+func streamPayload(r health.Report, p streamParams, prev map[string]json.RawMessage) ([]byte, map[string]json.RawMessage, error) {
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return nil, prev, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, prev, err
+	}
+
+	names := p.subsystems
+	if names == nil {
+		names = health.SubsystemNames()
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `{"timestamp":%s,"score":%s`, fields["timestamp"], fields["score"])
+	changed := false
+	for _, name := range names {
+		v, ok := fields[name]
+		if !ok || p.changes && prev != nil && bytes.Equal(prev[name], v) {
+			continue
+		}
+		fmt.Fprintf(&b, `,%q:%s`, name, v)
+		changed = true
+	}
+	b.WriteByte('}')
+	if p.changes && prev != nil && !changed {
+		return nil, fields, nil
+	}
+	return b.Bytes(), fields, nil
+}
+
+// handleEvents streams reports as Server-Sent Events. Browsers resume with
+// the Last-Event-ID header; other clients may pass ?last_event_id=.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	p, err := s.parseStreamParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		p.lastID = id
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+	s.stream(r.Context(), p, func(id string, data []byte) error {
+		if _, err := fmt.Fprintf(w, "id: %s\nevent: report\ndata: %s\n\n", id, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	})
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lu-zhengda/machealth/internal/health"
+)
+
+func TestParseStreamParams(t *testing.T) {
+	s := New(health.DefaultConfig(), Options{Interval: 30 * time.Second})
+	parse := func(query string) (streamParams, error) {
+		return s.parseStreamParams(httptest.NewRequest(http.MethodGet, "/events?"+query, nil))
+	}
+
+	p, err := parse("")
+	if err != nil || p.interval != 30*time.Second || p.subsystems != nil || p.changes {
+		t.Errorf("defaults = %+v, %v", p, err)
+	}
+	p, err = parse("subsystems=cpu,%20memory&interval=2s&changes=1&last_event_id=42")
+	if err != nil || len(p.subsystems) != 2 || p.subsystems[1] != "memory" || p.interval != 2*time.Second || !p.changes || p.lastID != "42" {
+		t.Errorf("parsed = %+v, %v", p, err)
+	}
+	for _, q := range []string{"interval=soon", "interval=10ms", "subsystems=cpu,gpu"} {
+		if _, err := parse(q); err == nil {
+			t.Errorf("%s: expected error", q)
+		}
+	}
+}
+
+func TestServer_FreshOnDemand(t *testing.T) {
+	s, calls := newTestServer(health.StatusGreen, Options{})
+	s.check = func(health.Config) health.Report {
+		calls.Add(1)
+		return health.Report{Timestamp: time.Now()}
+	}
+	first := s.fresh(time.Hour)
+	if r := s.fresh(time.Hour); !r.Timestamp.Equal(first.Timestamp) || calls.Load() != 1 {
+		t.Errorf("report younger than maxAge was not reused: %d checks", calls.Load())
+	}
+	if s.fresh(0); calls.Load() != 2 {
+		t.Errorf("stale report was not refreshed: %d checks", calls.Load())
+	}
+}
+
+func TestStreamPayload(t *testing.T) {
+	r := health.Report{
+		Timestamp: time.Unix(1700000000, 0).UTC(),
+		Score:     health.Score{Status: health.StatusYellow, Value: 80},
+		CPU:       health.CPU{Status: health.StatusGreen, LogicalCores: 8},
+		Memory:    health.Memory{Status: health.StatusYellow, PressurePercent: 60},
+	}
+
+	data, prev, err := streamPayload(r, streamParams{subsystems: []string{"memory", "cpu"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(data); !strings.HasPrefix(s, `{"timestamp":"2023-11-14T22:13:20Z","score":{`) ||
+		strings.Index(s, `"memory":`) > strings.Index(s, `"cpu":`) || strings.Contains(s, `"disk"`) {
+		t.Errorf("filtered payload = %s", s)
+	}
+
+	// Only changed subsystems are sent, and nothing when none changed.
+	changes := streamParams{changes: true}
+	_, prev, _ = streamPayload(r, changes, nil)
+	r.Memory.PressurePercent = 70
+	data, prev, _ = streamPayload(r, changes, prev)
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got["memory"] == nil {
+		t.Errorf("changes payload = %s", data)
+	}
+	if data, _, _ := streamPayload(r, changes, prev); data != nil {
+		t.Errorf("unchanged payload = %s", data)
+	}
+}
+
+// readEvent reads one Server-Sent Event.
+func readEvent(t *testing.T, br *bufio.Reader) map[string]string {
+	t.Helper()
+	ev := map[string]string{}
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return ev
+		}
+		k, v, _ := strings.Cut(line, ": ")
+		ev[k] = v
+	}
+}
+
+func TestServer_Events(t *testing.T) {
+	s, calls := newTestServer(health.StatusGreen, Options{Interval: time.Hour})
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	open := func(query, lastID string) (*http.Response, *bufio.Reader, context.CancelFunc) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/events?"+query, nil)
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, bufio.NewReader(resp.Body), cancel
+	}
+
+	resp, br, cancel := open("subsystems=memory", "")
+	ev := readEvent(t, br)
+	cancel()
+	resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" || ev["event"] != "report" || ev["id"] == "" {
+		t.Fatalf("first event = %v (%s)", ev, resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(ev["data"], `"memory":{`) || strings.Contains(ev["data"], `"cpu"`) {
+		t.Errorf("data = %s", ev["data"])
+	}
+
+	// Resuming with the latest ID skips it and waits for the next report,
+	// which a client interval shorter than the server's does not force.
+	resp, br, cancel = open("interval=1s", ev["id"])
+	defer cancel()
+	defer resp.Body.Close()
+	time.Sleep(1500 * time.Millisecond)
+	if n := calls.Load(); n != 1 {
+		t.Errorf("checks = %d, want 1", n)
+	}
+	s.Refresh()
+	if next := readEvent(t, br); next["id"] == ev["id"] || next["id"] == "" {
+		t.Errorf("resumed event = %v, last = %s", next, ev["id"])
+	}
+
+	if resp, _ := http.Get(ts.URL + "/events?subsystems=gpu"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown subsystem = %d", resp.StatusCode)
+	}
+}
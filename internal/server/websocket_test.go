@@ -0,0 +1,131 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lu-zhengda/machealth/internal/health"
+)
+
+func TestWSAccept(t *testing.T) {
+	// Example from RFC 6455 section 1.3.
+	if got := wsAccept("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("wsAccept() = %q", got)
+	}
+}
+
+// maskedFrame encodes a frame as a client sends it.
+func maskedFrame(opcode byte, payload []byte) []byte {
+	mask := []byte{1, 2, 3, 4}
+	b := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	b = append(b, mask...)
+	for i, c := range payload {
+		b = append(b, c^mask[i%4])
+	}
+	return b
+}
+
+func TestWSFrames(t *testing.T) {
+	for _, n := range []int{0, 125, 126, 4000, wsMaxFrame} {
+		var buf bytes.Buffer
+		payload := bytes.Repeat([]byte("x"), n)
+		if err := writeWSFrame(&buf, wsText, payload); err != nil {
+			t.Fatal(err)
+		}
+		op, got, err := readWSFrame(&buf)
+		if err != nil || op != wsText || !bytes.Equal(got, payload) {
+			t.Errorf("%d bytes: op=%d len=%d err=%v", n, op, len(got), err)
+		}
+	}
+
+	var buf bytes.Buffer
+	writeWSFrame(&buf, wsText, make([]byte, wsMaxFrame+1))
+	if _, _, err := readWSFrame(&buf); err == nil {
+		t.Error("expected error for oversized frame")
+	}
+
+	op, got, err := readWSFrame(bytes.NewReader(maskedFrame(wsPing, []byte("hello"))))
+	if err != nil || op != wsPing || string(got) != "hello" {
+		t.Errorf("masked frame = %d %q %v", op, got, err)
+	}
+}
+
+func TestServer_WebSocket(t *testing.T) {
+	s, _ := newTestServer(health.StatusYellow, Options{Interval: time.Hour})
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	conn.Write([]byte("GET /ws?subsystems=memory HTTP/1.1\r\nHost: " + ts.Listener.Addr().String() +
+		"\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\nSec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n"))
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("handshake = %s %v", resp.Status, resp.Header)
+	}
+
+	op, payload, err := readWSFrame(br)
+	if err != nil || op != wsText {
+		t.Fatalf("first frame = %d %v", op, err)
+	}
+	var msg struct {
+		ID     string        `json:"id"`
+		Report health.Report `json:"report"`
+	}
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.ID == "" || msg.Report.Memory.PressurePercent != 42 || msg.Report.Score.Status != health.StatusYellow {
+		t.Errorf("message = %s", payload)
+	}
+
+	conn.Write(maskedFrame(wsPing, []byte("p")))
+	if op, payload, err := readWSFrame(br); err != nil || op != wsPong || string(payload) != "p" {
+		t.Errorf("pong = %d %q %v", op, payload, err)
+	}
+	conn.Write(maskedFrame(wsClose, []byte{0x03, 0xE8}))
+	if op, _, err := readWSFrame(br); err != nil || op != wsClose {
+		t.Errorf("close = %d %v", op, err)
+	}
+}
+
+func TestServer_WebSocketRejected(t *testing.T) {
+	s, _ := newTestServer(health.StatusGreen, Options{Interval: time.Hour})
+	h := s.Handler()
+	upgrade := func(origin string) int {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:9876/ws", nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := upgrade("https://evil.example"); code != http.StatusForbidden {
+		t.Errorf("cross-origin = %d", code)
+	}
+	if rec := get(t, h, "/ws", ""); rec.StatusCode != http.StatusUpgradeRequired || !strings.Contains(rec.Status, "426") {
+		t.Errorf("plain GET = %s", rec.Status)
+	}
+}